@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// AttemptStore records which steps were already attempted,
+// backing the at-most-once execution guarantee.
+// Implementations can store attempts in-memory,
+// in a database, or any other storage mechanism.
+type AttemptStore interface {
+	// MarkAttempted atomically records an attempt for the given key
+	// and reports whether the key had already been attempted.
+	MarkAttempted(ctx context.Context, key string) (alreadyAttempted bool, err error)
+}
+
+// inMemoryAttemptStore is an in-memory implementation of AttemptStore.
+type inMemoryAttemptStore struct {
+	mu       sync.Mutex
+	attempts map[string]bool
+}
+
+// NewInMemoryAttemptStore creates a new in-memory AttemptStore.
+func NewInMemoryAttemptStore() AttemptStore {
+	return &inMemoryAttemptStore{
+		attempts: make(map[string]bool),
+	}
+}
+
+func (m *inMemoryAttemptStore) MarkAttempted(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.attempts[key] {
+		return true, nil
+	}
+	m.attempts[key] = true
+	return false, nil
+}
+
+// attemptKey returns the key used to record the attempts
+// of the step by the Saga with the given ID.
+func (s *step) attemptKey(sagaID string) string {
+	key := s.idempotencyKey
+	if key == "" {
+		key = stepKey(s)
+	}
+	return sagaID + ":" + key
+}
+
+// checkAtMostOnce records an attempt of the step, returning an
+// *AlreadyAttemptedError if the step was already attempted.
+func (s *step) checkAtMostOnce(ctx context.Context) error {
+	if !s.atMostOnce {
+		return nil
+	}
+	sagaID, _ := SagaIDFromContext(ctx)
+	if sagaID == "" && s.sharedAttemptStore {
+		return errors.Errorf("step %s records its attempts in a shared attempt store, which requires a saga ID (see WithSagaID)", s.name)
+	}
+	attempted, err := s.attemptStore.MarkAttempted(ctx, s.attemptKey(sagaID))
+	if err != nil {
+		return errors.Wrapf(err, "recording attempt of step %s", s.name)
+	}
+	if attempted {
+		return &AlreadyAttemptedError{StepName: s.name}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockAttemptStore struct {
+	err error
+}
+
+func (m *mockAttemptStore) MarkAttempted(ctx context.Context, key string) (bool, error) {
+	return false, m.err
+}
+
+func TestWithAtMostOnce(t *testing.T) {
+	testCases := []struct {
+		name          string
+		forwardErr    error
+		expectedCalls int
+	}{
+		{
+			name:          "successful attempt",
+			expectedCalls: 1,
+		},
+		{
+			name:          "failed attempt",
+			forwardErr:    errors.New("card declined"),
+			expectedCalls: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			step := NewStepWithOptions("charge",
+				func(ctx context.Context) error {
+					calls++
+					return tc.forwardErr
+				},
+				noop,
+				WithAtMostOnce(),
+				WithRetry(3, 0),
+			)
+			err := step.ExecuteForward(context.Background())
+			require.Equal(t, tc.forwardErr, err)
+			err = step.ExecuteForward(context.Background())
+			require.NotNil(t, err)
+			var attemptedErr *AlreadyAttemptedError
+			require.True(t, errors.As(err, &attemptedErr))
+			require.Equal(t, "step charge was already attempted", err.Error())
+			require.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestWithAtMostOnce_SharedStore(t *testing.T) {
+	store := NewInMemoryAttemptStore()
+	calls := 0
+	newChargeStep := func(orderID string) Step {
+		return NewStepWithOptions("charge",
+			func(ctx context.Context) error {
+				calls++
+				return nil
+			},
+			noop,
+			WithAtMostOnce(),
+			WithAttemptStore(store),
+			WithIdempotencyKey("charge:"+orderID),
+		)
+	}
+	ctx := withSagaID(context.Background(), "saga-1")
+	require.Nil(t, newChargeStep("order-1").ExecuteForward(ctx))
+	require.Nil(t, newChargeStep("order-2").ExecuteForward(ctx))
+	err := newChargeStep("order-1").ExecuteForward(ctx)
+	require.NotNil(t, err)
+	require.Equal(t, 2, calls)
+
+	// Attempts of other sagas are recorded apart.
+	require.Nil(t, newChargeStep("order-1").ExecuteForward(withSagaID(context.Background(), "saga-2")))
+	require.Equal(t, 3, calls)
+}
+
+func TestWithAtMostOnce_SharedStoreWithoutSagaID(t *testing.T) {
+	calls := 0
+	step := NewStepWithOptions("charge",
+		func(ctx context.Context) error {
+			calls++
+			return nil
+		},
+		noop,
+		WithAtMostOnce(),
+		WithAttemptStore(NewInMemoryAttemptStore()),
+	)
+	err := step.ExecuteForward(context.Background())
+	require.EqualError(t, err, "step charge records its attempts in a shared attempt store, which requires a saga ID (see WithSagaID)")
+	require.Equal(t, 0, calls)
+}
+
+func TestWithAtMostOnce_StoreError(t *testing.T) {
+	step := NewStepWithOptions("charge", noop, noop,
+		WithAtMostOnce(),
+		WithAttemptStore(&mockAttemptStore{err: errors.New("store unavailable")}),
+	)
+	err := step.ExecuteForward(withSagaID(context.Background(), "saga-1"))
+	require.NotNil(t, err)
+	require.Equal(t, "recording attempt of step charge: store unavailable", err.Error())
+}
+
+func TestWithAtMostOnce_StepAlias(t *testing.T) {
+	store := NewInMemoryAttemptStore()
+	oldStep := NewStepWithOptions("chargeCard", noop, noop,
+		WithAtMostOnce(),
+		WithAttemptStore(store),
+	)
+	ctx := withSagaID(context.Background(), "saga-1")
+	require.Nil(t, oldStep.ExecuteForward(ctx))
+	renamedStep := NewStepWithOptions("chargeCustomer", noop, noop,
+		WithAtMostOnce(),
+		WithAttemptStore(store),
+		WithStepAlias("chargeCard"),
+	)
+	err := renamedStep.ExecuteForward(ctx)
+	require.EqualError(t, err, "step chargeCustomer was already attempted")
+}
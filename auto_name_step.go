@@ -0,0 +1,44 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// AutoNameStep creates a new Step named after its forward function,
+// without the package path prefix (e.g. "reserveInventory" or
+// "(*OrderService).Reserve"), so that renamed functions are
+// automatically reflected in logs and traces.
+func AutoNameStep(forward, compensate func(ctx context.Context) error) Step {
+	return AutoNameStepWithOptions(forward, compensate)
+}
+
+// AutoNameStepWithOptions creates a new Step named after its
+// forward function, with the provided step options. See AutoNameStep.
+func AutoNameStepWithOptions(forward, compensate func(ctx context.Context) error, opts ...StepOption) Step {
+	return newStep(funcName(forward), forward, compensate, opts)
+}
+
+// funcName returns the name of the given function
+// without the package path prefix.
+func funcName(fn func(ctx context.Context) error) string {
+	f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer())
+	if f == nil {
+		return ""
+	}
+	name := f.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	// methods values are suffixed with "-fm".
+	return strings.TrimSuffix(name, "-fm")
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type inventoryService struct{}
+
+func (i *inventoryService) Reserve(ctx context.Context) error {
+	return nil
+}
+
+func reserveInventory(ctx context.Context) error {
+	return nil
+}
+
+func TestAutoNameStep(t *testing.T) {
+	testCases := []struct {
+		name         string
+		forward      func(ctx context.Context) error
+		expectedName string
+	}{
+		{
+			name:         "function",
+			forward:      reserveInventory,
+			expectedName: "reserveInventory",
+		},
+		{
+			name:         "method value",
+			forward:      (&inventoryService{}).Reserve,
+			expectedName: "(*inventoryService).Reserve",
+		},
+		{
+			name:         "anonymous function",
+			forward:      func(ctx context.Context) error { return nil },
+			expectedName: "TestAutoNameStep.func1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step := AutoNameStep(tc.forward, noop)
+			require.Equal(t, tc.expectedName, step.Name())
+		})
+	}
+}
+
+func TestAutoNameStepWithOptions(t *testing.T) {
+	step := AutoNameStepWithOptions(reserveInventory, noop, WithStepWeight(2))
+	require.Equal(t, "reserveInventory", step.Name())
+	weight, ok := step.(WeightedStep).Weight()
+	require.True(t, ok)
+	require.Equal(t, 2.0, weight)
+}
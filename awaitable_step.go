@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// awaitableStep is a step that, after running its forward action,
+// blocks until an external approval signal is received.
+type awaitableStep struct {
+	*step
+	wait func(ctx context.Context) error
+}
+
+// NewAwaitableStep creates a new Step that requires external approval
+// (e.g. from a human) to succeed. ExecuteForward first calls forward,
+// then blocks on signal. If true is received, the step succeeds.
+// If false is received, the channel is closed or the context is cancelled,
+// the step returns an *ApprovalRejectedError.
+func NewAwaitableStep(name string, forward, compensate func(ctx context.Context) error, signal <-chan bool) Step {
+	return &awaitableStep{
+		step: newStep(name, forward, compensate, nil),
+		wait: func(ctx context.Context) error {
+			select {
+			case approved := <-signal:
+				if !approved {
+					return &ApprovalRejectedError{StepName: name}
+				}
+				return nil
+			case <-ctx.Done():
+				return &ApprovalRejectedError{StepName: name, Cause: ctx.Err()}
+			}
+		},
+	}
+}
+
+// NewSignaledStep creates a new Step that requires external approval
+// to succeed. ExecuteForward first calls forward, then blocks until
+// a value is received on (or the closing of) approve. If the timeout
+// elapses or the context is cancelled first, the step returns
+// an *ApprovalRejectedError.
+func NewSignaledStep(name string, forward, compensate func(ctx context.Context) error, approve <-chan struct{}, timeout time.Duration) Step {
+	return &awaitableStep{
+		step: newStep(name, forward, compensate, nil),
+		wait: func(ctx context.Context) error {
+			timer := clockFromContext(ctx).NewTimer(timeout)
+			defer timer.Stop()
+			select {
+			case <-approve:
+				return nil
+			case <-timer.C():
+				return &ApprovalRejectedError{
+					StepName: name,
+					Cause:    errors.Errorf("approval timed out after %v", timeout),
+				}
+			case <-ctx.Done():
+				return &ApprovalRejectedError{StepName: name, Cause: ctx.Err()}
+			}
+		},
+	}
+}
+
+func (s *awaitableStep) ExecuteForward(ctx context.Context) error {
+	if err := s.step.ExecuteForward(ctx); err != nil {
+		return err
+	}
+	return s.wait(ctx)
+}
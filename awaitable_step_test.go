@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAwaitableStep(t *testing.T) {
+	testCases := []struct {
+		name          string
+		signal        func() <-chan bool
+		cancelCtx     bool
+		expectedError error
+	}{
+		{
+			name: "approved",
+			signal: func() <-chan bool {
+				ch := make(chan bool, 1)
+				ch <- true
+				return ch
+			},
+		},
+		{
+			name: "rejected",
+			signal: func() <-chan bool {
+				ch := make(chan bool, 1)
+				ch <- false
+				return ch
+			},
+			expectedError: errors.New("approval rejected for step approval"),
+		},
+		{
+			name: "context cancelled",
+			signal: func() <-chan bool {
+				return make(chan bool)
+			},
+			cancelCtx:     true,
+			expectedError: errors.New("approval rejected for step approval: context canceled"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancelCtx {
+				cancel()
+			}
+			forwardCalled := false
+			step := NewAwaitableStep("approval",
+				func(ctx context.Context) error {
+					forwardCalled = true
+					return nil
+				},
+				noop,
+				tc.signal(),
+			)
+			err := step.ExecuteForward(ctx)
+			require.True(t, forwardCalled)
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError.Error(), err.Error())
+				var rejectedErr *ApprovalRejectedError
+				require.True(t, errors.As(err, &rejectedErr))
+			} else {
+				require.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestSignaledStep(t *testing.T) {
+	t.Run("approved", func(t *testing.T) {
+		approve := make(chan struct{})
+		close(approve)
+		step := NewSignaledStep("approval", noop, noop, approve, time.Second)
+		require.Nil(t, step.ExecuteForward(context.Background()))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		step := NewSignaledStep("approval", noop, noop, make(chan struct{}), time.Millisecond)
+		err := step.ExecuteForward(context.Background())
+		require.NotNil(t, err)
+		require.Equal(t, "approval rejected for step approval: approval timed out after 1ms", err.Error())
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// BackoffPolicy computes the delays between the attempts
+// of the forward action of a step (see WithBackoffPolicy).
+type BackoffPolicy interface {
+	// NextDelay returns the delay to wait after the given
+	// failed attempt (starting at 1) before the next one.
+	NextDelay(attempt int) time.Duration
+
+	// Reset clears the state of the policy. It is called before
+	// each retry sequence, so that the same policy can be reused
+	// across executions of the step.
+	Reset()
+}
+
+// BackoffPolicyFunc is an adapter to allow the use of
+// ordinary functions as stateless backoff policies.
+type BackoffPolicyFunc func(attempt int) time.Duration
+
+func (f BackoffPolicyFunc) NextDelay(attempt int) time.Duration {
+	return f(attempt)
+}
+
+// Reset does nothing, as a BackoffPolicyFunc has no state.
+func (f BackoffPolicyFunc) Reset() {}
+
+// WithBackoffPolicy option sets the policy computing the delays between
+// the attempts of the forward action, replacing the delay and multiplier
+// of WithRetry and WithRetryMaxElapsed, which still bound the attempts.
+func WithBackoffPolicy(policy BackoffPolicy) StepOption {
+	return func(s *step) {
+		s.backoffPolicy = policy
+	}
+}
+
+// decorrelatedJitter implements the decorrelated jitter
+// algorithm described in the AWS Architecture Blog.
+type decorrelatedJitter struct {
+	mu   sync.Mutex
+	base time.Duration
+	cap  time.Duration
+	prev time.Duration
+}
+
+// DecorrelatedJitter creates a BackoffPolicy implementing the AWS
+// decorrelated jitter algorithm: each delay is picked at random between
+// base and three times the previous delay, and capped at cap. Since it
+// is stateful, a policy should not be shared by steps whose retry
+// sequences may run concurrently.
+func DecorrelatedJitter(base, cap time.Duration) BackoffPolicy {
+	return &decorrelatedJitter{base: base, cap: cap, prev: base}
+}
+
+func (d *decorrelatedJitter) NextDelay(attempt int) time.Duration {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delay := d.base
+	if upper := 3 * d.prev; upper > d.base {
+		delay += time.Duration(rand.Int63n(int64(upper - d.base)))
+	}
+	if delay > d.cap {
+		delay = d.cap
+	}
+	d.prev = delay
+	return delay
+}
+
+func (d *decorrelatedJitter) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.prev = d.base
+}
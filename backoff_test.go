@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingBackoff is a BackoffPolicy returning an
+// increasing delay, reset by Reset.
+type countingBackoff struct {
+	calls  int
+	resets int
+}
+
+func (b *countingBackoff) NextDelay(attempt int) time.Duration {
+	b.calls++
+	return time.Duration(b.calls) * time.Millisecond
+}
+
+func (b *countingBackoff) Reset() {
+	b.resets++
+	b.calls = 0
+}
+
+func TestWithBackoffPolicy(t *testing.T) {
+	testCases := []struct {
+		name           string
+		policy         BackoffPolicy
+		expectedDelays []time.Duration
+	}{
+		{
+			name: "policy func",
+			policy: BackoffPolicyFunc(func(attempt int) time.Duration {
+				return time.Duration(attempt*10) * time.Millisecond
+			}),
+			expectedDelays: []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+		},
+		{
+			name:           "stateful policy reset before each retry sequence",
+			policy:         &countingBackoff{},
+			expectedDelays: []time.Duration{time.Millisecond, 2 * time.Millisecond},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for execution := 1; execution <= 2; execution++ {
+				var delays []time.Duration
+				attempts := 0
+				step := NewStepWithOptions("step1",
+					func(ctx context.Context) error {
+						attempts++
+						if attempts < 3 {
+							return errors.New("forward error")
+						}
+						return nil
+					},
+					noop,
+					WithRetry(3, time.Hour),
+					WithBackoffPolicy(tc.policy),
+					WithRetryNotification(func(ctx context.Context, stepName string, attempt int, err error, nextDelay time.Duration) {
+						delays = append(delays, nextDelay)
+					}),
+				)
+				require.Nil(t, step.ExecuteForward(context.Background()))
+				require.Equal(t, tc.expectedDelays, delays)
+			}
+			if b, ok := tc.policy.(*countingBackoff); ok {
+				require.Equal(t, 2, b.resets)
+			}
+		})
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	base, cap := 10*time.Millisecond, 100*time.Millisecond
+	policy := DecorrelatedJitter(base, cap)
+	prev := base
+	for attempt := 1; attempt <= 50; attempt++ {
+		delay := policy.NextDelay(attempt)
+		require.GreaterOrEqual(t, delay, base)
+		require.LessOrEqual(t, delay, cap)
+		require.LessOrEqual(t, delay, 3*prev)
+		prev = delay
+	}
+	policy.Reset()
+	require.LessOrEqual(t, policy.NextDelay(1), 3*base)
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// batchStep is a step that processes its items in batches,
+// recording which batches succeeded so they are not processed again.
+type batchStep struct {
+	*step
+	batches      [][]any
+	state        *InMemoryStateManager
+	processFn    func(ctx context.Context, batch []any) error
+	compensateFn func(ctx context.Context, processedBatches [][]any) error
+}
+
+// NewBatchStep creates a new Step that splits items into batches of
+// batchSize and processes each one with processFn. Successfully processed
+// batches are recorded, so that a retried execution skips them.
+// ExecuteCompensate calls compensateFn with the batches processed so far,
+// which are no longer recorded as processed once it succeeds.
+// A batchSize lower than one processes all items in a single batch.
+// A nil compensateFn leaves the step without compensation action
+// (see WithCompensationMode).
+func NewBatchStep(name string, items []any, processFn func(ctx context.Context, batch []any) error, compensateFn func(ctx context.Context, processedBatches [][]any) error, batchSize int) Step {
+	s := &batchStep{
+		batches:      splitBatches(items, batchSize),
+		state:        NewInMemoryStateManager(),
+		processFn:    processFn,
+		compensateFn: compensateFn,
+	}
+	var compensate func(ctx context.Context) error
+	if compensateFn != nil {
+		compensate = s.compensateBatches
+	}
+	s.step = newStep(name, s.processBatches, compensate, nil)
+	return s
+}
+
+// processBatches processes the batches not processed yet.
+func (s *batchStep) processBatches(ctx context.Context) error {
+	for i, batch := range s.batches {
+		processed, err := s.state.StepState(i)
+		if err != nil {
+			return errors.Wrapf(err, "retrieving state for batch %d", i)
+		}
+		if processed {
+			continue
+		}
+		if err := s.processFn(ctx, batch); err != nil {
+			return errors.Wrapf(err, "processing batch %d", i)
+		}
+		if err := s.state.SetStepState(i, true); err != nil {
+			return errors.Wrapf(err, "setting state for batch %d", i)
+		}
+	}
+	return nil
+}
+
+// compensateBatches compensates the processed batches, then
+// records them as not processed, so that a later execution
+// processes them again.
+func (s *batchStep) compensateBatches(ctx context.Context) error {
+	var processed [][]any
+	var indexes []int
+	for i, batch := range s.batches {
+		done, err := s.state.StepState(i)
+		if err != nil {
+			return errors.Wrapf(err, "retrieving state for batch %d", i)
+		}
+		if done {
+			processed = append(processed, batch)
+			indexes = append(indexes, i)
+		}
+	}
+	if err := s.compensateFn(ctx, processed); err != nil {
+		return err
+	}
+	for _, i := range indexes {
+		if err := s.state.SetStepState(i, false); err != nil {
+			return errors.Wrapf(err, "clearing state for batch %d", i)
+		}
+	}
+	return nil
+}
+
+// splitBatches splits items into consecutive batches of the given size.
+func splitBatches(items []any, batchSize int) [][]any {
+	if batchSize < 1 {
+		batchSize = len(items)
+	}
+	var batches [][]any
+	for start := 0; start < len(items); start += batchSize {
+		end := start + batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		batches = append(batches, items[start:end])
+	}
+	return batches
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchStep(t *testing.T) {
+	var processedCalls [][]any
+	var compensated [][]any
+	failOnce := true
+	step := NewBatchStep("insert",
+		[]any{1, 2, 3, 4, 5},
+		func(ctx context.Context, batch []any) error {
+			if batch[0] == 3 && failOnce {
+				failOnce = false
+				return errors.New("insert error")
+			}
+			processedCalls = append(processedCalls, batch)
+			return nil
+		},
+		func(ctx context.Context, processedBatches [][]any) error {
+			compensated = processedBatches
+			return nil
+		},
+		2,
+	)
+
+	// First execution fails on the second batch.
+	err := step.ExecuteForward(context.Background())
+	require.NotNil(t, err)
+	require.Equal(t, "processing batch 1: insert error", err.Error())
+	require.Equal(t, [][]any{{1, 2}}, processedCalls)
+
+	// Retrying skips the first batch.
+	require.Nil(t, step.ExecuteForward(context.Background()))
+	require.Equal(t, [][]any{{1, 2}, {3, 4}, {5}}, processedCalls)
+
+	require.Nil(t, step.ExecuteCompensate(context.Background()))
+	require.Equal(t, [][]any{{1, 2}, {3, 4}, {5}}, compensated)
+}
+
+func TestBatchStep_Saga(t *testing.T) {
+	var processedCalls [][]any
+	var compensated [][]any
+	insert := NewBatchStep("insert",
+		[]any{1, 2, 3},
+		func(ctx context.Context, batch []any) error {
+			processedCalls = append(processedCalls, batch)
+			return nil
+		},
+		func(ctx context.Context, processedBatches [][]any) error {
+			compensated = processedBatches
+			return nil
+		},
+		2,
+	)
+	newSaga := func() Saga {
+		s := New()
+		s.AddStep(insert)
+		s.AddStep(NewStep("notify",
+			func(ctx context.Context) error {
+				return errors.New("notify error")
+			},
+			noop,
+		))
+		return s
+	}
+	require.EqualError(t, newSaga().Execute(context.Background()), "executing step notify: notify error")
+	require.Equal(t, [][]any{{1, 2}, {3}}, processedCalls)
+	require.Equal(t, [][]any{{1, 2}, {3}}, compensated)
+
+	// Compensated batches are processed again by the next saga running the step.
+	require.EqualError(t, newSaga().Execute(context.Background()), "executing step notify: notify error")
+	require.Equal(t, [][]any{{1, 2}, {3}, {1, 2}, {3}}, processedCalls)
+	require.Equal(t, [][]any{{1, 2}, {3}}, compensated)
+}
+
+func TestSplitBatches(t *testing.T) {
+	testCases := []struct {
+		name            string
+		items           []any
+		batchSize       int
+		expectedBatches [][]any
+	}{
+		{name: "exact", items: []any{1, 2, 3, 4}, batchSize: 2, expectedBatches: [][]any{{1, 2}, {3, 4}}},
+		{name: "remainder", items: []any{1, 2, 3}, batchSize: 2, expectedBatches: [][]any{{1, 2}, {3}}},
+		{name: "single batch", items: []any{1, 2, 3}, batchSize: 0, expectedBatches: [][]any{{1, 2, 3}}},
+		{name: "no items", items: nil, batchSize: 2, expectedBatches: nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedBatches, splitBatches(tc.items, tc.batchSize))
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// stepStateUpdate is a buffered write of the state of a step,
+// stored by index or, if named, by key.
+type stepStateUpdate struct {
+	stepIndex int
+	key       string
+	named     bool
+	success   bool
+}
+
+// BatchedStateManager is a StateManager decorator that buffers the step
+// state writes and flushes them to an inner StateManager in batches,
+// either when batchSize writes are buffered or flushInterval has passed
+// since the first buffered write, whichever comes first.
+//
+// This trades consistency for throughput: until they are flushed,
+// buffered writes are only visible through the BatchedStateManager and
+// are lost if the process crashes, so a restarted Saga may run again
+// steps that had completed. Errors of the writes flushed in the
+// background are returned by the next call to Flush.
+//
+// The BatchedStateManager is a NamedStateManager if inner is one. When
+// inner is a VersionedStateManager or a NamedVersionedStateManager, the
+// buffered writes are flushed with compare-and-swap semantics.
+type BatchedStateManager struct {
+	inner         StateManager
+	batchSize     int
+	flushInterval time.Duration
+
+	mu       sync.Mutex
+	pending  []stepStateUpdate
+	timer    *time.Timer
+	flushErr error
+}
+
+// NewBatchedStateManager creates a new BatchedStateManager
+// buffering the step state writes to inner.
+func NewBatchedStateManager(inner StateManager, batchSize int, flushInterval time.Duration) *BatchedStateManager {
+	return &BatchedStateManager{
+		inner:         inner,
+		batchSize:     batchSize,
+		flushInterval: flushInterval,
+	}
+}
+
+// WithBatchedStateWrites option makes the Saga buffer the step state
+// writes to its StateManager and flush them in batches (see
+// BatchedStateManager). Buffered writes are always flushed before
+// Execute returns, even if it panics.
+func WithBatchedStateWrites(batchSize int, flushInterval time.Duration) Option {
+	return func(s *saga) {
+		s.stateBatchSize = batchSize
+		s.stateFlushInterval = flushInterval
+	}
+}
+
+func (m *BatchedStateManager) SetStepState(stepIndex int, success bool) error {
+	return m.buffer(stepStateUpdate{stepIndex: stepIndex, success: success})
+}
+
+func (m *BatchedStateManager) StepState(stepIndex int) (bool, error) {
+	if success, ok := m.pendingState(func(u stepStateUpdate) bool {
+		return !u.named && u.stepIndex == stepIndex
+	}); ok {
+		return success, nil
+	}
+	return m.inner.StepState(stepIndex)
+}
+
+func (m *BatchedStateManager) SetNamedStepState(key string, success bool) error {
+	if _, ok := stateManagerAs[NamedStateManager](m.inner); !ok {
+		return errors.New("inner state manager does not support named step states")
+	}
+	return m.buffer(stepStateUpdate{key: key, named: true, success: success})
+}
+
+func (m *BatchedStateManager) NamedStepState(key string) (bool, error) {
+	nm, ok := stateManagerAs[NamedStateManager](m.inner)
+	if !ok {
+		return false, errors.New("inner state manager does not support named step states")
+	}
+	if success, ok := m.pendingState(func(u stepStateUpdate) bool {
+		return u.named && u.key == key
+	}); ok {
+		return success, nil
+	}
+	return nm.NamedStepState(key)
+}
+
+func (m *BatchedStateManager) unwrapStateManager() StateManager {
+	return m.inner
+}
+
+// buffer buffers the given write, flushing the
+// buffered writes if the batch is full.
+func (m *BatchedStateManager) buffer(u stepStateUpdate) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pending = append(m.pending, u)
+	if len(m.pending) >= m.batchSize {
+		return m.flush()
+	}
+	if m.timer == nil && m.flushInterval > 0 {
+		m.timer = time.AfterFunc(m.flushInterval, m.flushInBackground)
+	}
+	return nil
+}
+
+// pendingState returns the state of the last buffered
+// write matching the given function, if any.
+func (m *BatchedStateManager) pendingState(match func(u stepStateUpdate) bool) (bool, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.pending) - 1; i >= 0; i-- {
+		if match(m.pending[i]) {
+			return m.pending[i].success, true
+		}
+	}
+	return false, false
+}
+
+func (m *BatchedStateManager) SetStepMetadata(stepIndex int, metadata map[string]string) error {
+	mm, ok := m.inner.(StepMetadataManager)
+	if !ok {
+		return ErrStepMetadataNotSupported
+	}
+	return mm.SetStepMetadata(stepIndex, metadata)
+}
+
+func (m *BatchedStateManager) GetStepMetadata(stepIndex int) (map[string]string, error) {
+	mm, ok := m.inner.(StepMetadataManager)
+	if !ok {
+		return nil, ErrStepMetadataNotSupported
+	}
+	return mm.GetStepMetadata(stepIndex)
+}
+
+func (m *BatchedStateManager) SetStepOutput(stepIndex int, data []byte) error {
+	om, ok := m.inner.(StepOutputManager)
+	if !ok {
+		return ErrStepOutputNotSupported
+	}
+	return om.SetStepOutput(stepIndex, data)
+}
+
+func (m *BatchedStateManager) GetStepOutput(stepIndex int) ([]byte, error) {
+	om, ok := m.inner.(StepOutputManager)
+	if !ok {
+		return nil, ErrStepOutputNotSupported
+	}
+	return om.GetStepOutput(stepIndex)
+}
+
+// Flush writes the buffered step states to the inner StateManager. It
+// returns the first error of the writes, including the ones flushed in
+// the background since the last call.
+func (m *BatchedStateManager) Flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	err := m.flush()
+	if m.flushErr != nil {
+		err, m.flushErr = m.flushErr, nil
+	}
+	return err
+}
+
+// flushInBackground flushes the buffered step states when the flush
+// interval elapses, keeping the error for the next call to Flush.
+func (m *BatchedStateManager) flushInBackground() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.flush(); err != nil && m.flushErr == nil {
+		m.flushErr = err
+	}
+}
+
+// flush writes the buffered step states, in order, to the inner
+// StateManager. Writes that fail are dropped. It must be called
+// with m.mu held.
+func (m *BatchedStateManager) flush() error {
+	if m.timer != nil {
+		m.timer.Stop()
+		m.timer = nil
+	}
+	pending := m.pending
+	m.pending = nil
+	var firstErr error
+	for _, u := range pending {
+		if err := m.write(u); err != nil && firstErr == nil {
+			if u.named {
+				firstErr = errors.Wrapf(err, "flushing state of step %s", u.key)
+			} else {
+				firstErr = errors.Wrapf(err, "flushing state of step %d", u.stepIndex)
+			}
+		}
+	}
+	return firstErr
+}
+
+// write writes the given buffered write to the inner StateManager,
+// with compare-and-swap semantics if it supports versioning.
+func (m *BatchedStateManager) write(u stepStateUpdate) error {
+	if u.named {
+		if vm, ok := stateManagerAs[NamedVersionedStateManager](m.inner); ok {
+			return setNamedStepStateVersioned(vm, u.key, u.success)
+		}
+		nm, _ := stateManagerAs[NamedStateManager](m.inner)
+		return nm.SetNamedStepState(u.key, u.success)
+	}
+	if vm, ok := stateManagerAs[VersionedStateManager](m.inner); ok {
+		return setStepStateVersioned(vm, u.stepIndex, u.success)
+	}
+	return m.inner.SetStepState(u.stepIndex, u.success)
+}
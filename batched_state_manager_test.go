@@ -0,0 +1,204 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// writeCountingStateManager is an in-memory StateManager
+// counting the step state writes it receives.
+type writeCountingStateManager struct {
+	*InMemoryStateManager
+	mu     sync.Mutex
+	writes int
+	err    error
+}
+
+func (m *writeCountingStateManager) SetStepState(stepIndex int, success bool) error {
+	m.mu.Lock()
+	m.writes++
+	err := m.err
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return m.InMemoryStateManager.SetStepState(stepIndex, success)
+}
+
+func (m *writeCountingStateManager) writeCount() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.writes
+}
+
+func TestBatchedStateManager(t *testing.T) {
+	t.Run("flushed when the batch is full", func(t *testing.T) {
+		inner := &writeCountingStateManager{InMemoryStateManager: NewInMemoryStateManager()}
+		bm := NewBatchedStateManager(inner, 3, time.Hour)
+		require.Nil(t, bm.SetStepState(0, true))
+		require.Nil(t, bm.SetStepState(1, false))
+		require.Equal(t, 0, inner.writeCount())
+		success, err := bm.StepState(0)
+		require.Nil(t, err)
+		require.True(t, success)
+		require.Nil(t, bm.SetStepState(1, true))
+		require.Equal(t, 3, inner.writeCount())
+		success, err = inner.StepState(1)
+		require.Nil(t, err)
+		require.True(t, success)
+	})
+
+	t.Run("flushed when the interval elapses", func(t *testing.T) {
+		inner := &writeCountingStateManager{InMemoryStateManager: NewInMemoryStateManager()}
+		bm := NewBatchedStateManager(inner, 100, 10*time.Millisecond)
+		require.Nil(t, bm.SetStepState(0, true))
+		require.Eventually(t, func() bool {
+			return inner.writeCount() == 1
+		}, time.Second, time.Millisecond)
+	})
+
+	t.Run("background flush error returned by flush", func(t *testing.T) {
+		inner := &writeCountingStateManager{InMemoryStateManager: NewInMemoryStateManager(), err: errors.New("db down")}
+		bm := NewBatchedStateManager(inner, 100, 10*time.Millisecond)
+		require.Nil(t, bm.SetStepState(0, true))
+		require.Eventually(t, func() bool {
+			return inner.writeCount() == 1
+		}, time.Second, time.Millisecond)
+		require.EqualError(t, bm.Flush(), "flushing state of step 0: db down")
+		require.Nil(t, bm.Flush())
+	})
+}
+
+// casCountingStateManager is an in-memory StateManager counting
+// the named step state writes with compare-and-swap semantics.
+type casCountingStateManager struct {
+	*InMemoryStateManager
+	casWrites int
+}
+
+func (m *casCountingStateManager) SetNamedStepStateVersioned(key string, success bool, expectedVersion int) (int, error) {
+	m.casWrites++
+	return m.InMemoryStateManager.SetNamedStepStateVersioned(key, success, expectedVersion)
+}
+
+// versionedStateManager is an in-memory VersionedStateManager.
+type versionedStateManager struct {
+	*InMemoryStateManager
+	versions  map[int]int
+	casWrites int
+}
+
+func (m *versionedStateManager) SetStepStateVersioned(stepIndex int, success bool, expectedVersion int) (int, error) {
+	m.casWrites++
+	if m.versions[stepIndex] != expectedVersion {
+		return 0, &ConcurrentModificationError{StepIndex: stepIndex, ExpectedVersion: expectedVersion, ActualVersion: m.versions[stepIndex]}
+	}
+	m.versions[stepIndex]++
+	return m.versions[stepIndex], m.InMemoryStateManager.SetStepState(stepIndex, success)
+}
+
+func (m *versionedStateManager) StepStateWithVersion(stepIndex int) (bool, int, error) {
+	success, err := m.InMemoryStateManager.StepState(stepIndex)
+	return success, m.versions[stepIndex], err
+}
+
+func TestBatchedStateManager_VersionedState(t *testing.T) {
+	inner := &versionedStateManager{InMemoryStateManager: NewInMemoryStateManager(), versions: map[int]int{0: 3}}
+	bm := NewBatchedStateManager(inner, 1, time.Hour)
+	require.Nil(t, bm.SetStepState(0, true))
+	require.Equal(t, 1, inner.casWrites)
+	success, version, err := inner.StepStateWithVersion(0)
+	require.Nil(t, err)
+	require.True(t, success)
+	require.Equal(t, 4, version)
+}
+
+func TestBatchedStateManager_NamedState(t *testing.T) {
+	t.Run("forwarded to inner named state manager", func(t *testing.T) {
+		inner := &casCountingStateManager{InMemoryStateManager: NewInMemoryStateManager()}
+		bm := NewBatchedStateManager(inner, 2, time.Hour)
+		nm, ok := stateManagerAs[NamedStateManager](bm)
+		require.True(t, ok)
+		require.Nil(t, nm.SetNamedStepState("reserve", true))
+		success, err := nm.NamedStepState("reserve")
+		require.Nil(t, err)
+		require.True(t, success)
+		success, err = inner.NamedStepState("reserve")
+		require.Nil(t, err)
+		require.False(t, success)
+
+		// Named and indexed writes do not shadow each other.
+		require.Nil(t, bm.SetStepState(0, false))
+		success, err = inner.NamedStepState("reserve")
+		require.Nil(t, err)
+		require.True(t, success)
+		require.Equal(t, 1, inner.casWrites)
+	})
+
+	t.Run("hidden when inner does not support it", func(t *testing.T) {
+		bm := NewBatchedStateManager(&mockStateManager{}, 2, time.Hour)
+		_, ok := stateManagerAs[NamedStateManager](bm)
+		require.False(t, ok)
+		require.EqualError(t, bm.SetNamedStepState("reserve", true), "inner state manager does not support named step states")
+	})
+}
+
+func TestWithBatchedStateWrites(t *testing.T) {
+	t.Run("name-keyed state", func(t *testing.T) {
+		inner := &casCountingStateManager{InMemoryStateManager: NewInMemoryStateManager()}
+		saga := New(WithStateManager(inner), WithBatchedStateWrites(10, time.Hour), WithStepSorter(ByNameSorter()))
+		saga.AddStep(NewStep("step2", noop, noop))
+		saga.AddStep(NewStep("step1", noop, noop))
+		require.Nil(t, saga.Execute(context.Background()))
+		for _, name := range []string{"step1", "step2"} {
+			success, err := inner.NamedStepState(name)
+			require.Nil(t, err)
+			require.True(t, success)
+		}
+		require.Equal(t, 2, inner.casWrites)
+	})
+
+	t.Run("flushed before execute returns", func(t *testing.T) {
+		inner := &writeCountingStateManager{InMemoryStateManager: NewInMemoryStateManager()}
+		saga := New(WithStateManager(inner), WithBatchedStateWrites(10, time.Hour))
+		for _, name := range []string{"step1", "step2", "step3"} {
+			saga.AddStep(NewStep(name, noop, noop))
+		}
+		require.Nil(t, saga.Execute(context.Background()))
+		require.Equal(t, 3, inner.writeCount())
+	})
+
+	t.Run("flushed on panic", func(t *testing.T) {
+		inner := &writeCountingStateManager{InMemoryStateManager: NewInMemoryStateManager()}
+		saga := New(WithStateManager(inner), WithBatchedStateWrites(10, time.Hour))
+		saga.AddStep(NewStep("step1", noop, noop))
+		saga.AddStep(NewStep("step2",
+			func(ctx context.Context) error {
+				panic("boom")
+			},
+			noop,
+		))
+		require.Panics(t, func() {
+			saga.Execute(context.Background())
+		})
+		success, err := inner.StepState(0)
+		require.Nil(t, err)
+		require.True(t, success)
+	})
+
+	t.Run("flush error", func(t *testing.T) {
+		inner := &writeCountingStateManager{InMemoryStateManager: NewInMemoryStateManager(), err: errors.New("db down")}
+		saga := New(WithStateManager(inner), WithBatchedStateWrites(10, time.Hour))
+		saga.AddStep(NewStep("step1", noop, noop))
+		require.EqualError(t, saga.Execute(context.Background()), "flushing state writes: flushing state of step 0: db down")
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"runtime"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// BenchmarkResult holds the timing and allocation data
+// measured for a step.
+type BenchmarkResult struct {
+	StepName    string
+	Iterations  int
+	NsPerOp     int64
+	AllocsPerOp int64
+}
+
+// BenchmarkSaga executes the forward actions of the given saga
+// the given number of times and returns a result for each step,
+// in step order. Each run uses a fresh in-memory state manager,
+// so that no step is skipped as already completed. Only the steps
+// are taken from the given saga; its options are not applied. Like
+// AddStep, it must not be called concurrently with the other methods
+// of the given saga.
+func BenchmarkSaga(s Saga, ctx context.Context, iterations int) ([]BenchmarkResult, error) {
+	if iterations <= 0 {
+		return nil, errors.Errorf("iterations must be greater than zero, got %d", iterations)
+	}
+	impl, ok := s.(*saga)
+	if !ok {
+		return nil, errors.Errorf("unsupported saga implementation %T", s)
+	}
+	steps := make([]*measuredStep, len(impl.steps))
+	for i, step := range impl.steps {
+		steps[i] = &measuredStep{Step: step}
+	}
+
+	for i := 0; i < iterations; i++ {
+		run := New()
+		for _, step := range steps {
+			run.AddStep(step)
+		}
+		if err := run.Execute(ctx); err != nil {
+			return nil, errors.Wrapf(err, "executing saga in iteration %d", i)
+		}
+	}
+
+	results := make([]BenchmarkResult, len(steps))
+	for i, step := range steps {
+		results[i] = step.result(iterations)
+	}
+	return results, nil
+}
+
+// BenchmarkStep executes the forward action of the given step the
+// given number of times and returns its timing and allocation data.
+// Errors returned by the step are ignored.
+func BenchmarkStep(step Step, ctx context.Context, iterations int) BenchmarkResult {
+	ms := &measuredStep{Step: step}
+	for i := 0; i < iterations; i++ {
+		_ = ms.ExecuteForward(ctx)
+	}
+	return ms.result(iterations)
+}
+
+// measuredStep wraps a step, accumulating the time spent and
+// the number of allocations made by its forward action.
+type measuredStep struct {
+	Step
+	elapsed time.Duration
+	allocs  uint64
+}
+
+func (s *measuredStep) Unwrap() Step {
+	return s.Step
+}
+
+func (s *measuredStep) ExecuteForward(ctx context.Context) error {
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	err := s.Step.ExecuteForward(ctx)
+	s.elapsed += time.Since(start)
+	runtime.ReadMemStats(&after)
+	s.allocs += after.Mallocs - before.Mallocs
+	return err
+}
+
+// result returns the accumulated measurements averaged
+// over the given number of iterations.
+func (s *measuredStep) result(iterations int) BenchmarkResult {
+	r := BenchmarkResult{
+		StepName:   s.Name(),
+		Iterations: iterations,
+	}
+	if iterations > 0 {
+		r.NsPerOp = s.elapsed.Nanoseconds() / int64(iterations)
+		r.AllocsPerOp = int64(s.allocs) / int64(iterations)
+	}
+	return r
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBenchmarkSaga(t *testing.T) {
+	calls := 0
+	saga := New()
+	saga.AddStep(NewStep("step1",
+		func(ctx context.Context) error {
+			calls++
+			return nil
+		},
+		noop,
+	))
+	saga.AddStep(NewStep("step2", noop, noop))
+
+	results, err := BenchmarkSaga(saga, context.Background(), 5)
+	require.Nil(t, err)
+	require.Equal(t, 5, calls)
+	require.Len(t, results, 2)
+	require.Equal(t, "step1", results[0].StepName)
+	require.Equal(t, "step2", results[1].StepName)
+	require.Equal(t, 5, results[0].Iterations)
+
+	saga.AddStep(NewStep("step3",
+		func(ctx context.Context) error {
+			return errors.New("step3 error")
+		},
+		noop,
+	))
+	_, err = BenchmarkSaga(saga, context.Background(), 5)
+	require.NotNil(t, err)
+	require.Equal(t, "executing saga in iteration 0: executing step step3: step3 error", err.Error())
+}
+
+func TestBenchmarkStep(t *testing.T) {
+	calls := 0
+	step := NewStep("step1",
+		func(ctx context.Context) error {
+			calls++
+			return nil
+		},
+		noop,
+	)
+	result := BenchmarkStep(step, context.Background(), 3)
+	require.Equal(t, 3, calls)
+	require.Equal(t, "step1", result.StepName)
+	require.Equal(t, 3, result.Iterations)
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import "context"
+
+// checkpointStep is a special no-op step marking
+// a named point between steps of a Saga.
+type checkpointStep struct {
+	name string
+}
+
+func (c *checkpointStep) Name() string {
+	return c.name
+}
+
+func (c *checkpointStep) ExecuteForward(ctx context.Context) error {
+	return nil
+}
+
+func (c *checkpointStep) ExecuteCompensate(ctx context.Context) error {
+	return nil
+}
+
+// isCheckpoint reports whether the given step is a checkpoint.
+func isCheckpoint(step Step) bool {
+	_, ok := stepAs[*checkpointStep](step)
+	return ok
+}
+
+// reachCheckpoint records that the given checkpoint was reached
+// and fires the checkpoint hook, if any.
+func (s *saga) reachCheckpoint(ctx context.Context, step Step, completedSteps int) {
+	s.summaryMu.Lock()
+	s.summary.Checkpoints = append(s.summary.Checkpoints, step.Name())
+	s.summaryMu.Unlock()
+	if s.onCheckpoint != nil {
+		s.onCheckpoint(ctx, step.Name(), completedSteps)
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"sync"
+	"time"
+)
+
+// WithStateManagerCircuitBreaker option wraps the StateManager of the
+// Saga with a circuit breaker. After failureThreshold consecutive
+// failures, the circuit opens and the StateManager calls return a
+// *StateManagerCircuitOpenError immediately, without attempting the
+// operation. The circuit closes again after openDuration, as measured
+// by the clock of the Saga (see WithClock).
+func WithStateManagerCircuitBreaker(failureThreshold int, openDuration time.Duration) Option {
+	return func(s *saga) {
+		s.cbFailureThreshold = failureThreshold
+		s.cbOpenDuration = openDuration
+	}
+}
+
+// WithFallbackToMemoryOnCircuitOpen option makes the Saga fall back to
+// an in-memory state manager for the remainder of the execution when
+// the circuit of its StateManager opens.
+// It requires WithStateManagerCircuitBreaker.
+func WithFallbackToMemoryOnCircuitOpen() Option {
+	return func(s *saga) {
+		s.cbFallbackToMemory = true
+	}
+}
+
+// circuitBreakerStateManager is a StateManager that wraps another one
+// with a circuit breaker. It supports the NamedStateManager,
+// VersionedStateManager and NamedVersionedStateManager interfaces that
+// the wrapped StateManager supports. While falling back to memory,
+// versions are not checked.
+type circuitBreakerStateManager struct {
+	sm               StateManager
+	failureThreshold int
+	openDuration     time.Duration
+	clock            Clock
+
+	// fallback, if set, is used while falling back to memory.
+	fallback    *InMemoryStateManager
+	onFallback  func()
+	fallingBack bool
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// newCircuitBreakerStateManager wraps the given StateManager with a
+// circuit breaker, timing the open circuit with the given clock.
+func newCircuitBreakerStateManager(sm StateManager, failureThreshold int, openDuration time.Duration, clock Clock) *circuitBreakerStateManager {
+	if clock == nil {
+		clock = SystemClock{}
+	}
+	return &circuitBreakerStateManager{
+		sm:               sm,
+		failureThreshold: failureThreshold,
+		openDuration:     openDuration,
+		clock:            clock,
+	}
+}
+
+func (c *circuitBreakerStateManager) SetStepState(stepIndex int, success bool) error {
+	return c.call(func(sm StateManager) error {
+		return sm.SetStepState(stepIndex, success)
+	})
+}
+
+func (c *circuitBreakerStateManager) StepState(stepIndex int) (bool, error) {
+	var completed bool
+	err := c.call(func(sm StateManager) (err error) {
+		completed, err = sm.StepState(stepIndex)
+		return err
+	})
+	return completed, err
+}
+
+func (c *circuitBreakerStateManager) SetNamedStepState(key string, success bool) error {
+	return c.call(func(sm StateManager) error {
+		return sm.(NamedStateManager).SetNamedStepState(key, success)
+	})
+}
+
+func (c *circuitBreakerStateManager) NamedStepState(key string) (bool, error) {
+	var completed bool
+	err := c.call(func(sm StateManager) (err error) {
+		completed, err = sm.(NamedStateManager).NamedStepState(key)
+		return err
+	})
+	return completed, err
+}
+
+func (c *circuitBreakerStateManager) SetStepStateVersioned(stepIndex int, success bool, expectedVersion int) (int, error) {
+	var version int
+	err := c.call(func(sm StateManager) (err error) {
+		vm, ok := sm.(VersionedStateManager)
+		if !ok {
+			return sm.SetStepState(stepIndex, success)
+		}
+		version, err = vm.SetStepStateVersioned(stepIndex, success, expectedVersion)
+		return err
+	})
+	return version, err
+}
+
+func (c *circuitBreakerStateManager) StepStateWithVersion(stepIndex int) (bool, int, error) {
+	var (
+		completed bool
+		version   int
+	)
+	err := c.call(func(sm StateManager) (err error) {
+		vm, ok := sm.(VersionedStateManager)
+		if !ok {
+			completed, err = sm.StepState(stepIndex)
+			return err
+		}
+		completed, version, err = vm.StepStateWithVersion(stepIndex)
+		return err
+	})
+	return completed, version, err
+}
+
+func (c *circuitBreakerStateManager) SetNamedStepStateVersioned(key string, success bool, expectedVersion int) (int, error) {
+	var version int
+	err := c.call(func(sm StateManager) (err error) {
+		version, err = sm.(NamedVersionedStateManager).SetNamedStepStateVersioned(key, success, expectedVersion)
+		return err
+	})
+	return version, err
+}
+
+func (c *circuitBreakerStateManager) NamedStepStateWithVersion(key string) (bool, int, error) {
+	var (
+		completed bool
+		version   int
+	)
+	err := c.call(func(sm StateManager) (err error) {
+		completed, version, err = sm.(NamedVersionedStateManager).NamedStepStateWithVersion(key)
+		return err
+	})
+	return completed, version, err
+}
+
+func (c *circuitBreakerStateManager) unwrapStateManager() StateManager {
+	return c.sm
+}
+
+func (c *circuitBreakerStateManager) SetStepMetadata(stepIndex int, metadata map[string]string) error {
+	if _, ok := c.sm.(StepMetadataManager); !ok {
+		return ErrStepMetadataNotSupported
+	}
+	return c.call(func(sm StateManager) error {
+		return sm.(StepMetadataManager).SetStepMetadata(stepIndex, metadata)
+	})
+}
+
+func (c *circuitBreakerStateManager) GetStepMetadata(stepIndex int) (map[string]string, error) {
+	if _, ok := c.sm.(StepMetadataManager); !ok {
+		return nil, ErrStepMetadataNotSupported
+	}
+	var metadata map[string]string
+	err := c.call(func(sm StateManager) (err error) {
+		metadata, err = sm.(StepMetadataManager).GetStepMetadata(stepIndex)
+		return err
+	})
+	return metadata, err
+}
+
+func (c *circuitBreakerStateManager) SetStepOutput(stepIndex int, data []byte) error {
+	if _, ok := c.sm.(StepOutputManager); !ok {
+		return ErrStepOutputNotSupported
+	}
+	return c.call(func(sm StateManager) error {
+		return sm.(StepOutputManager).SetStepOutput(stepIndex, data)
+	})
+}
+
+func (c *circuitBreakerStateManager) GetStepOutput(stepIndex int) ([]byte, error) {
+	if _, ok := c.sm.(StepOutputManager); !ok {
+		return nil, ErrStepOutputNotSupported
+	}
+	var data []byte
+	err := c.call(func(sm StateManager) (err error) {
+		data, err = sm.(StepOutputManager).GetStepOutput(stepIndex)
+		return err
+	})
+	return data, err
+}
+
+// call runs the given operation against the wrapped StateManager,
+// unless the circuit is open, in which case it either fails fast
+// or runs the operation against the in-memory fallback.
+func (c *circuitBreakerStateManager) call(op func(sm StateManager) error) error {
+	c.mu.Lock()
+	if c.fallingBack {
+		c.mu.Unlock()
+		return op(c.fallback)
+	}
+	if now := c.clock.Now(); now.Before(c.openUntil) {
+		openUntil := c.openUntil
+		if c.fallback == nil {
+			c.mu.Unlock()
+			return &StateManagerCircuitOpenError{OpenUntil: openUntil}
+		}
+		c.fallingBack = true
+		c.mu.Unlock()
+		if c.onFallback != nil {
+			c.onFallback()
+		}
+		return op(c.fallback)
+	}
+	c.mu.Unlock()
+
+	err := op(c.sm)
+
+	c.mu.Lock()
+	if err == nil {
+		c.failures = 0
+		c.mu.Unlock()
+		return nil
+	}
+	c.failures++
+	if c.failures < c.failureThreshold {
+		c.mu.Unlock()
+		return err
+	}
+	c.failures = 0
+	c.openUntil = c.clock.Now().Add(c.openDuration)
+	if c.fallback == nil {
+		c.mu.Unlock()
+		return err
+	}
+	// the operation that opens the circuit already falls back to memory.
+	c.fallingBack = true
+	c.mu.Unlock()
+	if c.onFallback != nil {
+		c.onFallback()
+	}
+	return op(c.fallback)
+}
+
+// reset stops falling back to memory, so that
+// a new execution uses the wrapped StateManager.
+func (c *circuitBreakerStateManager) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fallingBack = false
+	if c.fallback != nil {
+		c.fallback = NewInMemoryStateManager()
+	}
+}
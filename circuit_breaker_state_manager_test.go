@@ -0,0 +1,166 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingStateManager struct {
+	mockStateManager
+	calls int
+}
+
+func (c *countingStateManager) SetStepState(stepIndex int, success bool) error {
+	c.calls++
+	return c.mockStateManager.SetStepState(stepIndex, success)
+}
+
+func (c *countingStateManager) StepState(stepIndex int) (bool, error) {
+	c.calls++
+	return c.mockStateManager.StepState(stepIndex)
+}
+
+func TestCircuitBreakerStateManager(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(now)
+	sm := &countingStateManager{
+		mockStateManager: mockStateManager{
+			setStepStateErr: errors.New("connection refused"),
+			stepStateErr:    errors.New("connection refused"),
+		},
+	}
+	cb := newCircuitBreakerStateManager(sm, 2, time.Minute, clock)
+
+	// closed: failures reach the wrapped state manager.
+	require.EqualError(t, cb.SetStepState(0, true), "connection refused")
+	_, err := cb.StepState(0)
+	require.EqualError(t, err, "connection refused")
+	require.Equal(t, 2, sm.calls)
+
+	// open: calls fail fast.
+	err = cb.SetStepState(0, true)
+	var openErr *StateManagerCircuitOpenError
+	require.True(t, errors.As(err, &openErr))
+	require.Equal(t, now.Add(time.Minute), openErr.OpenUntil)
+	require.Equal(t, "state manager circuit open until 2024-01-01T00:01:00Z", err.Error())
+	_, err = cb.StepState(0)
+	require.True(t, errors.As(err, &openErr))
+	require.Equal(t, 2, sm.calls)
+
+	// closed again after the open duration.
+	clock.Advance(time.Minute)
+	sm.setStepStateErr = nil
+	require.Nil(t, cb.SetStepState(0, true))
+	require.Equal(t, 3, sm.calls)
+}
+
+func TestCircuitBreakerStateManager_Capabilities(t *testing.T) {
+	cb := newCircuitBreakerStateManager(&mockStateManager{}, 2, time.Minute, nil)
+	_, ok := stateManagerAs[NamedStateManager](cb)
+	require.False(t, ok)
+	_, ok = stateManagerAs[VersionedStateManager](cb)
+	require.False(t, ok)
+
+	inner := NewInMemoryStateManager()
+	cb = newCircuitBreakerStateManager(inner, 2, time.Minute, nil)
+	nm, ok := stateManagerAs[NamedStateManager](cb)
+	require.True(t, ok)
+	require.Nil(t, nm.SetNamedStepState("reserve", true))
+	success, err := inner.NamedStepState("reserve")
+	require.Nil(t, err)
+	require.True(t, success)
+
+	vm, ok := stateManagerAs[NamedVersionedStateManager](cb)
+	require.True(t, ok)
+	_, err = vm.SetNamedStepStateVersioned("reserve", false, 0)
+	var conflictErr *ConcurrentModificationError
+	require.True(t, errors.As(err, &conflictErr))
+	_, version, err := vm.NamedStepStateWithVersion("reserve")
+	require.Nil(t, err)
+	require.Equal(t, 1, version)
+}
+
+func TestSaga_StateManagerCircuitBreaker(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       []Option
+		expectedError string
+	}{
+		{
+			name: "without fallback",
+			options: []Option{
+				WithStateManagerCircuitBreaker(1, time.Minute),
+			},
+			expectedError: "setting state for step step1: connection refused",
+		},
+		{
+			name: "with fallback",
+			options: []Option{
+				WithStateManagerCircuitBreaker(1, time.Minute),
+				WithFallbackToMemoryOnCircuitOpen(),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sm := &countingStateManager{
+				mockStateManager: mockStateManager{
+					setStepStateErr: errors.New("connection refused"),
+				},
+			}
+			opts := append([]Option{WithStateManager(sm)}, tc.options...)
+			saga := New(opts...)
+			saga.AddStep(NewStep("step1", noop, noop))
+			saga.AddStep(NewStep("step2", noop, noop))
+			err := saga.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestSaga_FallbackToMemoryOnCircuitOpen(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sm := &countingStateManager{
+		mockStateManager: mockStateManager{
+			stepStateErr: errors.New("connection refused"),
+		},
+	}
+	s := New(
+		WithStateManager(sm),
+		WithStateManagerCircuitBreaker(1, time.Minute),
+		WithFallbackToMemoryOnCircuitOpen(),
+		WithClock(clock),
+	)
+	executed := 0
+	for _, name := range []string{"step1", "step2", "step3"} {
+		s.AddStep(NewStep(name,
+			func(ctx context.Context) error {
+				executed++
+				return nil
+			},
+			noop,
+		))
+	}
+	require.Nil(t, s.Execute(context.Background()))
+	require.Equal(t, 3, executed)
+	require.Equal(t, 1, sm.calls)
+
+	// a new execution tries the wrapped state manager
+	// again once the circuit closes.
+	clock.Advance(time.Minute)
+	require.Nil(t, s.Execute(context.Background()))
+	require.Equal(t, 6, executed)
+	require.Equal(t, 2, sm.calls)
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Clock abstracts the passing of time in the timeout and retry delay
+// logic of steps, so that tests can control it instead of waiting.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the duration to elapse and then
+	// sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// Sleep pauses the current goroutine for the given duration.
+	Sleep(d time.Duration)
+
+	// NewTimer creates a Timer that sends the current time on its
+	// channel after the duration elapses, unless it is stopped.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event timer created by Clock.NewTimer.
+// Unlike the channel returned by Clock.After, it can be
+// stopped to release its resources before it fires.
+type Timer interface {
+	// C returns the channel on which the time is sent
+	// when the timer fires.
+	C() <-chan time.Time
+
+	// Stop prevents the timer from firing. It returns false
+	// if the timer already fired or was already stopped.
+	Stop() bool
+}
+
+// SystemClock is the Clock backed by the time package.
+// It is the default clock.
+type SystemClock struct{}
+
+func (SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (SystemClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (SystemClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (SystemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{timer: time.NewTimer(d)}
+}
+
+// systemTimer is the Timer of SystemClock.
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// WithClock option sets the clock used by the timeout and retry delay
+// logic of all the steps of the Saga, unless overridden by a step
+// through WithStepClock.
+func WithClock(c Clock) Option {
+	return func(s *saga) {
+		s.clock = c
+	}
+}
+
+// clockedSaga is implemented by Sagas
+// whose clock can be set (see WithClock).
+type clockedSaga interface {
+	// sagaClock returns the clock of the Saga, or nil if none was set.
+	sagaClock() Clock
+}
+
+func (s *saga) sagaClock() Clock {
+	return s.clock
+}
+
+// WithStepClock option sets the clock used by the timeout and retry
+// delay logic of the step, overriding the clock of the Saga.
+func WithStepClock(c Clock) StepOption {
+	return func(s *step) {
+		s.clock = c
+	}
+}
+
+// withClock returns a copy of ctx carrying the given clock,
+// or ctx itself if the clock is nil.
+func withClock(ctx context.Context, c Clock) context.Context {
+	if c == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, clockKey, c)
+}
+
+// clockFromContext returns the clock carried by
+// the given context, or SystemClock if none.
+func clockFromContext(ctx context.Context) Clock {
+	if c, ok := ctx.Value(clockKey).(Clock); ok {
+		return c
+	}
+	return SystemClock{}
+}
+
+// withClockTimeout returns a copy of ctx that is canceled, with
+// context.DeadlineExceeded, once the given duration elapses according
+// to the clock of ctx. With SystemClock, it is context.WithTimeout.
+// With other clocks, the returned context reports the deadline in the
+// time of the clock and is canceled by a timer of the clock.
+func withClockTimeout(ctx context.Context, d time.Duration) (context.Context, context.CancelFunc) {
+	clock := clockFromContext(ctx)
+	if _, ok := clock.(SystemClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+	deadline := clock.Now().Add(d)
+	cctx, cancel := context.WithCancelCause(ctx)
+	timer := clock.NewTimer(d)
+	go func() {
+		select {
+		case <-timer.C():
+			cancel(context.DeadlineExceeded)
+		case <-cctx.Done():
+			timer.Stop()
+		}
+	}()
+	return &clockDeadlineContext{Context: cctx, deadline: deadline}, func() { cancel(context.Canceled) }
+}
+
+// clockDeadlineContext is a context canceled by a timer of a Clock
+// other than SystemClock, reporting its deadline (see withClockTimeout).
+type clockDeadlineContext struct {
+	context.Context
+	deadline time.Time
+}
+
+func (c *clockDeadlineContext) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *clockDeadlineContext) Err() error {
+	err := c.Context.Err()
+	if err != nil && errors.Is(context.Cause(c.Context), context.DeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
+
+// FakeClock is a Clock whose time only passes when told to,
+// through Advance and Tick. It is meant for tests.
+type FakeClock struct {
+	mu      sync.Mutex
+	now     time.Time
+	waiters []*fakeWaiter
+}
+
+// fakeWaiter is a pending call to FakeClock.After,
+// or a pending Timer of the FakeClock.
+type fakeWaiter struct {
+	deadline time.Time
+	ch       chan time.Time
+}
+
+// NewFakeClock creates a new FakeClock set to the given time.
+func NewFakeClock(initial time.Time) *FakeClock {
+	return &FakeClock{now: initial}
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w := &fakeWaiter{deadline: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		w.ch <- c.now
+		return &fakeTimer{clock: c, waiter: w}
+	}
+	c.waiters = append(c.waiters, w)
+	sort.SliceStable(c.waiters, func(i, j int) bool {
+		return c.waiters[i].deadline.Before(c.waiters[j].deadline)
+	})
+	return &fakeTimer{clock: c, waiter: w}
+}
+
+// fakeTimer is the Timer of FakeClock.
+type fakeTimer struct {
+	clock  *FakeClock
+	waiter *fakeWaiter
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.waiter.ch
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	for i, w := range t.clock.waiters {
+		if w == t.waiter {
+			t.clock.waiters = append(t.clock.waiters[:i], t.clock.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance moves the clock forward by the given duration, firing the
+// pending calls to After whose duration has elapsed.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.advanceTo(c.now.Add(d))
+}
+
+// Tick moves the clock forward to the deadline of the earliest pending
+// call to After, firing it. It does nothing if there is none.
+func (c *FakeClock) Tick() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.waiters) == 0 {
+		return
+	}
+	c.advanceTo(c.waiters[0].deadline)
+}
+
+// Waiters returns the number of pending calls to After and
+// pending timers, so that tests can wait for the code under test to block
+// on the clock before advancing it.
+func (c *FakeClock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.waiters)
+}
+
+// advanceTo sets the clock to the given time and fires the
+// elapsed waiters. It must be called with c.mu held.
+func (c *FakeClock) advanceTo(now time.Time) {
+	c.now = now
+	i := 0
+	for ; i < len(c.waiters) && !c.waiters[i].deadline.After(now); i++ {
+		c.waiters[i].ch <- now
+	}
+	c.waiters = c.waiters[i:]
+}
@@ -0,0 +1,216 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeClock(t *testing.T) {
+	initial := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(initial)
+
+	first := clock.After(time.Minute)
+	second := clock.After(time.Hour)
+	require.Equal(t, 2, clock.Waiters())
+
+	clock.Advance(30 * time.Second)
+	require.Equal(t, initial.Add(30*time.Second), clock.Now())
+	require.Len(t, first, 0)
+
+	clock.Tick()
+	require.Equal(t, initial.Add(time.Minute), <-first)
+	require.Len(t, second, 0)
+
+	clock.Advance(2 * time.Hour)
+	require.Equal(t, initial.Add(time.Minute+2*time.Hour), <-second)
+	require.Equal(t, 0, clock.Waiters())
+
+	// Ticking without pending calls does nothing.
+	clock.Tick()
+	require.Equal(t, initial.Add(time.Minute+2*time.Hour), clock.Now())
+}
+
+func TestFakeClock_Timer(t *testing.T) {
+	initial := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(initial)
+
+	stopped := clock.NewTimer(time.Minute)
+	fired := clock.NewTimer(time.Hour)
+	require.Equal(t, 2, clock.Waiters())
+
+	require.True(t, stopped.Stop())
+	require.False(t, stopped.Stop())
+	require.Equal(t, 1, clock.Waiters())
+
+	clock.Advance(time.Hour)
+	require.Equal(t, initial.Add(time.Hour), <-fired.C())
+	require.Len(t, stopped.C(), 0)
+	require.False(t, fired.Stop())
+	require.Equal(t, 0, clock.Waiters())
+}
+
+// tickUntilDone ticks the given clock whenever something
+// waits on it, until done is closed.
+func tickUntilDone(clock *FakeClock, done <-chan struct{}) {
+	for {
+		select {
+		case <-done:
+			return
+		default:
+			clock.Tick()
+			time.Sleep(time.Millisecond)
+		}
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	testCases := []struct {
+		name      string
+		stepClock bool
+	}{
+		{
+			name: "saga clock",
+		},
+		{
+			name:      "step clock",
+			stepClock: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Now())
+			var sagaOpts []Option
+			stepOpts := []StepOption{WithRetry(3, time.Hour)}
+			if tc.stepClock {
+				stepOpts = append(stepOpts, WithStepClock(clock))
+			} else {
+				sagaOpts = append(sagaOpts, WithClock(clock))
+			}
+			attempts := 0
+			saga := New(sagaOpts...)
+			saga.AddStep(NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					attempts++
+					if attempts < 3 {
+						return errors.New("step1 error")
+					}
+					return nil
+				},
+				noop,
+				stepOpts...,
+			))
+			done := make(chan struct{})
+			go tickUntilDone(clock, done)
+			start := time.Now()
+			err := saga.Execute(context.Background())
+			close(done)
+			require.Nil(t, err)
+			require.Equal(t, 3, attempts)
+			require.Less(t, time.Since(start), time.Minute)
+		})
+	}
+}
+
+func TestWithClock_Timeouts(t *testing.T) {
+	testCases := []struct {
+		name          string
+		step          func(block <-chan struct{}) Step
+		compensate    bool
+		expectedError string
+	}{
+		{
+			name: "timeout step",
+			step: func(block <-chan struct{}) Step {
+				return NewTimeoutStep("step1", time.Hour, func(ctx context.Context) error {
+					<-block
+					return nil
+				}, noop)
+			},
+			expectedError: "step step1 timed out after 1h0m0s",
+		},
+		{
+			name: "compensation timeout",
+			step: func(block <-chan struct{}) Step {
+				return NewStepWithOptions("step1", noop, func(ctx context.Context) error {
+					<-block
+					return nil
+				}, WithCompensationTimeout(time.Hour))
+			},
+			compensate:    true,
+			expectedError: "compensation of step step1 timed out after 1h0m0s",
+		},
+		{
+			name: "signaled step",
+			step: func(block <-chan struct{}) Step {
+				return NewSignaledStep("step1", noop, noop, block, time.Hour)
+			},
+			expectedError: "approval rejected for step step1: approval timed out after 1h0m0s",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Now())
+			block := make(chan struct{})
+			defer close(block)
+			step := tc.step(block)
+			ctx := withClock(context.Background(), clock)
+			done := make(chan struct{})
+			go tickUntilDone(clock, done)
+			var err error
+			if tc.compensate {
+				err = step.ExecuteCompensate(ctx)
+			} else {
+				err = step.ExecuteForward(ctx)
+			}
+			close(done)
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedError, err.Error())
+		})
+	}
+}
+
+func TestWithClock_CompensationDeadline(t *testing.T) {
+	testCases := []struct {
+		name  string
+		clock Clock
+	}{
+		{
+			name:  "system clock",
+			clock: SystemClock{},
+		},
+		{
+			name:  "fake clock",
+			clock: NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var deadline time.Time
+			var hasDeadline bool
+			step := NewStepWithOptions("step1", noop, func(ctx context.Context) error {
+				deadline, hasDeadline = ctx.Deadline()
+				return nil
+			}, WithCompensationTimeout(time.Hour))
+			ctx := withClock(context.Background(), tc.clock)
+			before := tc.clock.Now()
+			err := step.ExecuteCompensate(ctx)
+			require.Nil(t, err)
+			require.True(t, hasDeadline)
+			require.False(t, deadline.Before(before.Add(time.Hour)))
+			require.False(t, deadline.After(tc.clock.Now().Add(time.Hour)))
+			if fake, ok := tc.clock.(*FakeClock); ok {
+				require.Eventually(t, func() bool {
+					return fake.Waiters() == 0
+				}, time.Second, time.Millisecond)
+			}
+		})
+	}
+}
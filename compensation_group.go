@@ -0,0 +1,133 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import "context"
+
+// WithCompensationGroup option makes the step part of the named
+// compensation group. The steps of a group are compensated concurrently,
+// while the groups, and the steps that are not part of any group, are
+// compensated sequentially in reverse execution order. A group is
+// compensated at the position of its last executed step. This is a
+// middle ground between the default sequential compensation and
+// WithParallelCompensation, which takes precedence over the groups.
+func WithCompensationGroup(groupName string) StepOption {
+	return func(s *step) {
+		s.compensationGroup = groupName
+	}
+}
+
+// CompensationGroup configures a named compensation group
+// (see WithCompensationGroup). It is built with a
+// CompensationGroupBuilder.
+type CompensationGroup struct {
+	// Name is the name of the group.
+	Name string
+
+	// Concurrency is the maximum number of steps of the group
+	// compensated at the same time (no limit if <= 0).
+	Concurrency int
+}
+
+// CompensationGroupBuilder builds a CompensationGroup.
+type CompensationGroupBuilder struct {
+	group CompensationGroup
+}
+
+// NewCompensationGroupBuilder creates a new CompensationGroupBuilder
+// for the named group, with no concurrency limit.
+func NewCompensationGroupBuilder(groupName string) *CompensationGroupBuilder {
+	return &CompensationGroupBuilder{group: CompensationGroup{Name: groupName}}
+}
+
+// WithGroupConcurrency sets the maximum number of steps of the group
+// compensated at the same time (no limit if n <= 0).
+func (b *CompensationGroupBuilder) WithGroupConcurrency(n int) *CompensationGroupBuilder {
+	b.group.Concurrency = n
+	return b
+}
+
+// Build returns the configured CompensationGroup.
+func (b *CompensationGroupBuilder) Build() CompensationGroup {
+	return b.group
+}
+
+// WithCompensationGroups option configures the given compensation
+// groups. Groups that are not configured have no concurrency limit.
+func WithCompensationGroups(groups ...CompensationGroup) Option {
+	return func(s *saga) {
+		if s.compensationGroupLimits == nil {
+			s.compensationGroupLimits = make(map[string]int)
+		}
+		for _, g := range groups {
+			s.compensationGroupLimits[g.Name] = g.Concurrency
+		}
+	}
+}
+
+// compensationGroupedStep is implemented by steps
+// that can be part of a compensation group.
+type compensationGroupedStep interface {
+	// compensationGroupName returns the name of the compensation
+	// group of the step, or an empty string if it has none.
+	compensationGroupName() string
+}
+
+func (s *step) compensationGroupName() string {
+	return s.compensationGroup
+}
+
+// compensationGroupOf returns the name of the compensation group
+// of the given step, or an empty string if it has none.
+func compensationGroupOf(step Step) string {
+	if g, ok := stepAs[compensationGroupedStep](step); ok {
+		return g.compensationGroupName()
+	}
+	return ""
+}
+
+// compensationUnits splits the given step indexes, in reverse execution
+// order, into the units compensated sequentially: one per compensation
+// group, at the position of its last executed step, and one per step
+// that is not part of any group. It returns nil if no step is part of
+// a group.
+func (s *saga) compensationUnits(indexes []int) [][]int {
+	var units [][]int
+	unitOfGroup := make(map[string]int)
+	for _, i := range indexes {
+		group := compensationGroupOf(s.stepAt(i))
+		if group == "" {
+			units = append(units, []int{i})
+			continue
+		}
+		if u, ok := unitOfGroup[group]; ok {
+			units[u] = append(units[u], i)
+			continue
+		}
+		unitOfGroup[group] = len(units)
+		units = append(units, []int{i})
+	}
+	if len(unitOfGroup) == 0 {
+		return nil
+	}
+	return units
+}
+
+// compensateByGroup compensates the given units sequentially, the
+// steps of each unit concurrently, returning the compensation errors.
+func (s *saga) compensateByGroup(ctx context.Context, units [][]int) []error {
+	var compensationErrors []error
+	for _, unit := range units {
+		if len(unit) == 1 {
+			if err := s.compensateStep(ctx, unit[0]); err != nil {
+				compensationErrors = append(compensationErrors, err)
+			}
+			continue
+		}
+		limit := s.compensationGroupLimits[compensationGroupOf(s.stepAt(unit[0]))]
+		compensationErrors = append(compensationErrors, s.compensateConcurrently(ctx, unit, limit)...)
+	}
+	return compensationErrors
+}
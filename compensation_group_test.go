@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCompensationGroup(t *testing.T) {
+	testCases := []struct {
+		name        string
+		options     []Option
+		expectedMax int
+	}{
+		{
+			name:        "unbounded groups",
+			expectedMax: 3,
+		},
+		{
+			name: "bounded groups",
+			options: []Option{WithCompensationGroups(
+				NewCompensationGroupBuilder("payments").WithGroupConcurrency(2).Build(),
+				NewCompensationGroupBuilder("inventory").WithGroupConcurrency(1).Build(),
+			)},
+			expectedMax: 2,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := &concurrencyTracker{}
+			saga := New(tc.options...)
+			steps := []struct{ name, group string }{
+				{"reserve1", "inventory"},
+				{"reserve2", "inventory"},
+				{"notify", ""},
+				{"charge1", "payments"},
+				{"charge2", "payments"},
+				{"charge3", "payments"},
+			}
+			for _, st := range steps {
+				saga.AddStep(NewStepWithOptions(st.name, noop, tracker.compensate(st.name, nil),
+					WithCompensationGroup(st.group)))
+			}
+			saga.AddStep(NewStep("ship",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				noop,
+			))
+			err := saga.Execute(context.Background())
+			require.EqualError(t, err, "executing step ship: forward error")
+			require.Equal(t, tc.expectedMax, tracker.max)
+			require.Len(t, tracker.order, 6)
+			require.ElementsMatch(t, []string{"charge1", "charge2", "charge3"}, tracker.order[0:3])
+			require.Equal(t, "notify", tracker.order[3])
+			require.ElementsMatch(t, []string{"reserve1", "reserve2"}, tracker.order[4:6])
+		})
+	}
+}
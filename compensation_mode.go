@@ -0,0 +1,55 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+// CompensationMode states whether a step is
+// expected to have a compensation action.
+type CompensationMode int
+
+const (
+	// CompensationRequired requires the step to have a compensation
+	// action. It is the default mode.
+	CompensationRequired CompensationMode = iota
+
+	// CompensationOptional allows the step not to have a compensation
+	// action, in which case compensating it is a no-op.
+	CompensationOptional
+
+	// CompensationForbidden requires the step not to have a
+	// compensation action, e.g. for one-way steps.
+	CompensationForbidden
+)
+
+// compensationChecker is implemented by steps that
+// check their compensation action against their
+// compensation mode.
+type compensationChecker interface {
+	// checkCompensation returns an error if the compensation
+	// action of the step does not match its compensation mode.
+	checkCompensation() error
+}
+
+func (s *step) checkCompensation() error {
+	switch {
+	case s.compensationMode == CompensationRequired && s.compensate == nil:
+		return &MissingCompensationError{StepName: s.name}
+	case s.compensationMode == CompensationForbidden && s.compensate != nil:
+		return &UnexpectedCompensationError{StepName: s.name}
+	}
+	return nil
+}
+
+// checkCompensations checks the compensation action of every
+// step against its compensation mode, before executing any.
+func (s *saga) checkCompensations() error {
+	for _, step := range s.steps {
+		if c, ok := stepAs[compensationChecker](step); ok {
+			if err := c.checkCompensation(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCompensationMode(t *testing.T) {
+	testCases := []struct {
+		name            string
+		compensate      func(ctx context.Context) error
+		opts            []StepOption
+		expectedForward int
+		expectedError   string
+	}{
+		{
+			name:            "required with compensation",
+			compensate:      noop,
+			expectedForward: 1,
+			expectedError:   "executing step step2: forward error",
+		},
+		{
+			name:          "required without compensation",
+			expectedError: "step step1 has no compensation",
+		},
+		{
+			name:            "optional without compensation",
+			opts:            []StepOption{WithCompensationMode(CompensationOptional)},
+			expectedForward: 1,
+			expectedError:   "executing step step2: forward error",
+		},
+		{
+			name:            "forbidden without compensation",
+			opts:            []StepOption{WithCompensationMode(CompensationForbidden)},
+			expectedForward: 1,
+			expectedError:   "executing step step2: forward error",
+		},
+		{
+			name:          "forbidden with compensation",
+			compensate:    noop,
+			opts:          []StepOption{WithCompensationMode(CompensationForbidden)},
+			expectedError: "step step1 has a compensation but its compensation mode forbids it",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			forward := 0
+			s := New()
+			s.AddStep(NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					forward++
+					return nil
+				},
+				tc.compensate,
+				tc.opts...,
+			))
+			s.AddStep(NewStep("step2",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				noop,
+			))
+			require.EqualError(t, s.Execute(context.Background()), tc.expectedError)
+			require.Equal(t, tc.expectedForward, forward)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+)
+
+// ComposeForward returns a forward action that executes
+// each of the given functions in sequence, stopping at the first error.
+func ComposeForward(funcs ...func(ctx context.Context) error) func(ctx context.Context) error {
+	return compose(funcs)
+}
+
+// ComposeCompensate returns a compensation action that executes
+// each of the given functions in sequence, stopping at the first error.
+// Functions are executed in the given order, so callers wanting to undo
+// a composed forward action should pass them in reverse.
+func ComposeCompensate(funcs ...func(ctx context.Context) error) func(ctx context.Context) error {
+	return compose(funcs)
+}
+
+// FanOutForward returns a forward action that executes all the given
+// functions concurrently and waits for them to finish. If any of them
+// fails, a *MultiError holding all errors, in the order of the functions,
+// is returned.
+func FanOutForward(funcs ...func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		errs := make([]error, len(funcs))
+		var wg sync.WaitGroup
+		for i, fn := range funcs {
+			wg.Add(1)
+			go func(i int, fn func(ctx context.Context) error) {
+				defer wg.Done()
+				errs[i] = fn(ctx)
+			}(i, fn)
+		}
+		wg.Wait()
+		var multiErr MultiError
+		for _, err := range errs {
+			if err != nil {
+				multiErr.Errors = append(multiErr.Errors, err)
+			}
+		}
+		if len(multiErr.Errors) > 0 {
+			return &multiErr
+		}
+		return nil
+	}
+}
+
+// compose returns a function that executes each of the given
+// functions in sequence, stopping at the first error.
+func compose(funcs []func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		for _, fn := range funcs {
+			if err := fn(ctx); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
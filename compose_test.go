@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComposeForward(t *testing.T) {
+	var calls []string
+	record := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			calls = append(calls, name)
+			return err
+		}
+	}
+	testCases := []struct {
+		name          string
+		funcs         []func(ctx context.Context) error
+		expectedCalls []string
+		expectedError error
+	}{
+		{
+			name:          "all succeed",
+			funcs:         []func(ctx context.Context) error{record("validate", nil), record("persist", nil), record("notify", nil)},
+			expectedCalls: []string{"validate", "persist", "notify"},
+		},
+		{
+			name:          "stops at first error",
+			funcs:         []func(ctx context.Context) error{record("validate", nil), record("persist", errors.New("persist error")), record("notify", nil)},
+			expectedCalls: []string{"validate", "persist"},
+			expectedError: errors.New("persist error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls = nil
+			err := ComposeForward(tc.funcs...)(context.Background())
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError.Error(), err.Error())
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestFanOutForward(t *testing.T) {
+	var mu sync.Mutex
+	calls := 0
+	fn := func(err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			return err
+		}
+	}
+	err := FanOutForward(fn(nil), fn(errors.New("error 1")), fn(errors.New("error 2")))(context.Background())
+	require.Equal(t, 3, calls)
+	var multiErr *MultiError
+	require.True(t, errors.As(err, &multiErr))
+	require.Equal(t, "[error 1 error 2]", multiErr.Error())
+
+	require.Nil(t, FanOutForward(fn(nil), fn(nil))(context.Background()))
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the root of a Saga definition loaded from a file.
+type Config struct {
+	Saga SagaConfig `json:"saga" yaml:"saga"`
+}
+
+// SagaConfig describes a Saga and its steps.
+type SagaConfig struct {
+	ID      string       `json:"id" yaml:"id"`
+	Version int          `json:"version" yaml:"version"`
+	Steps   []StepConfig `json:"steps" yaml:"steps"`
+}
+
+// StepConfig describes a single step of a Saga.
+// Type must be registered in the StepRegistry used to load the Saga.
+type StepConfig struct {
+	Name   string         `json:"name" yaml:"name"`
+	Type   string         `json:"type" yaml:"type"`
+	Params map[string]any `json:"params" yaml:"params"`
+}
+
+// LoadSagaFromYAML creates a Saga from the YAML file at the given path.
+// Steps are built using the given registry. The saga ID from the file
+// is applied before the provided options.
+func LoadSagaFromYAML(path string, registry *StepRegistry, opts ...Option) (Saga, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading file %s", path)
+	}
+	var cfg Config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, errors.Wrapf(err, "decoding yaml file %s", path)
+	}
+	return NewFromConfig(cfg, registry, opts...)
+}
+
+// LoadSagaFromJSON creates a Saga from the JSON file at the given path.
+// Steps are built using the given registry. The saga ID from the file
+// is applied before the provided options.
+func LoadSagaFromJSON(path string, registry *StepRegistry, opts ...Option) (Saga, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "reading file %s", path)
+	}
+	var cfg Config
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, errors.Wrapf(err, "decoding json file %s", path)
+	}
+	return NewFromConfig(cfg, registry, opts...)
+}
+
+// NewFromConfig creates a Saga from the given configuration,
+// building its steps with the given registry.
+func NewFromConfig(cfg Config, registry *StepRegistry, opts ...Option) (Saga, error) {
+	if registry == nil {
+		return nil, errors.New("step registry is required")
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, errors.Wrap(err, "validating config")
+	}
+	s := New(append([]Option{WithSagaID(cfg.Saga.ID)}, opts...)...)
+	for i, sc := range cfg.Saga.Steps {
+		step, err := registry.Build(sc.Type, sc.Name, sc.Params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "saga.steps[%d]", i)
+		}
+		s.AddStep(step)
+	}
+	return s, nil
+}
+
+// Validate checks that the configuration conforms to the expected schema.
+func (c Config) Validate() error {
+	if c.Saga.ID == "" {
+		return errors.New("saga.id is required")
+	}
+	if c.Saga.Version < 1 {
+		return errors.Errorf("saga.version must be greater than zero, got %d", c.Saga.Version)
+	}
+	if len(c.Saga.Steps) == 0 {
+		return errors.New("saga.steps must not be empty")
+	}
+	names := make(map[string]struct{}, len(c.Saga.Steps))
+	for i, sc := range c.Saga.Steps {
+		if sc.Name == "" {
+			return errors.Errorf("saga.steps[%d].name is required", i)
+		}
+		if sc.Type == "" {
+			return errors.Errorf("saga.steps[%d].type is required", i)
+		}
+		if _, exists := names[sc.Name]; exists {
+			return errors.Errorf("saga.steps[%d].name %q is duplicated", i, sc.Name)
+		}
+		names[sc.Name] = struct{}{}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+func TestLoadSaga(t *testing.T) {
+	registry := NewStepRegistry()
+	err := registry.Register("FooStep", func(name string, params map[string]any) (Step, error) {
+		return NewStep(name, noop, noop), nil
+	}, "key")
+	require.Nil(t, err)
+
+	testCases := []struct {
+		name string
+		file string
+		load func(path string, registry *StepRegistry, opts ...Option) (Saga, error)
+	}{
+		{name: "valid yaml", file: "valid.yaml", load: LoadSagaFromYAML},
+		{name: "valid json", file: "valid.json", load: LoadSagaFromJSON},
+		{name: "unknown step type", file: "unknown_step_type.yaml", load: LoadSagaFromYAML},
+		{name: "missing required param", file: "missing_param.yaml", load: LoadSagaFromYAML},
+		{name: "invalid version", file: "invalid_version.yaml", load: LoadSagaFromYAML},
+		{name: "missing step name", file: "missing_name.json", load: LoadSagaFromJSON},
+		{name: "unknown field", file: "unknown_field.json", load: LoadSagaFromJSON},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join("testdata", "config", tc.file)
+			saga, err := tc.load(path, registry)
+			var output string
+			if err != nil {
+				output = fmt.Sprintf("error: %v\n", err)
+			} else {
+				require.Nil(t, saga.Execute(context.Background()))
+				output = describeSaga(saga)
+			}
+			goldenFile := path + ".golden"
+			if *update {
+				require.Nil(t, os.WriteFile(goldenFile, []byte(output), 0644))
+			}
+			expected, err := os.ReadFile(goldenFile)
+			require.Nil(t, err)
+			require.Equal(t, string(expected), output)
+		})
+	}
+}
+
+// describeSaga returns a textual description of the given saga
+// to be compared against golden files.
+func describeSaga(s Saga) string {
+	var names []string
+	for _, step := range s.(*saga).steps {
+		names = append(names, step.Name())
+	}
+	return fmt.Sprintf("id: %s\nsteps: %s\n", s.ID(), strings.Join(names, ", "))
+}
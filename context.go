@@ -0,0 +1,142 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import "context"
+
+// contextKey is the type of the keys used by the Saga
+// to store values in the context passed to steps.
+type contextKey int
+
+const (
+	totalStepsKey contextKey = iota
+	currentStepIndexKey
+	correlationIDKey
+	traceHeadersKey
+	txKey
+	clockKey
+	sagaIDKey
+	dependencySummariesKey
+	forwardCorrelationIDKey
+)
+
+// SagaIDFromContext returns the identifier of the Saga executing
+// the step that received the given context, if it has one.
+func SagaIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(sagaIDKey).(string)
+	return id, ok
+}
+
+// TotalStepsFromContext returns the total number of steps of the
+// Saga executing the step that received the given context.
+func TotalStepsFromContext(ctx context.Context) (int, bool) {
+	total, ok := ctx.Value(totalStepsKey).(int)
+	return total, ok
+}
+
+// CurrentStepIndexFromContext returns the zero-based index of the
+// step that received the given context.
+func CurrentStepIndexFromContext(ctx context.Context) (int, bool) {
+	index, ok := ctx.Value(currentStepIndexKey).(int)
+	return index, ok
+}
+
+// withStepPosition returns a copy of ctx carrying the total
+// number of steps and the index of the current step.
+func withStepPosition(ctx context.Context, totalSteps, stepIndex int) context.Context {
+	ctx = context.WithValue(ctx, totalStepsKey, totalSteps)
+	return context.WithValue(ctx, currentStepIndexKey, stepIndex)
+}
+
+// withSagaID returns a copy of ctx carrying the given
+// Saga identifier, or ctx itself if it is empty.
+func withSagaID(ctx context.Context, id string) context.Context {
+	if id == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, sagaIDKey, id)
+}
+
+// ForwardContextValues returns a copy of child carrying the values
+// that parent holds for the given keys. Keys without a value in parent
+// are ignored. It is useful when work is run on a context that does not
+// derive from parent (e.g. a detached background context) but still
+// needs values such as trace IDs, user principals or tenant IDs.
+func ForwardContextValues(parent, child context.Context, keys ...any) context.Context {
+	for _, key := range keys {
+		if val := parent.Value(key); val != nil {
+			child = context.WithValue(child, key, val)
+		}
+	}
+	return child
+}
+
+// WithContextForwarder option makes the Saga copy the values that the
+// context passed to Execute or Compensate holds for the given keys (e.g.
+// trace IDs, user principals or tenant IDs) into each context it derives
+// for a step action, including the ones of compensations running
+// concurrently (see WithParallelCompensation). Forwarded values take
+// precedence over the values the derived context holds for the same
+// keys, e.g. when WithContextInheritance returns a context that does
+// not derive from the one passed to Execute. See ForwardContextValues.
+func WithContextForwarder(keys ...any) Option {
+	return func(s *saga) {
+		s.forwardedContextKeys = append(s.forwardedContextKeys, keys...)
+	}
+}
+
+// forwardContext returns a copy of child carrying the values that
+// parent holds for the keys set with WithContextForwarder, if any.
+func (s *saga) forwardContext(parent, child context.Context) context.Context {
+	if len(s.forwardedContextKeys) == 0 {
+		return child
+	}
+	return ForwardContextValues(parent, child, s.forwardedContextKeys...)
+}
+
+// WithContextInheritance option makes the Saga call the given function
+// before each step, with the context passed to Execute and the context
+// derived from it for the step, to merge additional values into the
+// latter. The step receives the returned context.
+// See ValueInheritance and FullValueInheritance.
+func WithContextInheritance(inherit func(parent, child context.Context) context.Context) Option {
+	return func(s *saga) {
+		s.contextInheritance = inherit
+	}
+}
+
+// ValueInheritance returns a context inheritance function, to be used
+// with WithContextInheritance, that copies the values parent holds for
+// the given keys into child.
+func ValueInheritance(keys ...any) func(parent, child context.Context) context.Context {
+	return func(parent, child context.Context) context.Context {
+		return ForwardContextValues(parent, child, keys...)
+	}
+}
+
+// FullValueInheritance returns a context inheritance function, to be
+// used with WithContextInheritance, that makes all the values of parent
+// visible through child. Since contexts cannot be introspected, values
+// are looked up in child first, then in parent. The deadline and
+// cancellation of child are kept.
+func FullValueInheritance() func(parent, child context.Context) context.Context {
+	return func(parent, child context.Context) context.Context {
+		return &inheritingContext{Context: child, parent: parent}
+	}
+}
+
+// inheritingContext is a context falling back
+// to the values of parent for missing values.
+type inheritingContext struct {
+	context.Context
+	parent context.Context
+}
+
+func (c *inheritingContext) Value(key any) any {
+	if val := c.Context.Value(key); val != nil {
+		return val
+	}
+	return c.parent.Value(key)
+}
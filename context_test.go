@@ -0,0 +1,169 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepPositionFromContext(t *testing.T) {
+	type position struct {
+		total, index int
+	}
+	positions := make(map[string]position)
+	saga := New()
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("step%d", i+1)
+		saga.AddStep(NewStep(name,
+			func(ctx context.Context) error {
+				total, ok := TotalStepsFromContext(ctx)
+				require.True(t, ok)
+				index, ok := CurrentStepIndexFromContext(ctx)
+				require.True(t, ok)
+				positions[name] = position{total: total, index: index}
+				return nil
+			},
+			noop,
+		))
+	}
+	require.Nil(t, saga.Execute(context.Background()))
+
+	testCases := []struct {
+		name             string
+		step             string
+		expectedPosition position
+	}{
+		{name: "first step", step: "step1", expectedPosition: position{total: 5, index: 0}},
+		{name: "middle step", step: "step3", expectedPosition: position{total: 5, index: 2}},
+		{name: "last step", step: "step5", expectedPosition: position{total: 5, index: 4}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedPosition, positions[tc.step])
+		})
+	}
+
+	_, ok := TotalStepsFromContext(context.Background())
+	require.False(t, ok)
+	_, ok = CurrentStepIndexFromContext(context.Background())
+	require.False(t, ok)
+}
+
+func TestForwardContextValues(t *testing.T) {
+	type key string
+	parent := context.WithValue(context.Background(), key("tenant"), "acme")
+	parent = context.WithValue(parent, key("trace"), "abc")
+	child := context.WithValue(context.Background(), key("user"), "john")
+
+	ctx := ForwardContextValues(parent, child, key("tenant"), key("missing"))
+	require.Equal(t, "acme", ctx.Value(key("tenant")))
+	require.Equal(t, "john", ctx.Value(key("user")))
+	require.Nil(t, ctx.Value(key("trace")))
+	require.Nil(t, ctx.Value(key("missing")))
+}
+
+func TestContextInheritance(t *testing.T) {
+	type key string
+	parent := context.WithValue(context.Background(), key("tenant"), "acme")
+	parent = context.WithValue(parent, key("trace"), "abc")
+	child, cancel := context.WithCancel(context.WithValue(context.Background(), key("tenant"), "globex"))
+	cancel()
+
+	testCases := []struct {
+		name           string
+		inherit        func(parent, child context.Context) context.Context
+		expectedTenant any
+		expectedTrace  any
+	}{
+		{
+			name:           "value inheritance",
+			inherit:        ValueInheritance(key("trace")),
+			expectedTenant: "globex",
+			expectedTrace:  "abc",
+		},
+		{
+			name:           "full value inheritance",
+			inherit:        FullValueInheritance(),
+			expectedTenant: "globex",
+			expectedTrace:  "abc",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := tc.inherit(parent, child)
+			require.Equal(t, tc.expectedTenant, ctx.Value(key("tenant")))
+			require.Equal(t, tc.expectedTrace, ctx.Value(key("trace")))
+			require.Nil(t, ctx.Value(key("missing")))
+			require.Equal(t, context.Canceled, ctx.Err())
+		})
+	}
+}
+
+func TestWithContextInheritance(t *testing.T) {
+	type key string
+	var parentIndexes, stepValues []any
+	saga := New(WithContextInheritance(func(parent, child context.Context) context.Context {
+		_, ok := CurrentStepIndexFromContext(parent)
+		parentIndexes = append(parentIndexes, ok)
+		index, _ := CurrentStepIndexFromContext(child)
+		return context.WithValue(child, key("inherited"), index)
+	}))
+	for i := 0; i < 2; i++ {
+		saga.AddStep(NewStep(fmt.Sprintf("step%d", i+1),
+			func(ctx context.Context) error {
+				stepValues = append(stepValues, ctx.Value(key("inherited")))
+				return nil
+			},
+			noop,
+		))
+	}
+	require.Nil(t, saga.Execute(context.Background()))
+	require.Equal(t, []any{false, false}, parentIndexes)
+	require.Equal(t, []any{0, 1}, stepValues)
+}
+
+func TestWithContextForwarder(t *testing.T) {
+	type key string
+	var mu sync.Mutex
+	values := map[string]any{}
+	record := func(name string) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			mu.Lock()
+			defer mu.Unlock()
+			values[name] = ctx.Value(key("tenant"))
+			return nil
+		}
+	}
+	saga := New(
+		WithContextForwarder(key("tenant")),
+		// The derived contexts do not inherit the values of the parent.
+		WithContextInheritance(func(parent, child context.Context) context.Context {
+			return context.WithValue(context.Background(), key("tenant"), "globex")
+		}),
+		WithParallelCompensation(0),
+	)
+	saga.AddStep(NewStep("step1", record("forward step1"), record("compensate step1")))
+	saga.AddStep(NewStep("step2", record("forward step2"), record("compensate step2")))
+	saga.AddStep(NewStep("step3",
+		func(ctx context.Context) error {
+			return errors.New("step3 error")
+		},
+		noop,
+	))
+	ctx := context.WithValue(context.Background(), key("tenant"), "acme")
+	require.NotNil(t, saga.Execute(ctx))
+	require.Equal(t, map[string]any{
+		"forward step1":    "acme",
+		"forward step2":    "acme",
+		"compensate step1": "acme",
+		"compensate step2": "acme",
+	}, values)
+}
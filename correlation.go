@@ -0,0 +1,40 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import "context"
+
+// WithCorrelationIDGenerator option sets a generator of correlation IDs.
+// Before the forward action of each step, a new correlation ID is
+// generated and injected into the context passed to the step (see
+// CorrelationIDFromContext). It is also logged at debug level, if a
+// logger is set, added to the step span, if a tracer is set, and
+// included in the StepProgress of the step.
+func WithCorrelationIDGenerator(gen func() string) Option {
+	return func(s *saga) {
+		s.correlationIDGen = gen
+	}
+}
+
+// CorrelationIDFromContext returns the correlation
+// ID of the step that received the given context.
+func CorrelationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(correlationIDKey).(string)
+	return id, ok
+}
+
+// withCorrelationID returns a copy of ctx carrying a new
+// correlation ID for the given step, which is the current one.
+func (s *saga) withCorrelationID(ctx context.Context, step Step) context.Context {
+	s.correlationID = ""
+	if s.correlationIDGen == nil {
+		return ctx
+	}
+	s.correlationID = s.correlationIDGen()
+	if s.logger != nil {
+		s.logger.DebugContext(ctx, "step correlation ID", "step", s.stepName(step), "correlationID", s.correlationID)
+	}
+	return context.WithValue(ctx, correlationIDKey, s.correlationID)
+}
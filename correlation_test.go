@@ -0,0 +1,52 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithCorrelationIDGenerator(t *testing.T) {
+	var buf bytes.Buffer
+	next := 0
+	var stepIDs, progressIDs []string
+	s := New(
+		WithCorrelationIDGenerator(func() string {
+			next++
+			return fmt.Sprintf("corr-%d", next)
+		}),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	s.(*saga).progressHooks = append(s.(*saga).progressHooks, func(ctx context.Context, p StepProgress) {
+		progressIDs = append(progressIDs, p.CorrelationID)
+	})
+	for _, name := range []string{"step1", "step2"} {
+		s.AddStep(NewStep(name,
+			func(ctx context.Context) error {
+				id, ok := CorrelationIDFromContext(ctx)
+				require.True(t, ok)
+				stepIDs = append(stepIDs, id)
+				return nil
+			},
+			noop,
+		))
+	}
+	require.Nil(t, s.Execute(context.Background()))
+	require.Equal(t, []string{"corr-1", "corr-2"}, stepIDs)
+	require.Equal(t, []string{"corr-1", "corr-2"}, progressIDs)
+	require.Contains(t, buf.String(), "correlationID=corr-2")
+}
+
+func TestCorrelationIDFromContext(t *testing.T) {
+	id, ok := CorrelationIDFromContext(context.Background())
+	require.False(t, ok)
+	require.Empty(t, id)
+}
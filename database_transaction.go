@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// WithDatabaseTransaction option makes Execute run all the steps within
+// a single transaction started with db.BeginTx and the given options.
+// Steps retrieve it with TxFromContext instead of opening their own
+// connections. The transaction is committed if the Saga succeeds, and
+// rolled back if it fails, in addition to the compensation of the steps,
+// or panics (the panic is propagated).
+func WithDatabaseTransaction(db *sql.DB, txOpts *sql.TxOptions) Option {
+	return func(s *saga) {
+		s.db = db
+		s.txOpts = txOpts
+	}
+}
+
+// TxFromContext returns the transaction of the Saga executing the step
+// that received the given context (see WithDatabaseTransaction).
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	tx, ok := ctx.Value(txKey).(*sql.Tx)
+	return tx, ok
+}
+
+// withDatabaseTransaction runs the given function within the
+// transaction of the Saga, if configured with WithDatabaseTransaction.
+func (s *saga) withDatabaseTransaction(ctx context.Context, run func(ctx context.Context) error) error {
+	if s.db == nil {
+		return run(ctx)
+	}
+	tx, err := s.db.BeginTx(ctx, s.txOpts)
+	if err != nil {
+		return errors.Wrap(err, "beginning saga transaction")
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err := run(context.WithValue(ctx, txKey, tx)); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return &MultiError{Errors: []error{
+				err,
+				errors.Wrap(rollbackErr, "rolling back saga transaction"),
+			}}
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrap(err, "committing saga transaction")
+	}
+	return nil
+}
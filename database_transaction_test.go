@@ -0,0 +1,102 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithDatabaseTransaction(t *testing.T) {
+	testCases := []struct {
+		name                string
+		driver              *txDriver
+		forwardErr          error
+		expectedCompensated bool
+		expectedError       string
+		expectedCommits     int
+		expectedRollbacks   int
+	}{
+		{
+			name:            "commit on success",
+			driver:          &txDriver{},
+			expectedCommits: 1,
+		},
+		{
+			name:                "rollback on failure",
+			driver:              &txDriver{},
+			forwardErr:          errors.New("forward error"),
+			expectedCompensated: true,
+			expectedError:       "executing step step2: forward error",
+			expectedRollbacks:   1,
+		},
+		{
+			name:          "begin error",
+			driver:        &txDriver{beginErr: errors.New("connection refused")},
+			expectedError: "beginning saga transaction: connection refused",
+		},
+		{
+			name:          "commit error",
+			driver:        &txDriver{commitErr: errors.New("serialization failure")},
+			expectedError: "committing saga transaction: serialization failure",
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			driverName := "sagatxdriver" + string(rune('a'+i))
+			sql.Register(driverName, tc.driver)
+			db, err := sql.Open(driverName, "")
+			require.Nil(t, err)
+			defer db.Close()
+
+			var txs []*sql.Tx
+			compensated := false
+			saga := New(WithDatabaseTransaction(db, nil))
+			saga.AddStep(NewStep("step1",
+				func(ctx context.Context) error {
+					tx, ok := TxFromContext(ctx)
+					require.True(t, ok)
+					txs = append(txs, tx)
+					return nil
+				},
+				func(ctx context.Context) error {
+					compensated = true
+					return nil
+				},
+			))
+			saga.AddStep(NewStep("step2",
+				func(ctx context.Context) error {
+					tx, ok := TxFromContext(ctx)
+					require.True(t, ok)
+					txs = append(txs, tx)
+					return tc.forwardErr
+				},
+				noop,
+			))
+			err = saga.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.Nil(t, err)
+			}
+			if len(txs) > 0 {
+				require.Len(t, txs, 2)
+				require.Same(t, txs[0], txs[1])
+			}
+			require.Equal(t, tc.expectedCompensated, compensated)
+			require.Equal(t, tc.expectedCommits, tc.driver.commits)
+			require.Equal(t, tc.expectedRollbacks, tc.driver.rollbacks)
+		})
+	}
+}
+
+func TestTxFromContext(t *testing.T) {
+	_, ok := TxFromContext(context.Background())
+	require.False(t, ok)
+}
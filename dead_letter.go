@@ -0,0 +1,174 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"time"
+)
+
+// DeadLetterItem describes a step whose compensation failed,
+// which needs to be handled out of band (e.g. retried later
+// or inspected by an operator).
+type DeadLetterItem struct {
+	SagaID    string
+	StepName  string
+	StepIndex int
+	Err       error
+	FailedAt  time.Time
+	// Attempts is the number of times the compensation of the step
+	// failed since the last execution of the Saga.
+	Attempts int
+	// FirstFailedAt is when the compensation of the step first
+	// failed since the last execution of the Saga.
+	FirstFailedAt time.Time
+}
+
+// DeadLetterHandler handles the steps whose compensation failed.
+type DeadLetterHandler interface {
+	// HandleDeadLetter handles the given dead letter item.
+	HandleDeadLetter(ctx context.Context, item DeadLetterItem) error
+}
+
+// WithDeadLetterHandler option sets the handler called for
+// each step whose compensation fails. Errors returned
+// by the handler are logged, if a logger is set.
+func WithDeadLetterHandler(h DeadLetterHandler) Option {
+	return func(s *saga) {
+		s.deadLetterHandler = h
+	}
+}
+
+// sendToDeadLetter hands the given step, whose compensation
+// failed, over to the dead letter handler, if any.
+func (s *saga) sendToDeadLetter(ctx context.Context, step Step, stepIndex int, compErr error) {
+	if s.deadLetterHandler == nil {
+		return
+	}
+	now := time.Now()
+	s.deadLetterMu.Lock()
+	failure := s.deadLetterFailures[stepIndex]
+	if failure.attempts == 0 {
+		failure.firstFailedAt = now
+	}
+	failure.attempts++
+	s.deadLetterFailures[stepIndex] = failure
+	s.deadLetterMu.Unlock()
+	item := DeadLetterItem{
+		SagaID:        s.id,
+		StepName:      s.stepName(step),
+		StepIndex:     stepIndex,
+		Err:           compErr,
+		FailedAt:      now,
+		Attempts:      failure.attempts,
+		FirstFailedAt: failure.firstFailedAt,
+	}
+	if err := s.deadLetterHandler.HandleDeadLetter(ctx, item); err != nil && s.logger != nil {
+		s.logger.WarnContext(ctx, "handling dead letter", "step", s.stepName(step), "error", err)
+	}
+}
+
+// deadLetterFailure tracks the failed compensations of a step.
+type deadLetterFailure struct {
+	attempts      int
+	firstFailedAt time.Time
+}
+
+// resetDeadLetterFailures clears the failed compensations
+// tracked at the start of an execution.
+func (s *saga) resetDeadLetterFailures() {
+	s.deadLetterMu.Lock()
+	defer s.deadLetterMu.Unlock()
+	s.deadLetterFailures = make(map[int]deadLetterFailure)
+}
+
+// DeadLetterRetryPolicy decides whether and when a consumer of dead
+// letter items retries the compensation of a Saga (see RetryDeadLetter).
+type DeadLetterRetryPolicy interface {
+	// ShouldRetry reports whether the compensation
+	// of the given item must be retried.
+	ShouldRetry(item DeadLetterItem) bool
+
+	// NextDelay returns the delay to wait before
+	// retrying the compensation of the given item.
+	NextDelay(item DeadLetterItem) time.Duration
+}
+
+// exponentialDLQPolicy is the DeadLetterRetryPolicy
+// returned by ExponentialDLQPolicy.
+type exponentialDLQPolicy struct {
+	maxAttempts int
+	baseDelay   time.Duration
+}
+
+// ExponentialDLQPolicy returns a DeadLetterRetryPolicy retrying the
+// compensation until it failed maxAttempts times, waiting baseDelay
+// after the first failure, then doubling the delay after each one.
+func ExponentialDLQPolicy(maxAttempts int, baseDelay time.Duration) DeadLetterRetryPolicy {
+	return &exponentialDLQPolicy{maxAttempts: maxAttempts, baseDelay: baseDelay}
+}
+
+func (p *exponentialDLQPolicy) ShouldRetry(item DeadLetterItem) bool {
+	return item.Attempts < p.maxAttempts
+}
+
+func (p *exponentialDLQPolicy) NextDelay(item DeadLetterItem) time.Duration {
+	attempts := item.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	return p.baseDelay * time.Duration(1<<(attempts-1))
+}
+
+// timeBoundedDLQPolicy is the DeadLetterRetryPolicy
+// returned by TimeBoundedDLQPolicy.
+type timeBoundedDLQPolicy struct {
+	maxAge time.Duration
+	now    func() time.Time
+}
+
+// TimeBoundedDLQPolicy returns a DeadLetterRetryPolicy retrying the
+// compensation until maxAge has passed since it first failed. The delay
+// starts at a second and doubles after each failure, without going past
+// maxAge.
+func TimeBoundedDLQPolicy(maxAge time.Duration) DeadLetterRetryPolicy {
+	return &timeBoundedDLQPolicy{maxAge: maxAge, now: time.Now}
+}
+
+func (p *timeBoundedDLQPolicy) ShouldRetry(item DeadLetterItem) bool {
+	return p.now().Sub(item.FirstFailedAt) < p.maxAge
+}
+
+func (p *timeBoundedDLQPolicy) NextDelay(item DeadLetterItem) time.Duration {
+	attempts := item.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := time.Second * time.Duration(1<<(attempts-1))
+	if remaining := item.FirstFailedAt.Add(p.maxAge).Sub(p.now()); delay > remaining {
+		delay = remaining
+	}
+	if delay < 0 {
+		delay = 0
+	}
+	return delay
+}
+
+// RetryDeadLetter is meant to be called by a consumer of the dead
+// letter items of the given Saga. If the policy allows retrying the
+// compensation of the given item, it waits for the delay given by the
+// policy and calls Compensate, which skips the steps already
+// compensated, reporting true along with its error. Otherwise, it
+// reports false. It returns early with the context error if the
+// context is done while waiting.
+func RetryDeadLetter(ctx context.Context, s Saga, item DeadLetterItem, policy DeadLetterRetryPolicy) (bool, error) {
+	if !policy.ShouldRetry(item) {
+		return false, nil
+	}
+	if err := sleepContext(ctx, policy.NextDelay(item)); err != nil {
+		return false, err
+	}
+	return true, s.Compensate(ctx)
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type mockDeadLetterHandler struct {
+	items []DeadLetterItem
+	err   error
+}
+
+func (m *mockDeadLetterHandler) HandleDeadLetter(ctx context.Context, item DeadLetterItem) error {
+	m.items = append(m.items, item)
+	return m.err
+}
+
+func TestWithDeadLetterHandler(t *testing.T) {
+	testCases := []struct {
+		name        string
+		handlerErr  error
+		expectedLog string
+	}{
+		{
+			name: "happy path",
+		},
+		{
+			name:        "handler error",
+			handlerErr:  errors.New("queue unavailable"),
+			expectedLog: "handling dead letter",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			handler := &mockDeadLetterHandler{err: tc.handlerErr}
+			saga := New(
+				WithSagaID("order-42"),
+				WithDeadLetterHandler(handler),
+				WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+			)
+			saga.AddStep(NewStep("step1",
+				noop,
+				func(ctx context.Context) error {
+					return errors.New("compensation error")
+				},
+			))
+			saga.AddStep(NewStep("step2",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				noop,
+			))
+			require.NotNil(t, saga.Execute(context.Background()))
+			require.Len(t, handler.items, 1)
+			item := handler.items[0]
+			require.Equal(t, "order-42", item.SagaID)
+			require.Equal(t, "step1", item.StepName)
+			require.Equal(t, 0, item.StepIndex)
+			require.EqualError(t, item.Err, "compensation error")
+			require.False(t, item.FailedAt.IsZero())
+			require.Contains(t, buf.String(), tc.expectedLog)
+		})
+	}
+}
+
+func TestDeadLetterRetryPolicies(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	exponential := ExponentialDLQPolicy(3, 100*time.Millisecond)
+	timeBounded := TimeBoundedDLQPolicy(time.Minute).(*timeBoundedDLQPolicy)
+	timeBounded.now = func() time.Time { return now }
+	testCases := []struct {
+		name          string
+		policy        DeadLetterRetryPolicy
+		item          DeadLetterItem
+		expectedRetry bool
+		expectedDelay time.Duration
+	}{
+		{
+			name:          "exponential, first failure",
+			policy:        exponential,
+			item:          DeadLetterItem{Attempts: 1},
+			expectedRetry: true,
+			expectedDelay: 100 * time.Millisecond,
+		},
+		{
+			name:          "exponential, second failure",
+			policy:        exponential,
+			item:          DeadLetterItem{Attempts: 2},
+			expectedRetry: true,
+			expectedDelay: 200 * time.Millisecond,
+		},
+		{
+			name:          "exponential, attempts exhausted",
+			policy:        exponential,
+			item:          DeadLetterItem{Attempts: 3},
+			expectedDelay: 400 * time.Millisecond,
+		},
+		{
+			name:          "time bounded, first failure",
+			policy:        timeBounded,
+			item:          DeadLetterItem{Attempts: 1, FirstFailedAt: now},
+			expectedRetry: true,
+			expectedDelay: time.Second,
+		},
+		{
+			name:          "time bounded, delay capped by max age",
+			policy:        timeBounded,
+			item:          DeadLetterItem{Attempts: 4, FirstFailedAt: now.Add(-55 * time.Second)},
+			expectedRetry: true,
+			expectedDelay: 5 * time.Second,
+		},
+		{
+			name:   "time bounded, too old",
+			policy: timeBounded,
+			item:   DeadLetterItem{Attempts: 1, FirstFailedAt: now.Add(-time.Minute)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedRetry, tc.policy.ShouldRetry(tc.item))
+			require.Equal(t, tc.expectedDelay, tc.policy.NextDelay(tc.item))
+		})
+	}
+}
+
+func TestRetryDeadLetter(t *testing.T) {
+	handler := &mockDeadLetterHandler{}
+	compensations := 0
+	saga := New(WithDeadLetterHandler(handler))
+	saga.AddStep(NewStep("step1",
+		noop,
+		func(ctx context.Context) error {
+			compensations++
+			if compensations < 3 {
+				return errors.New("compensation error")
+			}
+			return nil
+		},
+	))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("forward error")
+		},
+		noop,
+	))
+	require.NotNil(t, saga.Execute(context.Background()))
+	policy := ExponentialDLQPolicy(3, time.Millisecond)
+
+	// the consumer retries until the compensation succeeds.
+	for i := 0; len(handler.items) > i; i++ {
+		item := handler.items[i]
+		require.Equal(t, i+1, item.Attempts)
+		require.Equal(t, handler.items[0].FailedAt, item.FirstFailedAt)
+		retried, err := RetryDeadLetter(context.Background(), saga, item, policy)
+		require.True(t, retried)
+		if i == 0 {
+			require.NotNil(t, err)
+		} else {
+			require.Nil(t, err)
+		}
+	}
+	require.Len(t, handler.items, 2)
+	require.Equal(t, 3, compensations)
+
+	retried, err := RetryDeadLetter(context.Background(), saga, DeadLetterItem{Attempts: 3}, policy)
+	require.False(t, retried)
+	require.Nil(t, err)
+}
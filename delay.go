@@ -0,0 +1,20 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import "context"
+
+// withDelays runs the given action between the configured pre and post
+// execution delays. The post execution delay only applies if the action
+// succeeds. Both delays are interrupted if the context is done.
+func (s *step) withDelays(ctx context.Context, action func(ctx context.Context) error) error {
+	if err := sleepContext(ctx, s.preDelay); err != nil {
+		return err
+	}
+	if err := action(ctx); err != nil {
+		return err
+	}
+	return sleepContext(ctx, s.postDelay)
+}
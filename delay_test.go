@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStep_Delays(t *testing.T) {
+	const delay = 20 * time.Millisecond
+	testCases := []struct {
+		name            string
+		forwardErr      error
+		options         []StepOption
+		minForwardTime  time.Duration
+		minCompensation time.Duration
+		maxCompensation time.Duration
+		expectedError   error
+	}{
+		{
+			name:            "pre execution delay",
+			options:         []StepOption{WithPreExecutionDelay(delay)},
+			minForwardTime:  delay,
+			maxCompensation: delay,
+		},
+		{
+			name:            "post execution delay",
+			options:         []StepOption{WithPostExecutionDelay(delay)},
+			minForwardTime:  delay,
+			maxCompensation: delay,
+		},
+		{
+			name:            "delays applied before each attempt",
+			forwardErr:      errors.New("error"),
+			options:         []StepOption{WithPreExecutionDelay(delay), WithPostExecutionDelay(time.Hour), WithRetry(2, 0)},
+			minForwardTime:  2 * delay,
+			maxCompensation: delay,
+			expectedError:   errors.New("error"),
+		},
+		{
+			name: "delayed compensation",
+			options: []StepOption{
+				WithPreExecutionDelay(delay),
+				WithPostExecutionDelay(delay),
+				WithDelayCompensation(true),
+			},
+			minForwardTime:  2 * delay,
+			minCompensation: 2 * delay,
+			maxCompensation: time.Hour,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step := NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					return tc.forwardErr
+				},
+				noop,
+				tc.options...,
+			)
+			start := time.Now()
+			err := step.ExecuteForward(context.Background())
+			require.GreaterOrEqual(t, time.Since(start), tc.minForwardTime)
+			if tc.expectedError != nil {
+				require.EqualError(t, err, tc.expectedError.Error())
+			} else {
+				require.Nil(t, err)
+			}
+			start = time.Now()
+			require.Nil(t, step.ExecuteCompensate(context.Background()))
+			elapsed := time.Since(start)
+			require.GreaterOrEqual(t, elapsed, tc.minCompensation)
+			require.Less(t, elapsed, tc.maxCompensation)
+		})
+	}
+}
+
+func TestStep_DelayContextCancellation(t *testing.T) {
+	called := false
+	step := NewStepWithOptions("step1",
+		func(ctx context.Context) error {
+			called = true
+			return nil
+		},
+		noop,
+		WithPreExecutionDelay(time.Hour),
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := step.ExecuteForward(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.False(t, called)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// StepDiagnostic holds diagnostic information about
+// the last execution of a step action.
+type StepDiagnostic struct {
+	StartedAt   time.Time
+	CompletedAt time.Time
+
+	// Attempts is the number of attempts of the action in its last
+	// execution, retries included. It is zero if the execution failed
+	// before attempting the action.
+	Attempts int
+
+	Err     error
+	Skipped bool
+}
+
+// DiagnosticStep is a Step that records diagnostic
+// information about its executions.
+type DiagnosticStep interface {
+	Step
+
+	// LastForwardResult returns the diagnostic information
+	// of the last forward execution.
+	LastForwardResult() StepDiagnostic
+
+	// LastCompensateResult returns the diagnostic information
+	// of the last compensation execution.
+	LastCompensateResult() StepDiagnostic
+}
+
+// skippable is implemented by steps that want to be notified
+// when the Saga skips them because they were already completed.
+type skippable interface {
+	markSkipped()
+}
+
+// diagnosticStep is the concrete implementation of the DiagnosticStep interface.
+type diagnosticStep struct {
+	*step
+	forwardResult    StepDiagnostic
+	compensateResult StepDiagnostic
+	mu               sync.RWMutex
+}
+
+// NewDiagnosticStep creates a new DiagnosticStep instance with the provided
+// name, forward action, and compensation action.
+// It is safe to inspect its diagnostics while the Saga is running.
+func NewDiagnosticStep(name string, forward, compensate func(ctx context.Context) error) DiagnosticStep {
+	return &diagnosticStep{
+		step: newStep(name, forward, compensate, nil),
+	}
+}
+
+func (s *diagnosticStep) ExecuteForward(ctx context.Context) error {
+	s.step.setAttempts(0)
+	return s.record(&s.forwardResult, func() error {
+		return s.step.ExecuteForward(ctx)
+	}, s.step.attempts)
+}
+
+func (s *diagnosticStep) ExecuteCompensate(ctx context.Context) error {
+	return s.record(&s.compensateResult, func() error {
+		return s.step.ExecuteCompensate(ctx)
+	}, func() int { return 1 })
+}
+
+func (s *diagnosticStep) LastForwardResult() StepDiagnostic {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.forwardResult
+}
+
+func (s *diagnosticStep) LastCompensateResult() StepDiagnostic {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.compensateResult
+}
+
+func (s *diagnosticStep) markSkipped() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forwardResult.Skipped = true
+}
+
+// record runs the given action, updating the given diagnostic with
+// its timing, outcome and number of attempts, as returned by attempts
+// once the action returns.
+func (s *diagnosticStep) record(diag *StepDiagnostic, action func() error, attempts func() int) error {
+	s.mu.Lock()
+	diag.StartedAt = time.Now()
+	diag.CompletedAt = time.Time{}
+	diag.Attempts = 0
+	diag.Err = nil
+	diag.Skipped = false
+	s.mu.Unlock()
+
+	err := action()
+	n := attempts()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	diag.CompletedAt = time.Now()
+	diag.Attempts = n
+	diag.Err = err
+	return err
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiagnosticStep(t *testing.T) {
+	fail := true
+	step1 := NewDiagnosticStep("step1", noop, noop)
+	step2 := NewDiagnosticStep("step2",
+		func(ctx context.Context) error {
+			if fail {
+				return errors.New("step2 error")
+			}
+			return nil
+		},
+		noop,
+	)
+
+	saga := New(WithLazyCompensation())
+	saga.AddStep(step1)
+	saga.AddStep(step2)
+
+	// First run: step 2 fails.
+	require.NotNil(t, saga.Execute(context.Background()))
+	diag := step2.LastForwardResult()
+	require.Equal(t, 1, diag.Attempts)
+	require.Equal(t, "step2 error", diag.Err.Error())
+	require.False(t, diag.StartedAt.IsZero())
+	require.False(t, diag.CompletedAt.Before(diag.StartedAt))
+
+	// Second run: step 1 is skipped, step 2 succeeds.
+	fail = false
+	require.Nil(t, saga.Execute(context.Background()))
+	require.True(t, step1.LastForwardResult().Skipped)
+	require.Equal(t, 1, step1.LastForwardResult().Attempts)
+	diag = step2.LastForwardResult()
+	require.Equal(t, 1, diag.Attempts)
+	require.Nil(t, diag.Err)
+
+	// Compensation is recorded separately.
+	require.Nil(t, saga.Compensate(context.Background()))
+	require.Equal(t, 1, step2.LastCompensateResult().Attempts)
+	require.Equal(t, 1, step1.LastCompensateResult().Attempts)
+}
+
+func TestDiagnosticStep_RetryAttempts(t *testing.T) {
+	calls := 0
+	step := &diagnosticStep{
+		step: newStep("step1",
+			func(ctx context.Context) error {
+				calls++
+				if calls%3 != 0 {
+					return errors.New("step1 error")
+				}
+				return nil
+			},
+			noop,
+			[]StepOption{WithRetry(3, 0)},
+		),
+	}
+
+	// Attempts are counted per execution.
+	for i := 0; i < 2; i++ {
+		require.Nil(t, step.ExecuteForward(context.Background()))
+		require.Equal(t, 3, step.LastForwardResult().Attempts)
+	}
+}
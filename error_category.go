@@ -0,0 +1,66 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCategory classifies step errors for monitoring purposes,
+// e.g. to alert differently on transient and permanent failures.
+type ErrorCategory int
+
+const (
+	// CategoryUnknown is the category of errors that
+	// were not classified by an error categorizer.
+	CategoryUnknown ErrorCategory = iota
+
+	// CategoryTransient is the category of errors that
+	// may go away on their own (e.g. timeouts).
+	CategoryTransient
+
+	// CategoryPermanent is the category of errors that
+	// will not go away without intervention (e.g. invalid data).
+	CategoryPermanent
+)
+
+func (c ErrorCategory) String() string {
+	switch c {
+	case CategoryTransient:
+		return "transient"
+	case CategoryPermanent:
+		return "permanent"
+	default:
+		return "unknown"
+	}
+}
+
+// CategorizedError is returned by the steps with an error
+// categorizer (see WithErrorCategorizer) when their forward
+// action fails.
+type CategorizedError struct {
+	StepName string
+	Category ErrorCategory
+	Cause    error
+}
+
+func (e *CategorizedError) Error() string {
+	return fmt.Sprintf("step %s failed with %s error: %v", e.StepName, e.Category, e.Cause)
+}
+
+func (e *CategorizedError) Unwrap() error {
+	return e.Cause
+}
+
+// errorCategory returns the category of the given error,
+// or CategoryUnknown if it was not categorized.
+func errorCategory(err error) ErrorCategory {
+	var ce *CategorizedError
+	if errors.As(err, &ce) {
+		return ce.Category
+	}
+	return CategoryUnknown
+}
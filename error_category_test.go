@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithErrorCategorizer(t *testing.T) {
+	errTimeout := errors.New("timeout")
+	categorizer := func(err error) ErrorCategory {
+		if errors.Is(err, errTimeout) {
+			return CategoryTransient
+		}
+		return CategoryPermanent
+	}
+	testCases := []struct {
+		name             string
+		opts             []StepOption
+		forwardErr       error
+		expectedCategory ErrorCategory
+		expectedError    string
+	}{
+		{
+			name:             "transient error",
+			opts:             []StepOption{WithErrorCategorizer(categorizer)},
+			forwardErr:       errTimeout,
+			expectedCategory: CategoryTransient,
+			expectedError:    "executing step step1: step step1 failed with transient error: timeout",
+		},
+		{
+			name:             "permanent error",
+			opts:             []StepOption{WithErrorCategorizer(categorizer)},
+			forwardErr:       errors.New("invalid order"),
+			expectedCategory: CategoryPermanent,
+			expectedError:    "executing step step1: step step1 failed with permanent error: invalid order",
+		},
+		{
+			name:             "no categorizer",
+			forwardErr:       errTimeout,
+			expectedCategory: CategoryUnknown,
+			expectedError:    "executing step step1: timeout",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := New()
+			s.AddStep(NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					return tc.forwardErr
+				},
+				noop,
+				tc.opts...,
+			))
+			err := s.Execute(context.Background())
+			require.EqualError(t, err, tc.expectedError)
+			require.ErrorIs(t, err, tc.forwardErr)
+			failed := s.Summary().FailedStep
+			require.NotNil(t, failed)
+			require.Equal(t, tc.expectedCategory, failed.Category)
+		})
+	}
+}
+
+func TestErrorCategory_String(t *testing.T) {
+	require.Equal(t, "transient", CategoryTransient.String())
+	require.Equal(t, "permanent", CategoryPermanent.String())
+	require.Equal(t, "unknown", CategoryUnknown.String())
+}
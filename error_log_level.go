@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithErrorLogLevel option sets the level at which the Saga logs the
+// failures of the forward action of the step, instead of the default
+// slog.LevelWarn, e.g. slog.LevelError for critical payment steps and
+// slog.LevelInfo for optional notification steps. It only matters if
+// the Saga has a logger (see WithLogger).
+func WithErrorLogLevel(level slog.Level) StepOption {
+	return WithErrorLogLevelFn(func(err error) slog.Level {
+		return level
+	})
+}
+
+// WithErrorLogLevelFn option sets a function selecting, given the
+// error, the level at which the Saga logs the failures of the forward
+// action of the step. See WithErrorLogLevel.
+func WithErrorLogLevelFn(fn func(err error) slog.Level) StepOption {
+	return func(s *step) {
+		s.errorLogLevel = fn
+	}
+}
+
+// errorLogLevelStep is implemented by steps that set
+// the level at which their failures are logged.
+type errorLogLevelStep interface {
+	// errorLogLevelFor returns the level at which the given forward
+	// error is logged, and whether the step sets one.
+	errorLogLevelFor(err error) (slog.Level, bool)
+}
+
+func (s *step) errorLogLevelFor(err error) (slog.Level, bool) {
+	if s.errorLogLevel == nil {
+		return 0, false
+	}
+	return s.errorLogLevel(err), true
+}
+
+// logStepFailure logs the given message about the failure of the
+// forward action of the given step, at the level set by the step,
+// or slog.LevelWarn, if a logger is set.
+func (s *saga) logStepFailure(ctx context.Context, step Step, msg string, stepErr error) {
+	if s.logger == nil {
+		return
+	}
+	level := slog.LevelWarn
+	if l, ok := stepAs[errorLogLevelStep](step); ok {
+		if stepLevel, set := l.errorLogLevelFor(stepErr); set {
+			level = stepLevel
+		}
+	}
+	s.logger.Log(ctx, level, msg, "step", s.stepName(step), "error", s.sanitizeError(step, stepErr))
+}
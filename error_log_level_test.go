@@ -0,0 +1,71 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var errCardDeclined = errors.New("card declined")
+
+func TestWithErrorLogLevel(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       []StepOption
+		forwardErr    error
+		expectedLevel string
+	}{
+		{
+			name:          "default level",
+			forwardErr:    errors.New("forward error"),
+			expectedLevel: "level=WARN",
+		},
+		{
+			name:          "static level",
+			options:       []StepOption{WithErrorLogLevel(slog.LevelError)},
+			forwardErr:    errors.New("forward error"),
+			expectedLevel: "level=ERROR",
+		},
+		{
+			name:          "optional step",
+			options:       []StepOption{WithOptionalStep(), WithErrorLogLevel(slog.LevelInfo)},
+			forwardErr:    errors.New("forward error"),
+			expectedLevel: "level=INFO",
+		},
+		{
+			name: "level selected by error",
+			options: []StepOption{WithErrorLogLevelFn(func(err error) slog.Level {
+				if errors.Is(err, errCardDeclined) {
+					return slog.LevelInfo
+				}
+				return slog.LevelError
+			})},
+			forwardErr:    errCardDeclined,
+			expectedLevel: "level=INFO",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			saga := New(WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+			saga.AddStep(NewStepWithOptions("charge",
+				func(ctx context.Context) error {
+					return tc.forwardErr
+				},
+				noop,
+				tc.options...,
+			))
+			saga.Execute(context.Background())
+			require.Contains(t, buf.String(), tc.expectedLevel+" msg=")
+			require.Contains(t, buf.String(), "step=charge error=\""+tc.forwardErr.Error()+"\"")
+		})
+	}
+}
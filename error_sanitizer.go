@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"errors"
+	"regexp"
+)
+
+// WithErrorSanitizer option sets a function transforming the step errors
+// before they are logged, recorded in traces, reported in events and
+// progress messages, or included in the Summary, e.g. to redact personally
+// identifiable information or credentials. Execute still returns the
+// original errors. Steps can override it with WithStepErrorSanitizer.
+func WithErrorSanitizer(sanitize func(err error) error) Option {
+	return func(s *saga) {
+		s.errorSanitizer = sanitize
+	}
+}
+
+// RedactAllErrors returns an error sanitizer replacing
+// the message of every error with "[redacted]".
+func RedactAllErrors() func(err error) error {
+	return func(err error) error {
+		return errors.New("[redacted]")
+	}
+}
+
+// RegexpRedactor returns an error sanitizer replacing the matches of
+// the given pattern in error messages with replacement, which can
+// refer to submatches as in regexp.Regexp.ReplaceAllString.
+func RegexpRedactor(pattern *regexp.Regexp, replacement string) func(err error) error {
+	return func(err error) error {
+		return errors.New(pattern.ReplaceAllString(err.Error(), replacement))
+	}
+}
+
+// errorSanitizingStep is implemented by steps
+// that sanitize their own errors.
+type errorSanitizingStep interface {
+	// errorSanitizer returns the error sanitizer
+	// of the step, or nil if it has none.
+	errorSanitizer() func(err error) error
+}
+
+func (s *step) errorSanitizer() func(err error) error {
+	return s.stepErrorSanitizer
+}
+
+// sanitizeError returns the given error of the given step transformed by
+// the step error sanitizer if any, or the Saga error sanitizer otherwise.
+func (s *saga) sanitizeError(step Step, err error) error {
+	if err == nil {
+		return nil
+	}
+	sanitize := s.errorSanitizer
+	if es, ok := stepAs[errorSanitizingStep](step); ok {
+		if stepSanitize := es.errorSanitizer(); stepSanitize != nil {
+			sanitize = stepSanitize
+		}
+	}
+	if sanitize == nil {
+		return err
+	}
+	return sanitize(err)
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithErrorSanitizer(t *testing.T) {
+	cardRedactor := RegexpRedactor(regexp.MustCompile(`\d{16}`), "****")
+	testCases := []struct {
+		name             string
+		stepOpts         []StepOption
+		expectedReported string
+	}{
+		{
+			name:             "saga sanitizer",
+			expectedReported: "card **** declined",
+		},
+		{
+			name:             "step sanitizer",
+			stepOpts:         []StepOption{WithStepErrorSanitizer(RedactAllErrors())},
+			expectedReported: "[redacted]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var events []SagaEvent
+			var progress []StepProgress
+			s := New(
+				WithErrorSanitizer(cardRedactor),
+				WithEventHook(func(ctx context.Context, e SagaEvent) {
+					events = append(events, e)
+				}),
+			)
+			s.(*saga).progressHooks = append(s.(*saga).progressHooks, func(ctx context.Context, p StepProgress) {
+				progress = append(progress, p)
+			})
+			s.AddStep(NewStepWithOptions("charge",
+				func(ctx context.Context) error {
+					return errors.New("card 4111111111111111 declined")
+				},
+				noop,
+				tc.stepOpts...,
+			))
+			require.EqualError(t, s.Execute(context.Background()), "executing step charge: card 4111111111111111 declined")
+			require.EqualError(t, s.Summary().FailedStep.Err, tc.expectedReported)
+			require.EqualError(t, events[0].Err, tc.expectedReported)
+			require.Equal(t, tc.expectedReported, progress[0].Error)
+		})
+	}
+}
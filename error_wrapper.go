@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// WithErrorWrapper option sets the function wrapping the error of the
+// step that made the Saga fail, before it is returned by Execute. By
+// default, the error is wrapped as "executing step <name>: <error>".
+// See StructuredErrorWrapper, NoWrapWrapper and JSONErrorWrapper.
+func WithErrorWrapper(wrap func(err error, stepName string, stepIndex int) error) Option {
+	return func(s *saga) {
+		s.errorWrapper = wrap
+	}
+}
+
+// defaultErrorWrapper wraps the error of the given step with a message.
+func defaultErrorWrapper(err error, stepName string, stepIndex int) error {
+	return errors.Wrapf(err, "executing step %s", stepName)
+}
+
+// StructuredErrorWrapper is an error wrapper, to be used with
+// WithErrorWrapper, returning a *StepExecutionError.
+func StructuredErrorWrapper(err error, stepName string, stepIndex int) error {
+	return &StepExecutionError{StepName: stepName, StepIndex: stepIndex, Cause: err}
+}
+
+// NoWrapWrapper is an error wrapper, to be used with WithErrorWrapper,
+// returning the error of the step unchanged.
+func NoWrapWrapper(err error, stepName string, stepIndex int) error {
+	return err
+}
+
+// JSONErrorWrapper is an error wrapper, to be used with
+// WithErrorWrapper, returning a *JSONError.
+func JSONErrorWrapper(err error, stepName string, stepIndex int) error {
+	return &JSONError{StepName: stepName, StepIndex: stepIndex, Message: err.Error(), Cause: err}
+}
+
+// JSONError is a JSON-serializable error of a step,
+// returned by Execute when using JSONErrorWrapper.
+// Its message is its JSON encoding.
+type JSONError struct {
+	StepName  string `json:"step_name"`
+	StepIndex int    `json:"step_index"`
+	Message   string `json:"message"`
+	Cause     error  `json:"-"`
+}
+
+func (e *JSONError) Error() string {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return e.Message
+	}
+	return string(data)
+}
+
+func (e *JSONError) Unwrap() error {
+	return e.Cause
+}
+
+// wrapStepError wraps the error of the given step
+// with the configured error wrapper.
+func (s *saga) wrapStepError(err error, step Step) error {
+	wrap := s.errorWrapper
+	if wrap == nil {
+		wrap = defaultErrorWrapper
+	}
+	return wrap(err, s.stepName(step), s.currentStep)
+}
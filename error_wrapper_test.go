@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithErrorWrapper(t *testing.T) {
+	stepErr := errors.New("step2 error")
+	testCases := []struct {
+		name          string
+		opts          []Option
+		expectedError string
+		check         func(t *testing.T, err error)
+	}{
+		{
+			name:          "default wrapper",
+			expectedError: "executing step step2: step2 error",
+		},
+		{
+			name:          "structured wrapper",
+			opts:          []Option{WithErrorWrapper(StructuredErrorWrapper)},
+			expectedError: "step step2 (index 1) failed: step2 error",
+			check: func(t *testing.T, err error) {
+				var execErr *StepExecutionError
+				require.True(t, errors.As(err, &execErr))
+				require.Equal(t, &StepExecutionError{StepName: "step2", StepIndex: 1, Cause: stepErr}, execErr)
+			},
+		},
+		{
+			name:          "no wrap wrapper",
+			opts:          []Option{WithErrorWrapper(NoWrapWrapper)},
+			expectedError: "step2 error",
+			check: func(t *testing.T, err error) {
+				require.Equal(t, stepErr, err)
+			},
+		},
+		{
+			name:          "JSON wrapper",
+			opts:          []Option{WithErrorWrapper(JSONErrorWrapper)},
+			expectedError: `{"step_name":"step2","step_index":1,"message":"step2 error"}`,
+			check: func(t *testing.T, err error) {
+				var jsonErr *JSONError
+				require.True(t, errors.As(err, &jsonErr))
+				require.ErrorIs(t, err, stepErr)
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			saga := New(tc.opts...)
+			saga.AddStep(NewStep("step1", noop, noop))
+			saga.AddStep(NewStep("step2",
+				func(ctx context.Context) error {
+					return stepErr
+				},
+				noop,
+			))
+			err := saga.Execute(context.Background())
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedError, err.Error())
+			if tc.check != nil {
+				tc.check(t, err)
+			}
+		})
+	}
+}
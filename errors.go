@@ -0,0 +1,272 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrStepMetadataNotSupported is returned by StateManager decorators
+// implementing StepMetadataManager when the StateManager they
+// decorate does not support step metadata.
+var ErrStepMetadataNotSupported = errors.New("step metadata not supported by state manager")
+
+// ErrStepOutputNotSupported is returned by StateManager decorators
+// implementing StepOutputManager when the StateManager they
+// decorate does not support step outputs.
+var ErrStepOutputNotSupported = errors.New("step output not supported by state manager")
+
+// MultiError aggregates multiple errors into a single error.
+type MultiError struct {
+	Errors []error
+}
+
+func (e *MultiError) Error() string {
+	return fmt.Sprintf("%v", e.Errors)
+}
+
+// Unwrap returns the aggregated errors, so that errors.Is
+// and errors.As inspect each one of them.
+func (e *MultiError) Unwrap() []error {
+	return e.Errors
+}
+
+// ApprovalRejectedError is returned by awaitable and signaled steps
+// when the approval is rejected, times out, or the context is cancelled
+// while waiting for it.
+type ApprovalRejectedError struct {
+	StepName string
+	Cause    error
+}
+
+func (e *ApprovalRejectedError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("approval rejected for step %s: %v", e.StepName, e.Cause)
+	}
+	return fmt.Sprintf("approval rejected for step %s", e.StepName)
+}
+
+func (e *ApprovalRejectedError) Unwrap() error {
+	return e.Cause
+}
+
+// CompensationTimeoutError is returned when a step's compensation
+// action does not finish within its configured timeout.
+type CompensationTimeoutError struct {
+	StepName string
+	Timeout  time.Duration
+}
+
+func (e *CompensationTimeoutError) Error() string {
+	return fmt.Sprintf("compensation of step %s timed out after %v", e.StepName, e.Timeout)
+}
+
+// MutationVerificationError is returned when the compensation of a step
+// does not restore the state captured before its forward action.
+type MutationVerificationError struct {
+	StepName string
+	Cause    error
+}
+
+func (e *MutationVerificationError) Error() string {
+	return fmt.Sprintf("mutation verification failed for step %s: %v", e.StepName, e.Cause)
+}
+
+func (e *MutationVerificationError) Unwrap() error {
+	return e.Cause
+}
+
+// CompensationIncompleteError is returned when the compensation of a
+// step with WithStateDiff does not return the system to its state
+// before the forward action.
+type CompensationIncompleteError struct {
+	StepName string
+	Before   []byte
+	After    []byte
+}
+
+func (e *CompensationIncompleteError) Error() string {
+	return fmt.Sprintf("compensation of step %s did not restore its state", e.StepName)
+}
+
+// StepOrderingError is returned when the step orderer of a Saga
+// (see WithStepOrderer) does not return exactly the steps of the Saga.
+type StepOrderingError struct {
+	Missing    []string
+	Unexpected []string
+}
+
+func (e *StepOrderingError) Error() string {
+	return fmt.Sprintf("invalid step ordering: missing steps %v, unexpected steps %v", e.Missing, e.Unexpected)
+}
+
+// StepExecutionError is returned by Execute when a step fails,
+// if the Saga uses StructuredErrorWrapper.
+type StepExecutionError struct {
+	StepName  string
+	StepIndex int
+	Cause     error
+}
+
+func (e *StepExecutionError) Error() string {
+	return fmt.Sprintf("step %s (index %d) failed: %v", e.StepName, e.StepIndex, e.Cause)
+}
+
+func (e *StepExecutionError) Unwrap() error {
+	return e.Cause
+}
+
+// CompensationVerificationError is returned when the compensation
+// of a step succeeded but its verification (see
+// WithCompensationVerifier) failed, so that manual intervention
+// may be needed.
+type CompensationVerificationError struct {
+	StepName string
+	Cause    error
+}
+
+func (e *CompensationVerificationError) Error() string {
+	return fmt.Sprintf("verification of compensation of step %s failed: %v", e.StepName, e.Cause)
+}
+
+func (e *CompensationVerificationError) Unwrap() error {
+	return e.Cause
+}
+
+// PanicError is returned when a step action panics
+// and the Saga has panic recovery enabled.
+type PanicError struct {
+	StepName string
+	Value    any
+	Stack    []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("step %s panicked: %v", e.StepName, e.Value)
+}
+
+// InputValidationError is returned when the input validation
+// of a step fails. It is never retried.
+type InputValidationError struct {
+	StepName string
+	Cause    error
+}
+
+func (e *InputValidationError) Error() string {
+	return fmt.Sprintf("invalid input for step %s: %v", e.StepName, e.Cause)
+}
+
+func (e *InputValidationError) Unwrap() error {
+	return e.Cause
+}
+
+// AlreadyAttemptedError is returned when an at-most-once
+// step is executed after having already been attempted.
+type AlreadyAttemptedError struct {
+	StepName string
+}
+
+func (e *AlreadyAttemptedError) Error() string {
+	return fmt.Sprintf("step %s was already attempted", e.StepName)
+}
+
+// StateManagerCircuitOpenError is returned by a StateManager
+// wrapped with a circuit breaker while its circuit is open.
+type StateManagerCircuitOpenError struct {
+	OpenUntil time.Time
+}
+
+func (e *StateManagerCircuitOpenError) Error() string {
+	return fmt.Sprintf("state manager circuit open until %s", e.OpenUntil.Format(time.RFC3339))
+}
+
+// StepTimeoutError is returned when the forward action
+// of a step does not complete within its timeout.
+type StepTimeoutError struct {
+	StepName string
+	Timeout  time.Duration
+}
+
+func (e *StepTimeoutError) Error() string {
+	return fmt.Sprintf("step %s timed out after %v", e.StepName, e.Timeout)
+}
+
+// ConsistencyError is returned by a RedisStateManager with
+// read-your-writes consistency when the state written for
+// a step does not become visible.
+type ConsistencyError struct {
+	StepIndex int
+
+	// StepKey is the key of the state of the step, when it is
+	// stored by key (see NamedStateManager).
+	StepKey string
+}
+
+func (e *ConsistencyError) Error() string {
+	if e.StepKey != "" {
+		return fmt.Sprintf("state of step %q not visible after write", e.StepKey)
+	}
+	return fmt.Sprintf("state of step %d not visible after write", e.StepIndex)
+}
+
+// ConcurrentModificationError is returned by a VersionedStateManager
+// when the version of the state of a step does not match the expected
+// one, because another runner updated it in the meantime.
+type ConcurrentModificationError struct {
+	StepIndex int
+
+	// StepKey is the key of the state of the step, when it is
+	// stored by key (see NamedVersionedStateManager).
+	StepKey string
+
+	ExpectedVersion int
+	ActualVersion   int
+}
+
+func (e *ConcurrentModificationError) Error() string {
+	if e.StepKey != "" {
+		return fmt.Sprintf("state of step %q was modified concurrently: expected version %d, got %d", e.StepKey, e.ExpectedVersion, e.ActualVersion)
+	}
+	return fmt.Sprintf("state of step %d was modified concurrently: expected version %d, got %d", e.StepIndex, e.ExpectedVersion, e.ActualVersion)
+}
+
+// MissingCompensationError is returned when a step whose
+// compensation mode is CompensationRequired has no
+// compensation action.
+type MissingCompensationError struct {
+	StepName string
+}
+
+func (e *MissingCompensationError) Error() string {
+	return fmt.Sprintf("step %s has no compensation", e.StepName)
+}
+
+// UnexpectedCompensationError is returned when a step whose
+// compensation mode is CompensationForbidden has a
+// compensation action.
+type UnexpectedCompensationError struct {
+	StepName string
+}
+
+func (e *UnexpectedCompensationError) Error() string {
+	return fmt.Sprintf("step %s has a compensation but its compensation mode forbids it", e.StepName)
+}
+
+// PostconditionFailedError is returned when the postcondition
+// assertion of a step fails after its forward action succeeded.
+type PostconditionFailedError struct {
+	StepName string
+	Cause    error
+}
+
+func (e *PostconditionFailedError) Error() string {
+	return fmt.Sprintf("postcondition failed for step %s: %v", e.StepName, e.Cause)
+}
+
+func (e *PostconditionFailedError) Unwrap() error {
+	return e.Cause
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"time"
+)
+
+// Saga event types reported in SagaEvent.
+const (
+	EventStepCompleted      = "step_completed"
+	EventStepFailed         = "step_failed"
+	EventStepSkipped        = "step_skipped"
+	EventStepCompensated    = "step_compensated"
+	EventCompensationFailed = "compensation_failed"
+)
+
+// SagaEvent describes a step lifecycle event of a Saga:
+// the execution of the forward or compensation action of a step.
+type SagaEvent struct {
+	SagaID    string
+	Type      string
+	StepName  string
+	StepIndex int
+	Err       error
+
+	// Duration is the duration of the action.
+	Duration time.Duration
+
+	// Timestamp is the time the action ended.
+	Timestamp time.Time
+}
+
+// WithEventHook option registers a hook called after each
+// step lifecycle event of the Saga (see SagaEvent).
+// Hooks are called synchronously, so they should be fast.
+func WithEventHook(hook func(ctx context.Context, e SagaEvent)) Option {
+	return func(s *saga) {
+		s.eventHooks = append(s.eventHooks, hook)
+	}
+}
+
+// emitEvent reports a step lifecycle event to all the event hooks.
+func (s *saga) emitEvent(ctx context.Context, eventType string, step Step, stepIndex int, stepErr error, duration time.Duration) {
+	if len(s.eventHooks) == 0 {
+		return
+	}
+	e := SagaEvent{
+		SagaID:    s.id,
+		Type:      eventType,
+		StepName:  s.stepName(step),
+		StepIndex: stepIndex,
+		Err:       s.sanitizeError(step, stepErr),
+		Duration:  duration,
+		Timestamp: time.Now(),
+	}
+	for _, hook := range s.eventHooks {
+		hook(ctx, e)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrEventBusFull is returned by a BufferedEventBus
+// whose capacity has been reached.
+var ErrEventBusFull = errors.New("event bus is full")
+
+// EventBus publishes messages to topics of an event
+// bus (e.g. EventBridge or a custom pub/sub system).
+type EventBus interface {
+	// Publish publishes the given payload to the given topic.
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// EventPayload is the JSON payload published
+// to an EventBus for each step lifecycle event.
+type EventPayload struct {
+	SagaID    string    `json:"saga_id,omitempty"`
+	Type      string    `json:"type"`
+	StepName  string    `json:"step_name"`
+	StepIndex int       `json:"step_index"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// WithEventBus option makes the Saga publish each step lifecycle event
+// (see SagaEvent) to the given event bus, as an EventPayload JSON message
+// to the topic "{topicPrefix}/step/{eventType}". Publishing errors do not
+// fail the Saga; they are logged if a logger is configured.
+func WithEventBus(bus EventBus, topicPrefix string) Option {
+	return func(s *saga) {
+		s.eventHooks = append(s.eventHooks, func(ctx context.Context, e SagaEvent) {
+			topic := topicPrefix + "/step/" + e.Type
+			if err := publishEvent(ctx, bus, topic, e); err != nil && s.logger != nil {
+				s.logger.WarnContext(ctx, "publishing saga event", "topic", topic, "error", err)
+			}
+		})
+	}
+}
+
+// publishEvent publishes the given event as JSON to an event bus topic.
+func publishEvent(ctx context.Context, bus EventBus, topic string, e SagaEvent) error {
+	p := EventPayload{
+		SagaID:    e.SagaID,
+		Type:      e.Type,
+		StepName:  e.StepName,
+		StepIndex: e.StepIndex,
+		Timestamp: e.Timestamp,
+	}
+	if e.Err != nil {
+		p.Error = e.Err.Error()
+	}
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return bus.Publish(ctx, topic, payload)
+}
+
+// writerEventBus is an EventBus writing each message as a line.
+type writerEventBus struct {
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// StdoutEventBus returns an EventBus that writes each message to the
+// standard output as a line with its topic and payload. It is meant for
+// development.
+func StdoutEventBus() EventBus {
+	return &writerEventBus{w: os.Stdout}
+}
+
+func (b *writerEventBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, err := fmt.Fprintf(b.w, "%s %s\n", topic, payload)
+	return err
+}
+
+// Event is a message published to a BufferedEventBus.
+type Event struct {
+	Topic   string
+	Payload []byte
+}
+
+// BufferedEventBus is an EventBus that keeps the published
+// messages in memory. It is meant for testing.
+// It is safe for concurrent use.
+type BufferedEventBus struct {
+	capacity int
+	events   []Event
+	mu       sync.Mutex
+}
+
+// NewBufferedEventBus creates a new BufferedEventBus keeping up to
+// capacity messages. Once full, Publish returns ErrEventBusFull.
+func NewBufferedEventBus(capacity int) *BufferedEventBus {
+	return &BufferedEventBus{
+		capacity: capacity,
+	}
+}
+
+func (b *BufferedEventBus) Publish(ctx context.Context, topic string, payload []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if len(b.events) >= b.capacity {
+		return ErrEventBusFull
+	}
+	b.events = append(b.events, Event{
+		Topic:   topic,
+		Payload: append([]byte(nil), payload...),
+	})
+	return nil
+}
+
+// Events returns the messages published so far, in publication order.
+func (b *BufferedEventBus) Events() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Event(nil), b.events...)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEventBus(t *testing.T) {
+	testCases := []struct {
+		name           string
+		capacity       int
+		expectedTopics []string
+		expectedLog    string
+	}{
+		{
+			name:     "happy path",
+			capacity: 10,
+			expectedTopics: []string{
+				"orders/step/step_completed",
+				"orders/step/step_failed",
+				"orders/step/step_compensated",
+				"orders/step/step_compensated",
+			},
+		},
+		{
+			name:     "publishing error",
+			capacity: 1,
+			expectedTopics: []string{
+				"orders/step/step_completed",
+			},
+			expectedLog: "publishing saga event",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			bus := NewBufferedEventBus(tc.capacity)
+			s := New(
+				WithSagaID("order-42"),
+				WithEventBus(bus, "orders"),
+				WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+			)
+			s.AddStep(NewStep("step1", noop, noop))
+			s.AddStep(NewStep("step2",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				noop,
+			))
+			require.NotNil(t, s.Execute(context.Background()))
+
+			events := bus.Events()
+			var topics []string
+			for _, e := range events {
+				topics = append(topics, e.Topic)
+			}
+			require.Equal(t, tc.expectedTopics, topics)
+			require.Contains(t, buf.String(), tc.expectedLog)
+			if len(events) < 2 {
+				return
+			}
+			var p EventPayload
+			require.Nil(t, json.Unmarshal(events[1].Payload, &p))
+			require.Equal(t, "order-42", p.SagaID)
+			require.Equal(t, EventStepFailed, p.Type)
+			require.Equal(t, "step2", p.StepName)
+			require.Equal(t, 1, p.StepIndex)
+			require.Equal(t, "forward error", p.Error)
+			require.False(t, p.Timestamp.IsZero())
+		})
+	}
+}
+
+func TestWriterEventBus(t *testing.T) {
+	var buf bytes.Buffer
+	bus := &writerEventBus{w: &buf}
+	require.Nil(t, bus.Publish(context.Background(), "orders/step/step_completed", []byte(`{"step_name":"step1"}`)))
+	require.Equal(t, "orders/step/step_completed {\"step_name\":\"step1\"}\n", buf.String())
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithEventHook(t *testing.T) {
+	var events []SagaEvent
+	saga := New(
+		WithSagaID("order-42"),
+		WithEventHook(func(ctx context.Context, e SagaEvent) {
+			events = append(events, e)
+		}),
+	)
+	saga.AddStep(NewStep("step1", noop, noop))
+	saga.AddStep(AsOptional(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("optional error")
+		},
+		noop,
+	)))
+	saga.AddStep(NewStep("step3",
+		func(ctx context.Context) error {
+			return errors.New("forward error")
+		},
+		func(ctx context.Context) error {
+			return errors.New("compensation error")
+		},
+	))
+	require.NotNil(t, saga.Execute(context.Background()))
+
+	type event struct {
+		eventType string
+		stepName  string
+		stepIndex int
+		err       string
+	}
+	expectedEvents := []event{
+		{eventType: EventStepCompleted, stepName: "step1", stepIndex: 0},
+		{eventType: EventStepSkipped, stepName: "step2", stepIndex: 1, err: "optional error"},
+		{eventType: EventStepFailed, stepName: "step3", stepIndex: 2, err: "forward error"},
+		{eventType: EventCompensationFailed, stepName: "step3", stepIndex: 2, err: "compensation error"},
+		{eventType: EventStepCompensated, stepName: "step1", stepIndex: 0},
+	}
+	require.Len(t, events, len(expectedEvents))
+	for i, expected := range expectedEvents {
+		e := events[i]
+		require.Equal(t, "order-42", e.SagaID)
+		require.Equal(t, expected.eventType, e.Type)
+		require.Equal(t, expected.stepName, e.StepName)
+		require.Equal(t, expected.stepIndex, e.StepIndex)
+		if expected.err != "" {
+			require.EqualError(t, e.Err, expected.err)
+		} else {
+			require.Nil(t, e.Err)
+		}
+		require.False(t, e.Timestamp.IsZero())
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// WithFeatureFlagCheck option sets a function called with the name
+// of each step before executing it. If it returns false, the step is
+// disabled: it is skipped, marked as completed, and not compensated
+// if the Saga rolls back. It allows to integrate with any feature
+// flag system.
+func WithFeatureFlagCheck(checker func(ctx context.Context, stepName string) bool) Option {
+	return func(s *saga) {
+		s.featureFlagCheck = checker
+	}
+}
+
+// stepEnabled reports whether the given step is enabled
+// by the feature flag check, if any.
+func (s *saga) stepEnabled(ctx context.Context, step Step) bool {
+	if s.featureFlagCheck == nil {
+		return true
+	}
+	return s.featureFlagCheck(ctx, step.Name())
+}
+
+// skipDisabledStep marks the given disabled step as done,
+// so that it is neither executed nor compensated.
+func (s *saga) skipDisabledStep(ctx context.Context, step Step) error {
+	if s.logger != nil {
+		s.logger.DebugContext(ctx, "step disabled by feature flag, skipping", "step", s.stepName(step))
+	}
+	if err := s.setStepState(s.currentStep, step, true); err != nil {
+		return errors.Wrapf(err, "setting state for step %s", s.stepName(step))
+	}
+	s.skipped[s.declaredIndex(s.currentStep)] = true
+	return nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithFeatureFlagCheck(t *testing.T) {
+	testCases := []struct {
+		name                string
+		failingStep         bool
+		expectedForward     []string
+		expectedCompensated []string
+		expectedError       string
+	}{
+		{
+			name:            "disabled step is skipped",
+			expectedForward: []string{"step1", "step3"},
+		},
+		{
+			name:                "disabled step is not compensated",
+			failingStep:         true,
+			expectedForward:     []string{"step1", "step3"},
+			expectedCompensated: []string{"step3", "step1"},
+			expectedError:       "executing step step4: forward error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var forward, compensated []string
+			sm := NewInMemoryStateManager()
+			s := New(
+				WithStateManager(sm),
+				WithFeatureFlagCheck(func(ctx context.Context, stepName string) bool {
+					return stepName != "step2"
+				}),
+			)
+			for _, name := range []string{"step1", "step2", "step3"} {
+				s.AddStep(NewStep(name,
+					func(ctx context.Context) error {
+						forward = append(forward, name)
+						return nil
+					},
+					func(ctx context.Context) error {
+						compensated = append(compensated, name)
+						return nil
+					},
+				))
+			}
+			if tc.failingStep {
+				s.AddStep(NewStep("step4",
+					func(ctx context.Context) error {
+						return errors.New("forward error")
+					},
+					noop,
+				))
+			}
+			err := s.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+				completed, err := sm.StepState(1)
+				require.NoError(t, err)
+				require.True(t, completed)
+				require.Equal(t, float64(100), s.Summary().ProgressPercent)
+			}
+			require.Equal(t, tc.expectedForward, forward)
+			require.Equal(t, tc.expectedCompensated, compensated)
+		})
+	}
+}
+
+func TestWithFeatureFlagCheck_Reexecution(t *testing.T) {
+	var compensated []string
+	enabled := false
+	s := New(
+		WithFeatureFlagCheck(func(ctx context.Context, stepName string) bool {
+			return enabled || stepName != "step2"
+		}),
+		WithStepSorter(ByNameSorter()),
+	)
+	for _, name := range []string{"step3", "step2", "step1"} {
+		s.AddStep(NewStep(name, noop, func(ctx context.Context) error {
+			compensated = append(compensated, name)
+			return nil
+		}))
+	}
+	s.AddStep(NewStep("step4",
+		func(ctx context.Context) error {
+			return errors.New("forward error")
+		},
+		noop,
+	))
+
+	// The disabled step is skipped and not compensated.
+	require.EqualError(t, s.Execute(context.Background()), "executing step step4: forward error")
+	require.Equal(t, []string{"step3", "step1"}, compensated)
+
+	// Once enabled, it is executed and compensated by the next execution.
+	compensated = nil
+	enabled = true
+	require.EqualError(t, s.Execute(context.Background()), "executing step step4: forward error")
+	require.Equal(t, []string{"step3", "step2", "step1"}, compensated)
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithForwardCorrelationID option sets a generator of forward
+// correlation IDs. Unlike the correlation ID of the Saga (see
+// WithCorrelationIDGenerator), a new forward correlation ID is
+// generated for each attempt of the forward action, retries included,
+// and injected into the context passed to it (see
+// ForwardCorrelationIDFromContext). Each attempt is recorded as an
+// event carrying its ID on the step span, if a tracer is set, and the
+// IDs of all the attempts are logged at debug level, if a logger is set.
+func WithForwardCorrelationID(generator func() string) StepOption {
+	return func(s *step) {
+		s.forwardCorrelationIDGen = generator
+	}
+}
+
+// ForwardCorrelationIDFromContext returns the forward correlation
+// ID of the attempt of the forward action that received the given
+// context.
+func ForwardCorrelationIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(forwardCorrelationIDKey).(string)
+	return id, ok
+}
+
+// forwardCorrelatingStep is implemented by steps that generate
+// a correlation ID for each attempt of their forward action.
+type forwardCorrelatingStep interface {
+	// forwardCorrelationIDs returns the forward correlation IDs of the
+	// attempts of the last execution of the forward action.
+	forwardCorrelationIDs() []string
+}
+
+func (s *step) forwardCorrelationIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastForwardCorrelationIDs
+}
+
+// withForwardCorrelationID returns a copy of ctx carrying a new forward
+// correlation ID for the given attempt, or ctx itself if the step has
+// no generator. The attempt is recorded on the span of ctx, if any.
+func (s *step) withForwardCorrelationID(ctx context.Context, attempt int) context.Context {
+	if s.forwardCorrelationIDGen == nil {
+		return ctx
+	}
+	id := s.forwardCorrelationIDGen()
+	s.mu.Lock()
+	if attempt == 1 {
+		s.lastForwardCorrelationIDs = nil
+	}
+	s.lastForwardCorrelationIDs = append(s.lastForwardCorrelationIDs, id)
+	s.mu.Unlock()
+	trace.SpanFromContext(ctx).AddEvent("forward attempt", trace.WithAttributes(
+		attribute.Int("saga.step.attempt", attempt),
+		attribute.String("saga.forward_correlation_id", id),
+	))
+	return context.WithValue(ctx, forwardCorrelationIDKey, id)
+}
+
+// logForwardCorrelationIDs logs, at debug level, the forward
+// correlation IDs of the attempts of the given step, if any.
+func (s *saga) logForwardCorrelationIDs(ctx context.Context, step Step) {
+	if s.logger == nil {
+		return
+	}
+	c, ok := stepAs[forwardCorrelatingStep](step)
+	if !ok {
+		return
+	}
+	if ids := c.forwardCorrelationIDs(); len(ids) > 0 {
+		s.logger.DebugContext(ctx, "step forward correlation IDs", "step", s.stepName(step), "forwardCorrelationIDs", ids)
+	}
+}
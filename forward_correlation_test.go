@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithForwardCorrelationID(t *testing.T) {
+	var buf bytes.Buffer
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	s := New(
+		WithCorrelationIDGenerator(func() string { return "saga-corr" }),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+		WithTracer(tp.Tracer("test")),
+	)
+	next := 0
+	var attemptIDs []string
+	s.AddStep(NewStepWithOptions("step1",
+		func(ctx context.Context) error {
+			id, ok := ForwardCorrelationIDFromContext(ctx)
+			require.True(t, ok)
+			attemptIDs = append(attemptIDs, id)
+			corrID, _ := CorrelationIDFromContext(ctx)
+			require.Equal(t, "saga-corr", corrID)
+			if len(attemptIDs) < 3 {
+				return errors.New("forward error")
+			}
+			return nil
+		},
+		noop,
+		WithRetry(3, 0),
+		WithForwardCorrelationID(func() string {
+			next++
+			return fmt.Sprintf("attempt-%d", next)
+		}),
+	))
+	require.Nil(t, s.Execute(context.Background()))
+	require.Equal(t, []string{"attempt-1", "attempt-2", "attempt-3"}, attemptIDs)
+	require.Contains(t, buf.String(), "forwardCorrelationIDs=\"[attempt-1 attempt-2 attempt-3]\"")
+
+	spans := sr.Ended()
+	require.Len(t, spans, 1)
+	events := spans[0].Events()
+	require.Len(t, events, 3)
+	for i, event := range events {
+		require.Equal(t, "forward attempt", event.Name)
+		require.ElementsMatch(t, []attribute.KeyValue{
+			attribute.Int("saga.step.attempt", i+1),
+			attribute.String("saga.forward_correlation_id", attemptIDs[i]),
+		}, event.Attributes)
+	}
+}
+
+func TestForwardCorrelationIDFromContext(t *testing.T) {
+	id, ok := ForwardCorrelationIDFromContext(context.Background())
+	require.False(t, ok)
+	require.Empty(t, id)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+)
+
+// GoroutineLocalKey is the type of the keys under which
+// the Saga stores step data in a GoroutineLocalStore.
+type GoroutineLocalKey string
+
+const (
+	// GoroutineLocalSagaID is the key of the Saga identifier.
+	GoroutineLocalSagaID GoroutineLocalKey = "saga.id"
+
+	// GoroutineLocalStepName is the key of the step name.
+	GoroutineLocalStepName GoroutineLocalKey = "saga.step.name"
+
+	// GoroutineLocalStepIndex is the key of the step index.
+	GoroutineLocalStepIndex GoroutineLocalKey = "saga.step.index"
+)
+
+// GoroutineLocalStore is a goroutine-local storage, such as the one
+// provided by github.com/timandy/routine, through which step data is
+// accessible without threading it through the context.
+type GoroutineLocalStore interface {
+	// Set stores the value for the given key.
+	Set(key, value any)
+
+	// Get returns the value stored for the given key, or nil if none.
+	Get(key any) any
+}
+
+// WithGoroutineLocalStore option makes the Saga store the Saga
+// identifier, the step name and the step index in the given store
+// (under GoroutineLocalSagaID, GoroutineLocalStepName and
+// GoroutineLocalStepIndex) before running the forward and compensation
+// actions of each step, on the goroutine running them.
+//
+// Beware that goroutine-local values are only visible from the
+// goroutine that set them: work handed off to other goroutines (e.g.
+// timeout steps, parallel compensation or goroutine pools) does not see
+// them, and pooled goroutines may see stale values left by a previous
+// step unless the store is cleared. Prefer the context where possible.
+func WithGoroutineLocalStore(store GoroutineLocalStore) Option {
+	return func(s *saga) {
+		s.goroutineLocalStore = store
+	}
+}
+
+// ContextGoroutineLocalStore is a GoroutineLocalStore for environments
+// without true goroutine-local storage. The values are stored in a map
+// shared by all goroutines and, when used with WithGoroutineLocalStore,
+// also in the context passed to the step actions. Use GetFromContext to
+// read them from the context, falling back to the map.
+type ContextGoroutineLocalStore struct {
+	mu     sync.RWMutex
+	values map[any]any
+}
+
+// NewContextGoroutineLocalStore creates a new ContextGoroutineLocalStore.
+func NewContextGoroutineLocalStore() *ContextGoroutineLocalStore {
+	return &ContextGoroutineLocalStore{
+		values: make(map[any]any),
+	}
+}
+
+func (c *ContextGoroutineLocalStore) Set(key, value any) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] = value
+}
+
+func (c *ContextGoroutineLocalStore) Get(key any) any {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.values[key]
+}
+
+// GetFromContext returns the value stored for the given key in the
+// given context, or in the store if the context has none.
+func (c *ContextGoroutineLocalStore) GetFromContext(ctx context.Context, key any) any {
+	if val := ctx.Value(key); val != nil {
+		return val
+	}
+	return c.Get(key)
+}
+
+// setGoroutineLocals stores the data of the given step, at the
+// given index, in the goroutine-local store, if any. It returns
+// the context to pass to the step actions.
+func (s *saga) setGoroutineLocals(ctx context.Context, step Step, stepIndex int) context.Context {
+	if s.goroutineLocalStore == nil {
+		return ctx
+	}
+	values := []struct {
+		key   GoroutineLocalKey
+		value any
+	}{
+		{GoroutineLocalSagaID, s.id},
+		{GoroutineLocalStepName, s.stepName(step)},
+		{GoroutineLocalStepIndex, stepIndex},
+	}
+	_, withContext := s.goroutineLocalStore.(*ContextGoroutineLocalStore)
+	for _, v := range values {
+		s.goroutineLocalStore.Set(v.key, v.value)
+		if withContext {
+			ctx = context.WithValue(ctx, v.key, v.value)
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mapGoroutineLocalStore is a GoroutineLocalStore backed by a map,
+// standing in for a true goroutine-local storage in tests.
+type mapGoroutineLocalStore map[any]any
+
+func (m mapGoroutineLocalStore) Set(key, value any) {
+	m[key] = value
+}
+
+func (m mapGoroutineLocalStore) Get(key any) any {
+	return m[key]
+}
+
+func TestWithGoroutineLocalStore(t *testing.T) {
+	store := mapGoroutineLocalStore{}
+	var seen []any
+	record := func(ctx context.Context) error {
+		seen = append(seen, store.Get(GoroutineLocalSagaID), store.Get(GoroutineLocalStepName), store.Get(GoroutineLocalStepIndex))
+		return nil
+	}
+	saga := New(WithSagaID("order-42"), WithGoroutineLocalStore(store))
+	saga.AddStep(NewStep("step1", record, record))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("step2 error")
+		},
+		noop,
+	))
+	require.NotNil(t, saga.Execute(context.Background()))
+	require.Equal(t, []any{
+		"order-42", "step1", 0,
+		"order-42", "step1", 0,
+	}, seen)
+}
+
+func TestContextGoroutineLocalStore(t *testing.T) {
+	store := NewContextGoroutineLocalStore()
+	var fromContext []any
+	saga := New(WithSagaID("order-42"), WithGoroutineLocalStore(store))
+	for _, name := range []string{"step1", "step2"} {
+		saga.AddStep(NewStep(name,
+			func(ctx context.Context) error {
+				fromContext = append(fromContext, store.GetFromContext(ctx, GoroutineLocalStepName))
+				return nil
+			},
+			noop,
+		))
+	}
+	require.Nil(t, saga.Execute(context.Background()))
+	require.Equal(t, []any{"step1", "step2"}, fromContext)
+
+	// Outside of the steps, the last stored values are returned.
+	require.Equal(t, "step2", store.GetFromContext(context.Background(), GoroutineLocalStepName))
+	require.Equal(t, 1, store.Get(GoroutineLocalStepIndex))
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+	"github.com/pkg/errors"
+)
+
+// poolRetryInterval is the interval between submissions
+// to a full non-blocking goroutine pool.
+const poolRetryInterval = 10 * time.Millisecond
+
+// WithGoroutinePool option makes the Saga run its concurrent work
+// (e.g. parallel compensation, see WithParallelCompensation) on the
+// given pool instead of spawning raw goroutines, bounding the number
+// of goroutines across all the Sagas sharing the pool.
+//
+// When the pool is full, a blocking pool (the ants default) makes the
+// submission wait for space. A non-blocking one (ants.WithNonblocking)
+// makes the Saga retry the submission until there is space. Either
+// way, the submission fails once the context is done, and the work
+// is not run.
+func WithGoroutinePool(pool *ants.Pool) Option {
+	return func(s *saga) {
+		s.goroutinePool = pool
+	}
+}
+
+// goroutine runs fn on a new goroutine, or on the goroutine pool of the
+// Saga if any. It returns an error if fn could not be submitted to the
+// pool, in which case fn is not run.
+func (s *saga) goroutine(ctx context.Context, fn func()) error {
+	if s.goroutinePool == nil {
+		go fn()
+		return nil
+	}
+	if ctx.Done() == nil {
+		return s.submit(ctx, fn)
+	}
+	// A blocking pool does not return until there is space, so the
+	// submission is waited for along with the context. fn only runs
+	// if the context was not done before the pool started it.
+	const (
+		pending int32 = iota
+		started
+		abandoned
+	)
+	var state atomic.Int32
+	task := func() {
+		if state.CompareAndSwap(pending, started) {
+			fn()
+		}
+	}
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- s.submit(ctx, task)
+	}()
+	select {
+	case err := <-submitted:
+		return err
+	case <-ctx.Done():
+		if state.CompareAndSwap(pending, abandoned) {
+			return errors.Wrap(ctx.Err(), "submitting to goroutine pool")
+		}
+		return <-submitted
+	}
+}
+
+// submit submits fn to the goroutine pool of the Saga,
+// retrying while a non-blocking pool is full.
+func (s *saga) submit(ctx context.Context, fn func()) error {
+	for {
+		err := s.goroutinePool.Submit(fn)
+		if err == nil {
+			return nil
+		}
+		if err != ants.ErrPoolOverload {
+			return errors.Wrap(err, "submitting to goroutine pool")
+		}
+		select {
+		case <-ctx.Done():
+			return errors.Wrap(ctx.Err(), "submitting to goroutine pool")
+		case <-time.After(poolRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/panjf2000/ants/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithGoroutinePool(t *testing.T) {
+	testCases := []struct {
+		name        string
+		poolSize    int
+		nonblocking bool
+		expectedMax int
+	}{
+		{name: "blocking pool", poolSize: 2, expectedMax: 2},
+		{name: "non-blocking pool", poolSize: 3, nonblocking: true, expectedMax: 3},
+		{name: "pool larger than steps", poolSize: 10, expectedMax: 4},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool, err := ants.NewPool(tc.poolSize, ants.WithNonblocking(tc.nonblocking))
+			require.Nil(t, err)
+			defer pool.Release()
+			tracker := &concurrencyTracker{}
+			saga := New(WithParallelCompensation(0), WithGoroutinePool(pool))
+			for i := 1; i <= 4; i++ {
+				name := fmt.Sprintf("step%d", i)
+				saga.AddStep(NewStep(name, noop, tracker.compensate(name, nil)))
+			}
+			saga.AddStep(NewStep("step5",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				noop,
+			))
+			err = saga.Execute(context.Background())
+			require.EqualError(t, err, "executing step step5: forward error")
+			require.Equal(t, tc.expectedMax, tracker.max)
+			require.Len(t, tracker.order, 4)
+		})
+	}
+}
+
+func TestWithGoroutinePool_ContextCanceled(t *testing.T) {
+	testCases := []struct {
+		name        string
+		nonblocking bool
+	}{
+		{name: "blocking pool"},
+		{name: "non-blocking pool", nonblocking: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool, err := ants.NewPool(1, ants.WithNonblocking(tc.nonblocking))
+			require.Nil(t, err)
+			defer pool.Release()
+			// Occupy the only slot of the pool.
+			release := make(chan struct{})
+			require.Nil(t, pool.Submit(func() { <-release }))
+
+			var compensations atomic.Int32
+			compensate := func(ctx context.Context) error {
+				compensations.Add(1)
+				return nil
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			saga := New(WithParallelCompensation(0), WithGoroutinePool(pool))
+			saga.AddStep(NewStep("step1", noop, compensate))
+			saga.AddStep(NewStep("step2",
+				func(ctx context.Context) error {
+					cancel()
+					return errors.New("forward error")
+				},
+				compensate,
+			))
+			err = saga.Execute(ctx)
+			require.EqualError(t, err, "compensating after failure in step step2: forward error: compensation failed with errors: [submitting to goroutine pool: context canceled submitting to goroutine pool: context canceled]")
+
+			// The abandoned submissions do not run once the pool has
+			// space: a task submitted after them runs after them.
+			close(release)
+			require.Eventually(t, func() bool { return pool.Waiting() == 0 }, time.Second, time.Millisecond)
+			done := make(chan struct{})
+			require.Eventually(t, func() bool {
+				return pool.Submit(func() { close(done) }) == nil
+			}, time.Second, time.Millisecond)
+			<-done
+			require.Equal(t, int32(0), compensations.Load())
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Status is the outcome of a Saga execution.
+type Status string
+
+// Saga execution statuses reported in HistoryEntry.
+const (
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+)
+
+// HistoryEntry records an execution of a Saga.
+type HistoryEntry struct {
+	SagaID    string
+	StartedAt time.Time
+	EndedAt   time.Time
+	Status    Status
+	Summary   Summary
+}
+
+// HistoryOption defines a function type that applies
+// a configuration option to a History instance.
+type HistoryOption func(*History)
+
+// WithMaxHistorySize option caps the number of entries kept by the
+// History: once full, the oldest entry is dropped for each new one.
+func WithMaxHistorySize(n int) HistoryOption {
+	return func(h *History) {
+		h.maxSize = n
+	}
+}
+
+// History keeps the executions of sagas, for audit and debugging
+// purposes (e.g. repeated failures in a long-running service).
+// It is safe for concurrent use.
+type History struct {
+	entries []HistoryEntry
+	maxSize int
+	mu      sync.RWMutex
+}
+
+// NewHistory creates a new, empty, History. By default,
+// the number of entries it keeps is unbounded.
+func NewHistory(opts ...HistoryOption) *History {
+	h := &History{}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// add records the given entry, dropping the oldest one if full.
+func (h *History) add(e HistoryEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = append(h.entries, e)
+	if h.maxSize > 0 && len(h.entries) > h.maxSize {
+		h.entries = append([]HistoryEntry(nil), h.entries[len(h.entries)-h.maxSize:]...)
+	}
+}
+
+// All returns all the entries, from the oldest to the newest.
+func (h *History) All() []HistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return append([]HistoryEntry(nil), h.entries...)
+}
+
+// Last returns the newest entry, and false if the History is empty.
+func (h *History) Last() (HistoryEntry, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.entries) == 0 {
+		return HistoryEntry{}, false
+	}
+	return h.entries[len(h.entries)-1], true
+}
+
+// ForSagaID returns the entries of the Saga with the
+// given ID, from the oldest to the newest.
+func (h *History) ForSagaID(id string) []HistoryEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var entries []HistoryEntry
+	for _, e := range h.entries {
+		if e.SagaID == id {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+// Clear removes all the entries.
+func (h *History) Clear() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.entries = nil
+}
+
+// historySaga is a Saga that records its executions in a History.
+type historySaga struct {
+	Saga
+	history *History
+}
+
+// NewHistorySaga wraps the given Saga, recording each of its
+// executions in the given History, timed with the clock of the
+// Saga (see WithClock).
+func NewHistorySaga(inner Saga, history *History) Saga {
+	return &historySaga{
+		Saga:    inner,
+		history: history,
+	}
+}
+
+func (s *historySaga) Execute(ctx context.Context) error {
+	clock := s.sagaClock()
+	if clock == nil {
+		clock = clockFromContext(ctx)
+	}
+	startedAt := clock.Now()
+	err := s.Saga.Execute(ctx)
+	status := StatusCompleted
+	if err != nil {
+		status = StatusFailed
+	}
+	s.history.add(HistoryEntry{
+		SagaID:    s.ID(),
+		StartedAt: startedAt,
+		EndedAt:   clock.Now(),
+		Status:    status,
+		Summary:   s.Summary(),
+	})
+	return err
+}
+
+func (s *historySaga) sagaClock() Clock {
+	if c, ok := s.Saga.(clockedSaga); ok {
+		return c.sagaClock()
+	}
+	return nil
+}
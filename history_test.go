@@ -0,0 +1,88 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistorySaga(t *testing.T) {
+	history := NewHistory()
+	_, ok := history.Last()
+	require.False(t, ok)
+
+	completed := NewHistorySaga(New(WithSagaID("order-1")), history)
+	completed.AddStep(NewStep("step1", noop, noop))
+	require.Nil(t, completed.Execute(context.Background()))
+
+	failed := NewHistorySaga(New(WithSagaID("order-2")), history)
+	failed.AddStep(NewStep("step1",
+		func(ctx context.Context) error {
+			return errors.New("forward error")
+		},
+		noop,
+	))
+	require.NotNil(t, failed.Execute(context.Background()))
+
+	entries := history.All()
+	require.Len(t, entries, 2)
+	require.Equal(t, "order-1", entries[0].SagaID)
+	require.Equal(t, StatusCompleted, entries[0].Status)
+	require.Equal(t, float64(100), entries[0].Summary.ProgressPercent)
+	require.False(t, entries[0].EndedAt.Before(entries[0].StartedAt))
+	require.Equal(t, "order-2", entries[1].SagaID)
+	require.Equal(t, StatusFailed, entries[1].Status)
+	require.Equal(t, "step1", entries[1].Summary.FailedStep.StepName)
+
+	last, ok := history.Last()
+	require.True(t, ok)
+	require.Equal(t, entries[1], last)
+
+	require.NotNil(t, failed.Execute(context.Background()))
+	require.Len(t, history.ForSagaID("order-2"), 2)
+	require.Len(t, history.ForSagaID("order-1"), 1)
+	require.Empty(t, history.ForSagaID("order-3"))
+
+	history.Clear()
+	require.Empty(t, history.All())
+}
+
+func TestHistorySaga_Clock(t *testing.T) {
+	startedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(startedAt)
+	history := NewHistory()
+	s := NewHistorySaga(New(WithClock(clock)), history)
+	s.AddStep(NewStep("step1",
+		func(ctx context.Context) error {
+			clock.Advance(5 * time.Second)
+			return nil
+		},
+		noop,
+	))
+	require.Nil(t, s.Execute(context.Background()))
+
+	last, ok := history.Last()
+	require.True(t, ok)
+	require.Equal(t, startedAt, last.StartedAt)
+	require.Equal(t, startedAt.Add(5*time.Second), last.EndedAt)
+}
+
+func TestWithMaxHistorySize(t *testing.T) {
+	history := NewHistory(WithMaxHistorySize(2))
+	for _, id := range []string{"order-1", "order-2", "order-3"} {
+		s := NewHistorySaga(New(WithSagaID(id)), history)
+		s.AddStep(NewStep("step1", noop, noop))
+		require.Nil(t, s.Execute(context.Background()))
+	}
+	entries := history.All()
+	require.Len(t, entries, 2)
+	require.Equal(t, "order-2", entries[0].SagaID)
+	require.Equal(t, "order-3", entries[1].SagaID)
+}
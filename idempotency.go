@@ -0,0 +1,220 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// defaultIdempotencyTTL is the time the successful executions
+// of idempotent steps are remembered for, by default.
+const defaultIdempotencyTTL = 24 * time.Hour
+
+// IdempotencyStore records the successful executions of idempotent
+// steps (see WithIdempotentExecution).
+// Implementations can store them in-memory, in Redis,
+// or any other storage mechanism.
+type IdempotencyStore interface {
+	// RecordIfAbsent atomically records an execution for the given
+	// key, expiring after ttl, unless one was already recorded and
+	// has not expired yet. It reports whether it recorded it.
+	RecordIfAbsent(ctx context.Context, key string, ttl time.Duration) (recorded bool, err error)
+
+	// Delete removes the execution recorded for the given key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// WithIdempotentExecution option makes the step idempotent without its
+// forward action being aware of it: before running it, the step records
+// its execution in the given store, and if an execution was already
+// recorded, succeeds without running it again. If the forward action
+// fails, the recorded execution is deleted, so that it can be retried,
+// and so it is once the step is compensated, so that executing the Saga
+// again runs it again. If the process stops while the forward action
+// runs, the recorded execution is kept: the step is then skipped by
+// later executions until its TTL expires.
+// As the execution is recorded atomically before running the forward
+// action, concurrent executions of the step run it only once. The key is
+// composed of the Saga ID, the step name and the step's idempotency key
+// (see WithIdempotencyKey), separated by colons; the step fails when
+// executed without a Saga ID (see WithSagaID). Executions are remembered
+// for 24 hours unless WithIdempotencyTTL is used.
+func WithIdempotentExecution(store IdempotencyStore) StepOption {
+	return func(s *step) {
+		s.idempotencyStore = store
+	}
+}
+
+// WithIdempotencyTTL option sets the time the successful executions
+// of an idempotent step are remembered for.
+func WithIdempotencyTTL(ttl time.Duration) StepOption {
+	return func(s *step) {
+		s.idempotencyTTL = ttl
+	}
+}
+
+// idempotencyStoreKey returns the key used to record the executions
+// of the step by the Saga with the given ID in its idempotency store.
+func (s *step) idempotencyStoreKey(sagaID string) string {
+	return sagaID + ":" + s.name + ":" + s.idempotencyKey
+}
+
+// recordExecution records an execution of the step in its idempotency
+// store, if any. It reports whether the forward action should run, i.e.
+// whether the step has no idempotency store or no execution of the step
+// was recorded yet, along with the key it recorded the execution at.
+func (s *step) recordExecution(ctx context.Context) (run bool, key string, err error) {
+	if s.idempotencyStore == nil {
+		return true, "", nil
+	}
+	sagaID, _ := SagaIDFromContext(ctx)
+	if sagaID == "" {
+		return false, "", errors.Errorf("step %s records its executions in an idempotency store, which requires a saga ID (see WithSagaID)", s.name)
+	}
+	ttl := s.idempotencyTTL
+	if ttl <= 0 {
+		ttl = defaultIdempotencyTTL
+	}
+	key = s.idempotencyStoreKey(sagaID)
+	recorded, err := s.idempotencyStore.RecordIfAbsent(ctx, key, ttl)
+	if err != nil {
+		return false, "", errors.Wrapf(err, "recording execution of step %s", s.name)
+	}
+	return recorded, key, nil
+}
+
+// withIdempotentExecution runs the given forward action of the step
+// unless an execution was already recorded in its idempotency store.
+// If the action fails, the recorded execution is deleted.
+func (s *step) withIdempotentExecution(ctx context.Context, forward func(ctx context.Context) error) (err error) {
+	run, key, err := s.recordExecution(ctx)
+	if err != nil || !run {
+		return err
+	}
+	defer func() {
+		if err == nil || key == "" {
+			return
+		}
+		if deleteErr := s.idempotencyStore.Delete(context.WithoutCancel(ctx), key); deleteErr != nil {
+			deleteErr = errors.Wrapf(deleteErr, "deleting execution of step %s", s.name)
+			err = &MultiError{Errors: []error{err, deleteErr}}
+		}
+	}()
+	return forward(ctx)
+}
+
+// forgetExecution deletes the execution of the step recorded
+// in its idempotency store, if any, once it is compensated.
+func (s *step) forgetExecution(ctx context.Context) error {
+	if s.idempotencyStore == nil {
+		return nil
+	}
+	sagaID, _ := SagaIDFromContext(ctx)
+	if sagaID == "" {
+		// Without a Saga ID, no execution was recorded.
+		return nil
+	}
+	if err := s.idempotencyStore.Delete(ctx, s.idempotencyStoreKey(sagaID)); err != nil {
+		return errors.Wrapf(err, "deleting execution of step %s", s.name)
+	}
+	return nil
+}
+
+// InMemoryIdempotencyStore is an implementation of the
+// IdempotencyStore interface that records executions in memory.
+type InMemoryIdempotencyStore struct {
+	mu        sync.Mutex
+	expiresAt map[string]time.Time
+	now       func() time.Time
+}
+
+// NewInMemoryIdempotencyStore creates a new InMemoryIdempotencyStore.
+func NewInMemoryIdempotencyStore() *InMemoryIdempotencyStore {
+	return &InMemoryIdempotencyStore{
+		expiresAt: make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// Exists reports whether an execution was recorded
+// for the given key and has not expired yet.
+func (m *InMemoryIdempotencyStore) Exists(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	expiresAt, exists := m.expiresAt[key]
+	if !exists {
+		return false, nil
+	}
+	if !m.now().Before(expiresAt) {
+		delete(m.expiresAt, key)
+		return false, nil
+	}
+	return true, nil
+}
+
+func (m *InMemoryIdempotencyStore) RecordIfAbsent(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if expiresAt, exists := m.expiresAt[key]; exists && now.Before(expiresAt) {
+		return false, nil
+	}
+	m.expiresAt[key] = now.Add(ttl)
+	return true, nil
+}
+
+func (m *InMemoryIdempotencyStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.expiresAt, key)
+	return nil
+}
+
+// RedisIdempotencyStore is an implementation of the IdempotencyStore
+// interface that records each execution as a Redis key expiring
+// after its TTL.
+type RedisIdempotencyStore struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+// NewRedisIdempotencyStore creates a new RedisIdempotencyStore storing
+// each execution at keyPrefix followed by its key.
+func NewRedisIdempotencyStore(client redis.UniversalClient, keyPrefix string) *RedisIdempotencyStore {
+	return &RedisIdempotencyStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// Exists reports whether an execution was recorded
+// for the given key and has not expired yet.
+func (m *RedisIdempotencyStore) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := m.client.Exists(ctx, m.keyPrefix+key).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "checking idempotency key %s in redis", key)
+	}
+	return n > 0, nil
+}
+
+func (m *RedisIdempotencyStore) RecordIfAbsent(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	recorded, err := m.client.SetNX(ctx, m.keyPrefix+key, 1, ttl).Result()
+	if err != nil {
+		return false, errors.Wrapf(err, "recording idempotency key %s in redis", key)
+	}
+	return recorded, nil
+}
+
+func (m *RedisIdempotencyStore) Delete(ctx context.Context, key string) error {
+	if err := m.client.Del(ctx, m.keyPrefix+key).Err(); err != nil {
+		return errors.Wrapf(err, "deleting idempotency key %s in redis", key)
+	}
+	return nil
+}
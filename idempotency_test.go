@@ -0,0 +1,228 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// checkableIdempotencyStore is an IdempotencyStore
+// whose recorded executions can be checked.
+type checkableIdempotencyStore interface {
+	IdempotencyStore
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+func TestWithIdempotentExecution(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testCases := []struct {
+		name  string
+		store checkableIdempotencyStore
+	}{
+		{
+			name:  "in-memory store",
+			store: NewInMemoryIdempotencyStore(),
+		},
+		{
+			name:  "redis store",
+			store: NewRedisIdempotencyStore(client, "saga:idempotency:"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := 0
+			fail := true
+			newSaga := func() Saga {
+				saga := New(WithSagaID("order-42"))
+				saga.AddStep(NewStepWithOptions("charge",
+					func(ctx context.Context) error {
+						calls++
+						if fail {
+							return errors.New("charge error")
+						}
+						return nil
+					},
+					noop,
+					WithIdempotentExecution(tc.store),
+					WithIdempotencyKey("payment-1"),
+				))
+				return saga
+			}
+
+			// Failed executions are not recorded.
+			require.NotNil(t, newSaga().Execute(context.Background()))
+			exists, err := tc.store.Exists(context.Background(), "order-42:charge:payment-1")
+			require.Nil(t, err)
+			require.False(t, exists)
+
+			fail = false
+			require.Nil(t, newSaga().Execute(context.Background()))
+			exists, err = tc.store.Exists(context.Background(), "order-42:charge:payment-1")
+			require.Nil(t, err)
+			require.True(t, exists)
+
+			// A new Saga with the same ID skips the recorded execution.
+			require.Nil(t, newSaga().Execute(context.Background()))
+			require.Equal(t, 2, calls)
+		})
+	}
+}
+
+func TestWithIdempotentExecution_Compensation(t *testing.T) {
+	store := NewInMemoryIdempotencyStore()
+	calls := 0
+	newSaga := func() Saga {
+		saga := New(WithSagaID("order-42"))
+		saga.AddStep(NewStepWithOptions("charge",
+			func(ctx context.Context) error {
+				calls++
+				return nil
+			},
+			noop,
+			WithIdempotentExecution(store),
+		))
+		saga.AddStep(NewStep("ship",
+			func(ctx context.Context) error {
+				return errors.New("ship error")
+			},
+			noop,
+		))
+		return saga
+	}
+
+	// Compensated executions are deleted, so that a new
+	// Saga with the same ID runs the step again.
+	require.EqualError(t, newSaga().Execute(context.Background()), "executing step ship: ship error")
+	exists, err := store.Exists(context.Background(), "order-42:charge:")
+	require.Nil(t, err)
+	require.False(t, exists)
+	require.EqualError(t, newSaga().Execute(context.Background()), "executing step ship: ship error")
+	require.Equal(t, 2, calls)
+}
+
+func TestIdempotencyStoreTTL(t *testing.T) {
+	ctx := context.Background()
+
+	now := time.Now()
+	inMemory := NewInMemoryIdempotencyStore()
+	inMemory.now = func() time.Time { return now }
+	recorded, err := inMemory.RecordIfAbsent(ctx, "key", time.Minute)
+	require.Nil(t, err)
+	require.True(t, recorded)
+	exists, err := inMemory.Exists(ctx, "key")
+	require.Nil(t, err)
+	require.True(t, exists)
+	now = now.Add(time.Minute)
+	exists, err = inMemory.Exists(ctx, "key")
+	require.Nil(t, err)
+	require.False(t, exists)
+	recorded, err = inMemory.RecordIfAbsent(ctx, "key", time.Minute)
+	require.Nil(t, err)
+	require.True(t, recorded)
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedisIdempotencyStore(client, "saga:idempotency:")
+	recorded, err = store.RecordIfAbsent(ctx, "key", time.Minute)
+	require.Nil(t, err)
+	require.True(t, recorded)
+	require.Equal(t, time.Minute, mr.TTL("saga:idempotency:key"))
+	mr.FastForward(time.Minute)
+	exists, err = store.Exists(ctx, "key")
+	require.Nil(t, err)
+	require.False(t, exists)
+}
+
+func TestIdempotencyStore_RecordIfAbsent(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testCases := []struct {
+		name  string
+		store checkableIdempotencyStore
+	}{
+		{
+			name:  "in-memory store",
+			store: NewInMemoryIdempotencyStore(),
+		},
+		{
+			name:  "redis store",
+			store: NewRedisIdempotencyStore(client, "saga:idempotency:"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			recorded, err := tc.store.RecordIfAbsent(ctx, "key", time.Minute)
+			require.Nil(t, err)
+			require.True(t, recorded)
+			recorded, err = tc.store.RecordIfAbsent(ctx, "key", time.Minute)
+			require.Nil(t, err)
+			require.False(t, recorded)
+
+			require.Nil(t, tc.store.Delete(ctx, "key"))
+			exists, err := tc.store.Exists(ctx, "key")
+			require.Nil(t, err)
+			require.False(t, exists)
+			recorded, err = tc.store.RecordIfAbsent(ctx, "key", time.Minute)
+			require.Nil(t, err)
+			require.True(t, recorded)
+		})
+	}
+}
+
+func TestWithIdempotentExecution_Concurrent(t *testing.T) {
+	store := NewInMemoryIdempotencyStore()
+	var calls atomic.Int32
+	step := NewStepWithOptions("charge",
+		func(ctx context.Context) error {
+			calls.Add(1)
+			return nil
+		},
+		noop,
+		WithIdempotentExecution(store),
+		WithIdempotencyKey("payment-1"),
+	)
+	ctx := withSagaID(context.Background(), "order-42")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Nil(t, step.ExecuteForward(ctx))
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestWithIdempotentExecution_WithoutSagaID(t *testing.T) {
+	calls := 0
+	step := NewStepWithOptions("charge",
+		func(ctx context.Context) error {
+			calls++
+			return nil
+		},
+		noop,
+		WithIdempotentExecution(NewInMemoryIdempotencyStore()),
+	)
+	err := step.ExecuteForward(context.Background())
+	require.EqualError(t, err, "step charge records its executions in an idempotency store, which requires a saga ID (see WithSagaID)")
+	require.Equal(t, 0, calls)
+}
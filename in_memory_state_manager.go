@@ -10,14 +10,22 @@ import "sync"
 // StateManager interface that stores the state of each step
 // in memory using a map.
 type InMemoryStateManager struct {
-	state map[int]bool
-	mu    sync.RWMutex
+	state         map[int]bool
+	metadata      map[int]map[string]string
+	outputs       map[int][]byte
+	namedState    map[string]bool
+	namedVersions map[string]int
+	mu            sync.RWMutex
 }
 
 // NewInMemoryStateManager creates a new instance of InMemoryStateManager.
 func NewInMemoryStateManager() *InMemoryStateManager {
 	return &InMemoryStateManager{
-		state: make(map[int]bool),
+		state:         make(map[int]bool),
+		metadata:      make(map[int]map[string]string),
+		outputs:       make(map[int][]byte),
+		namedState:    make(map[string]bool),
+		namedVersions: make(map[string]int),
 	}
 }
 
@@ -37,3 +45,80 @@ func (m *InMemoryStateManager) StepState(stepIndex int) (bool, error) {
 	}
 	return state, nil
 }
+
+func (m *InMemoryStateManager) SetNamedStepState(key string, success bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.namedState[key] = success
+	m.namedVersions[key]++
+	return nil
+}
+
+func (m *InMemoryStateManager) NamedStepState(key string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.namedState[key], nil
+}
+
+func (m *InMemoryStateManager) SetNamedStepStateVersioned(key string, success bool, expectedVersion int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if version := m.namedVersions[key]; version != expectedVersion {
+		return 0, &ConcurrentModificationError{
+			StepKey:         key,
+			ExpectedVersion: expectedVersion,
+			ActualVersion:   version,
+		}
+	}
+	m.namedState[key] = success
+	m.namedVersions[key]++
+	return m.namedVersions[key], nil
+}
+
+func (m *InMemoryStateManager) NamedStepStateWithVersion(key string) (bool, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.namedState[key], m.namedVersions[key], nil
+}
+
+func (m *InMemoryStateManager) SetStepMetadata(stepIndex int, metadata map[string]string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.metadata[stepIndex] = copyMetadata(metadata)
+	return nil
+}
+
+func (m *InMemoryStateManager) GetStepMetadata(stepIndex int) (map[string]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return copyMetadata(m.metadata[stepIndex]), nil
+}
+
+func (m *InMemoryStateManager) SetStepOutput(stepIndex int, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outputs[stepIndex] = append([]byte(nil), data...)
+	return nil
+}
+
+func (m *InMemoryStateManager) GetStepOutput(stepIndex int) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	output, exists := m.outputs[stepIndex]
+	if !exists {
+		return nil, nil
+	}
+	return append([]byte(nil), output...), nil
+}
+
+// copyMetadata returns a copy of the given metadata, or nil if empty.
+func copyMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	c := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+)
+
+// StepProvider provides the actions of a lazy step (see NewLazyStep).
+type StepProvider interface {
+	// Forward returns the forward action of the step.
+	Forward() func(ctx context.Context) error
+
+	// Compensate returns the compensation action of the step,
+	// or nil if the step has nothing to compensate.
+	Compensate() func(ctx context.Context) error
+}
+
+// WithLazyStepLoading option defers the loading of the actions of
+// lazy steps (see NewLazyStep) until their first execution. By default,
+// they are loaded when the steps are added to the Saga.
+func WithLazyStepLoading() Option {
+	return func(s *saga) {
+		s.lazyStepLoading = true
+	}
+}
+
+// lazyLoader is implemented by steps whose actions can be
+// loaded before their first execution.
+type lazyLoader interface {
+	// load loads the actions of the step.
+	load()
+}
+
+// lazyStep is a step whose actions are provided by a StepProvider
+// the first time they are needed.
+type lazyStep struct {
+	*step
+	provider StepProvider
+
+	forwardOnce    sync.Once
+	lazyForward    func(ctx context.Context) error
+	compensateOnce sync.Once
+	lazyCompensate func(ctx context.Context) error
+}
+
+// NewLazyStep creates a new Step whose actions are returned by the given
+// provider, which is called at most once per action. It is useful when
+// building the actions is expensive, e.g. when they depend on database
+// queries that should not run when the Saga is defined. The provider is
+// called when the step is added to the Saga, or on the first execution
+// of each action with WithLazyStepLoading.
+func NewLazyStep(name string, provider StepProvider, opts ...StepOption) Step {
+	s := &lazyStep{
+		provider: provider,
+	}
+	s.step = newStep(name, s.runForward, s.runCompensate, opts)
+	return s
+}
+
+func (s *lazyStep) load() {
+	s.loadForward()
+	s.loadCompensate()
+}
+
+// loadForward loads the forward action, if not loaded yet.
+func (s *lazyStep) loadForward() func(ctx context.Context) error {
+	s.forwardOnce.Do(func() {
+		s.lazyForward = s.provider.Forward()
+	})
+	return s.lazyForward
+}
+
+// loadCompensate loads the compensation action, if not loaded yet.
+func (s *lazyStep) loadCompensate() func(ctx context.Context) error {
+	s.compensateOnce.Do(func() {
+		s.lazyCompensate = s.provider.Compensate()
+	})
+	return s.lazyCompensate
+}
+
+// runForward runs the forward action, loading it if needed.
+func (s *lazyStep) runForward(ctx context.Context) error {
+	return s.loadForward()(ctx)
+}
+
+// runCompensate runs the compensation action, loading it if needed.
+// A nil compensation action has nothing to compensate.
+func (s *lazyStep) runCompensate(ctx context.Context) error {
+	compensate := s.loadCompensate()
+	if compensate == nil {
+		return nil
+	}
+	return compensate(ctx)
+}
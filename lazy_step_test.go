@@ -0,0 +1,102 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingStepProvider struct {
+	forwardCalls    int
+	compensateCalls int
+	executed        []string
+}
+
+func (p *countingStepProvider) Forward() func(ctx context.Context) error {
+	p.forwardCalls++
+	return func(ctx context.Context) error {
+		p.executed = append(p.executed, "forward")
+		return nil
+	}
+}
+
+func (p *countingStepProvider) Compensate() func(ctx context.Context) error {
+	p.compensateCalls++
+	return func(ctx context.Context) error {
+		p.executed = append(p.executed, "compensate")
+		return nil
+	}
+}
+
+type noCompensationStepProvider struct {
+	executed []string
+}
+
+func (p *noCompensationStepProvider) Forward() func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		p.executed = append(p.executed, "forward")
+		return nil
+	}
+}
+
+func (p *noCompensationStepProvider) Compensate() func(ctx context.Context) error {
+	return nil
+}
+
+func TestNewLazyStep(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		opts                    []Option
+		expectedCallsBeforeExec int
+	}{
+		{
+			name:                    "eager loading",
+			expectedCallsBeforeExec: 1,
+		},
+		{
+			name:                    "lazy loading",
+			opts:                    []Option{WithLazyStepLoading()},
+			expectedCallsBeforeExec: 0,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := &countingStepProvider{}
+			s := New(tc.opts...)
+			s.AddStep(NewLazyStep("step1", provider))
+			s.AddStep(NewStep("step2",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				noop,
+			))
+			require.Equal(t, tc.expectedCallsBeforeExec, provider.forwardCalls)
+			require.Equal(t, tc.expectedCallsBeforeExec, provider.compensateCalls)
+
+			require.EqualError(t, s.Execute(context.Background()), "executing step step2: forward error")
+			require.Equal(t, 1, provider.forwardCalls)
+			require.Equal(t, 1, provider.compensateCalls)
+			require.Equal(t, []string{"forward", "compensate"}, provider.executed)
+		})
+	}
+}
+
+func TestNewLazyStep_NilCompensation(t *testing.T) {
+	provider := &noCompensationStepProvider{}
+	s := New()
+	s.AddStep(NewLazyStep("step1", provider))
+	s.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("forward error")
+		},
+		noop,
+	))
+	require.EqualError(t, s.Execute(context.Background()), "executing step step2: forward error")
+	require.Equal(t, []string{"forward"}, provider.executed)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"slices"
+)
+
+// StepMiddleware wraps the forward action of a step, given as next,
+// returning the action to run instead (e.g. adding a circuit breaker
+// or retries around calls to an external API).
+type StepMiddleware func(step Step, next func(ctx context.Context) error) func(ctx context.Context) error
+
+// TaggedStep is implemented by steps that carry tags,
+// used to select the middleware applied to them.
+type TaggedStep interface {
+	// Tags returns the tags of the step, or nil if none.
+	Tags() []string
+}
+
+// middlewareSelector selects the steps a middleware applies to.
+type middlewareSelector struct {
+	middleware StepMiddleware
+	match      func(step Step) bool
+}
+
+// WithMiddleware option applies the given middleware to the steps of
+// the Saga not selected by WithMiddlewareForSteps or
+// WithMiddlewareForTags. The first middleware is the outermost one.
+func WithMiddleware(mws ...StepMiddleware) Option {
+	return func(s *saga) {
+		s.middlewares = append(s.middlewares, mws...)
+	}
+}
+
+// WithMiddlewareForSteps option applies the given middleware only to
+// the steps with the given names, instead of the middleware set through
+// WithMiddleware. Selected middleware applies in the order the options
+// are given, the first one being the outermost one.
+func WithMiddlewareForSteps(mw StepMiddleware, stepNames ...string) Option {
+	return func(s *saga) {
+		s.middlewareSelectors = append(s.middlewareSelectors, middlewareSelector{
+			middleware: mw,
+			match: func(step Step) bool {
+				return slices.Contains(stepNames, step.Name())
+			},
+		})
+	}
+}
+
+// WithMiddlewareForTags option applies the given middleware only to
+// the steps with any of the given tags (see WithTags), instead of the
+// middleware set through WithMiddleware. For example, a circuit breaker
+// can be applied to the steps tagged "external-api" only.
+// See WithMiddlewareForSteps.
+func WithMiddlewareForTags(mw StepMiddleware, tags ...string) Option {
+	return func(s *saga) {
+		s.middlewareSelectors = append(s.middlewareSelectors, middlewareSelector{
+			middleware: mw,
+			match: func(step Step) bool {
+				t, ok := stepAs[TaggedStep](step)
+				if !ok {
+					return false
+				}
+				for _, tag := range t.Tags() {
+					if slices.Contains(tags, tag) {
+						return true
+					}
+				}
+				return false
+			},
+		})
+	}
+}
+
+// WithTags option sets the tags of the step.
+func WithTags(tags ...string) StepOption {
+	return func(s *step) {
+		s.tags = append(s.tags, tags...)
+	}
+}
+
+func (s *step) Tags() []string {
+	return s.tags
+}
+
+// buildMiddlewareChains selects the middleware applied to each step.
+func (s *saga) buildMiddlewareChains() {
+	s.stepMiddlewares = make([][]StepMiddleware, len(s.steps))
+	for i, step := range s.steps {
+		var chain []StepMiddleware
+		for _, sel := range s.middlewareSelectors {
+			if sel.match(step) {
+				chain = append(chain, sel.middleware)
+			}
+		}
+		if chain == nil {
+			chain = s.middlewares
+		}
+		s.stepMiddlewares[i] = chain
+	}
+}
+
+// withMiddleware wraps the given forward action of the
+// step at the given execution position with its middleware.
+func (s *saga) withMiddleware(pos int, step Step, forward func(ctx context.Context) error) func(ctx context.Context) error {
+	chain := s.stepMiddlewares[s.declaredIndex(pos)]
+	for i := len(chain) - 1; i >= 0; i-- {
+		forward = chain[i](step, forward)
+	}
+	return forward
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepMiddleware(t *testing.T) {
+	var calls []string
+	recorder := func(label string) StepMiddleware {
+		return func(step Step, next func(ctx context.Context) error) func(ctx context.Context) error {
+			return func(ctx context.Context) error {
+				calls = append(calls, label+" "+step.Name())
+				return next(ctx)
+			}
+		}
+	}
+	saga := New(
+		WithMiddleware(recorder("default 1"), recorder("default 2")),
+		WithMiddlewareForTags(recorder("circuit breaker"), "external-api"),
+		WithMiddlewareForTags(recorder("db retry"), "db"),
+		WithMiddlewareForSteps(recorder("audit"), "charge", "ship"),
+	)
+	for _, tc := range []struct {
+		name string
+		tags []string
+	}{
+		{name: "reserve", tags: []string{"db"}},
+		{name: "charge", tags: []string{"external-api", "db"}},
+		{name: "ship"},
+		{name: "notify"},
+	} {
+		name := tc.name
+		saga.AddStep(NewStepWithOptions(name,
+			func(ctx context.Context) error {
+				calls = append(calls, name)
+				return nil
+			},
+			noop,
+			WithTags(tc.tags...),
+		))
+	}
+	require.Nil(t, saga.Execute(context.Background()))
+	require.Equal(t, []string{
+		"db retry reserve", "reserve",
+		"circuit breaker charge", "db retry charge", "audit charge", "charge",
+		"audit ship", "ship",
+		"default 1 notify", "default 2 notify", "notify",
+	}, calls)
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import "strings"
+
+// WithStepNameFormatter option sets a function transforming the step
+// names reported by the Saga in logs, traces, errors, events, progress
+// messages and its Summary, e.g. to add an environment prefix to all
+// of them. The formatter is applied at execution time, so Step.Name
+// keeps returning the original name.
+func WithStepNameFormatter(format func(name string) string) Option {
+	return func(s *saga) {
+		s.stepNameFormatter = format
+	}
+}
+
+// PrefixFormatter returns a step name formatter
+// prepending the given prefix to step names.
+func PrefixFormatter(prefix string) func(name string) string {
+	return func(name string) string {
+		return prefix + name
+	}
+}
+
+// SuffixFormatter returns a step name formatter
+// appending the given suffix to step names.
+func SuffixFormatter(suffix string) func(name string) string {
+	return func(name string) string {
+		return name + suffix
+	}
+}
+
+// UpperCaseFormatter is a step name formatter
+// mapping step names to upper case.
+func UpperCaseFormatter(name string) string {
+	return strings.ToUpper(name)
+}
+
+// LowerCaseFormatter is a step name formatter
+// mapping step names to lower case.
+func LowerCaseFormatter(name string) string {
+	return strings.ToLower(name)
+}
+
+// TruncateFormatter returns a step name formatter truncating
+// step names longer than maxLen characters.
+func TruncateFormatter(maxLen int) func(name string) string {
+	if maxLen < 0 {
+		maxLen = 0
+	}
+	return func(name string) string {
+		runes := []rune(name)
+		if len(runes) <= maxLen {
+			return name
+		}
+		return string(runes[:maxLen])
+	}
+}
+
+// WithServiceName option sets the name of the service running the Saga,
+// for cross-service correlation. The step names reported by the Saga
+// are prefixed with "{serviceName}/" before being passed to the step
+// name formatter, if any (see WithStepNameFormatter), and so are the
+// keys of the step states when a step name resolver is set (see
+// WithStepNameResolver). The service name is also added as the
+// "service.name" attribute of the step spans, if a tracer is set.
+func WithServiceName(name string) Option {
+	return func(s *saga) {
+		s.serviceName = name
+	}
+}
+
+// withServiceName returns the given name
+// prefixed with the service name, if any.
+func (s *saga) withServiceName(name string) string {
+	if s.serviceName == "" {
+		return name
+	}
+	return s.serviceName + "/" + name
+}
+
+// stepName returns the name of the given step, prefixed with the
+// service name and transformed by the step name formatter, if any.
+func (s *saga) stepName(step Step) string {
+	name := s.withServiceName(step.Name())
+	if s.stepNameFormatter == nil {
+		return name
+	}
+	return s.stepNameFormatter(name)
+}
@@ -0,0 +1,116 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestStepNameFormatters(t *testing.T) {
+	testCases := []struct {
+		name      string
+		formatter func(name string) string
+		stepName  string
+		expected  string
+	}{
+		{
+			name:      "prefix",
+			formatter: PrefixFormatter("prod."),
+			stepName:  "charge",
+			expected:  "prod.charge",
+		},
+		{
+			name:      "suffix",
+			formatter: SuffixFormatter(".v2"),
+			stepName:  "charge",
+			expected:  "charge.v2",
+		},
+		{
+			name:      "upper case",
+			formatter: UpperCaseFormatter,
+			stepName:  "charge",
+			expected:  "CHARGE",
+		},
+		{
+			name:      "lower case",
+			formatter: LowerCaseFormatter,
+			stepName:  "Charge",
+			expected:  "charge",
+		},
+		{
+			name:      "truncate",
+			formatter: TruncateFormatter(6),
+			stepName:  "charge customer",
+			expected:  "charge",
+		},
+		{
+			name:      "truncate short name",
+			formatter: TruncateFormatter(6),
+			stepName:  "ship",
+			expected:  "ship",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.formatter(tc.stepName))
+		})
+	}
+}
+
+func TestWithStepNameFormatter(t *testing.T) {
+	s := New(WithStepNameFormatter(PrefixFormatter("prod.")))
+	step1 := NewStep("step1", noop, noop)
+	s.AddStep(step1)
+	s.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("forward error")
+		},
+		noop,
+	))
+	require.EqualError(t, s.Execute(context.Background()), "executing step prod.step2: forward error")
+	summary := s.Summary()
+	require.Equal(t, "prod.step2", summary.FailedStep.StepName)
+	require.Equal(t, "prod.step1", summary.CompensatedSteps[1].StepName)
+	require.Equal(t, "step1", step1.Name())
+}
+
+func TestWithServiceName(t *testing.T) {
+	sm := NewInMemoryStateManager()
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	s := New(
+		WithServiceName("orders"),
+		WithStepNameFormatter(UpperCaseFormatter),
+		WithStepNameResolver(IdentityResolver),
+		WithStateManager(sm),
+		WithTracer(tp.Tracer("test")),
+	)
+	step1 := NewStep("step1", noop, noop)
+	s.AddStep(step1)
+	s.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("forward error")
+		},
+		noop,
+	))
+	require.EqualError(t, s.Execute(context.Background()), "executing step ORDERS/STEP2: forward error")
+	require.Equal(t, "step1", step1.Name())
+
+	success, err := sm.NamedStepState("orders/step1")
+	require.Nil(t, err)
+	require.True(t, success)
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	require.Equal(t, "ORDERS/STEP1", spans[0].Name())
+	require.Contains(t, spans[0].Attributes(), attribute.String("service.name", "orders"))
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// StepObserver inspects the execution of the forward and compensation
+// actions of the steps of a Saga (see WithObservers). Its methods are
+// called synchronously, possibly concurrently (e.g. with
+// WithParallelCompensation), so they should be fast and safe for
+// concurrent use. The errors reported are sanitized (see
+// WithErrorSanitizer).
+type StepObserver interface {
+	// BeforeForward is called before the forward action of the step.
+	BeforeForward(ctx context.Context, step Step)
+
+	// AfterForward is called after the forward action
+	// of the step, with its duration and error.
+	AfterForward(ctx context.Context, step Step, dur time.Duration, err error)
+
+	// BeforeCompensate is called before the
+	// compensation action of the step.
+	BeforeCompensate(ctx context.Context, step Step)
+
+	// AfterCompensate is called after the compensation
+	// action of the step, with its duration and error.
+	AfterCompensate(ctx context.Context, step Step, dur time.Duration, err error)
+}
+
+// WithObservers option adds observers inspecting the execution
+// of the steps of the Saga independently, in the given order.
+// To add observers at runtime, add an ObserverChain.
+func WithObservers(observers ...StepObserver) Option {
+	return func(s *saga) {
+		for _, o := range observers {
+			s.observers.AddObserver(o)
+		}
+	}
+}
+
+// ObserverChain is a StepObserver notifying a chain of observers,
+// in the order they were added. It is safe for concurrent use, so
+// observers can be added while the Sagas using it execute.
+type ObserverChain struct {
+	mu        sync.RWMutex
+	observers []StepObserver
+}
+
+// NewObserverChain creates a new ObserverChain
+// notifying the given observers.
+func NewObserverChain(observers ...StepObserver) *ObserverChain {
+	return &ObserverChain{observers: append([]StepObserver(nil), observers...)}
+}
+
+// AddObserver adds the given observer at the end of the chain.
+func (c *ObserverChain) AddObserver(o StepObserver) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.observers = append(c.observers, o)
+}
+
+// snapshot returns the observers of the chain.
+func (c *ObserverChain) snapshot() []StepObserver {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.observers
+}
+
+func (c *ObserverChain) BeforeForward(ctx context.Context, step Step) {
+	for _, o := range c.snapshot() {
+		o.BeforeForward(ctx, step)
+	}
+}
+
+func (c *ObserverChain) AfterForward(ctx context.Context, step Step, dur time.Duration, err error) {
+	for _, o := range c.snapshot() {
+		o.AfterForward(ctx, step, dur, err)
+	}
+}
+
+func (c *ObserverChain) BeforeCompensate(ctx context.Context, step Step) {
+	for _, o := range c.snapshot() {
+		o.BeforeCompensate(ctx, step)
+	}
+}
+
+func (c *ObserverChain) AfterCompensate(ctx context.Context, step Step, dur time.Duration, err error) {
+	for _, o := range c.snapshot() {
+		o.AfterCompensate(ctx, step, dur, err)
+	}
+}
+
+// LoggingObserver is a StepObserver logging the execution of the
+// steps: at debug level when an action starts or succeeds, and at
+// warn level when it fails.
+type LoggingObserver struct {
+	logger *slog.Logger
+}
+
+// NewLoggingObserver creates a new LoggingObserver
+// logging with the given logger.
+func NewLoggingObserver(logger *slog.Logger) *LoggingObserver {
+	return &LoggingObserver{logger: logger}
+}
+
+func (o *LoggingObserver) BeforeForward(ctx context.Context, step Step) {
+	o.logger.DebugContext(ctx, "executing step", "step", step.Name())
+}
+
+func (o *LoggingObserver) AfterForward(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.logAfter(ctx, step, "step executed", "step execution failed", dur, err)
+}
+
+func (o *LoggingObserver) BeforeCompensate(ctx context.Context, step Step) {
+	o.logger.DebugContext(ctx, "compensating step", "step", step.Name())
+}
+
+func (o *LoggingObserver) AfterCompensate(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.logAfter(ctx, step, "step compensated", "step compensation failed", dur, err)
+}
+
+// logAfter logs the outcome of an action of the given step.
+func (o *LoggingObserver) logAfter(ctx context.Context, step Step, successMsg, failureMsg string, dur time.Duration, err error) {
+	if err != nil {
+		o.logger.WarnContext(ctx, failureMsg, "step", step.Name(), "duration", dur, "error", err)
+		return
+	}
+	o.logger.DebugContext(ctx, successMsg, "step", step.Name(), "duration", dur)
+}
+
+// MetricsObserver is a StepObserver recording the duration of
+// the actions of the steps in the "saga_step_duration_seconds"
+// Prometheus histogram, by step, action ("forward" or
+// "compensate") and result ("success" or "failure").
+type MetricsObserver struct {
+	durations *prometheus.HistogramVec
+}
+
+// NewMetricsObserver creates a new MetricsObserver and registers its
+// metrics in the given registerer. Registration errors cause it to panic.
+func NewMetricsObserver(reg prometheus.Registerer) *MetricsObserver {
+	o := &MetricsObserver{
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "saga_step_duration_seconds",
+			Help: "Duration of the actions of the saga steps, by step, action and result.",
+		}, []string{"step", "action", "result"}),
+	}
+	reg.MustRegister(o.durations)
+	return o
+}
+
+func (o *MetricsObserver) BeforeForward(ctx context.Context, step Step) {}
+
+func (o *MetricsObserver) AfterForward(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.observe(step, "forward", dur, err)
+}
+
+func (o *MetricsObserver) BeforeCompensate(ctx context.Context, step Step) {}
+
+func (o *MetricsObserver) AfterCompensate(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.observe(step, "compensate", dur, err)
+}
+
+// observe records the duration of an action of the given step.
+func (o *MetricsObserver) observe(step Step, action string, dur time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	o.durations.WithLabelValues(step.Name(), action, result).Observe(dur.Seconds())
+}
+
+// TracingObserver is a StepObserver recording an OpenTelemetry span for
+// each action of the steps, named after the step and the action (e.g.
+// "reserve.forward"). Since observers cannot change the context of the
+// action, the span is recorded once the action ends, starting at the
+// time it started, as a child of the span of the step, if any (see
+// WithTracer).
+type TracingObserver struct {
+	tracer trace.Tracer
+}
+
+// NewTracingObserver creates a new TracingObserver
+// recording spans with the given tracer.
+func NewTracingObserver(tracer trace.Tracer) *TracingObserver {
+	return &TracingObserver{tracer: tracer}
+}
+
+func (o *TracingObserver) BeforeForward(ctx context.Context, step Step) {}
+
+func (o *TracingObserver) AfterForward(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.record(ctx, step, "forward", dur, err)
+}
+
+func (o *TracingObserver) BeforeCompensate(ctx context.Context, step Step) {}
+
+func (o *TracingObserver) AfterCompensate(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.record(ctx, step, "compensate", dur, err)
+}
+
+// record records the span of an action of the given step.
+func (o *TracingObserver) record(ctx context.Context, step Step, action string, dur time.Duration, err error) {
+	end := clockFromContext(ctx).Now()
+	_, span := o.tracer.Start(ctx, step.Name()+"."+action, trace.WithTimestamp(end.Add(-dur)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(end))
+}
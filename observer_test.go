@@ -0,0 +1,222 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordingObserver records the notifications it receives.
+type recordingObserver struct {
+	name  string
+	mu    sync.Mutex
+	calls []string
+}
+
+func (o *recordingObserver) record(call string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls = append(o.calls, call)
+}
+
+func (o *recordingObserver) BeforeForward(ctx context.Context, step Step) {
+	o.record(o.name + " before forward " + step.Name())
+}
+
+func (o *recordingObserver) AfterForward(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.record(fmt.Sprintf("%s after forward %s: %s", o.name, step.Name(), errString(err)))
+}
+
+func (o *recordingObserver) BeforeCompensate(ctx context.Context, step Step) {
+	o.record(o.name + " before compensate " + step.Name())
+}
+
+func (o *recordingObserver) AfterCompensate(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.record(fmt.Sprintf("%s after compensate %s: %s", o.name, step.Name(), errString(err)))
+}
+
+func TestWithObservers(t *testing.T) {
+	first := &recordingObserver{name: "first"}
+	second := &recordingObserver{name: "second"}
+	saga := New(WithObservers(first, second))
+	saga.AddStep(NewStep("step1", noop, func(ctx context.Context) error {
+		return errors.New("step1 compensation error")
+	}))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("step2 error")
+		},
+		noop,
+	))
+	require.NotNil(t, saga.Execute(context.Background()))
+
+	expected := []string{
+		" before forward step1",
+		" after forward step1: <nil>",
+		" before forward step2",
+		" after forward step2: step2 error",
+		" before compensate step2",
+		" after compensate step2: <nil>",
+		" before compensate step1",
+		" after compensate step1: step1 compensation error",
+	}
+	for _, o := range []*recordingObserver{first, second} {
+		var calls []string
+		for _, call := range expected {
+			calls = append(calls, o.name+call)
+		}
+		require.Equal(t, calls, o.calls)
+	}
+}
+
+func TestObserverChain_AddObserver(t *testing.T) {
+	first := &recordingObserver{name: "first"}
+	second := &recordingObserver{name: "second"}
+	chain := NewObserverChain(first)
+	saga := New(WithObservers(chain))
+	saga.AddStep(NewStep("step1", func(ctx context.Context) error {
+		chain.AddObserver(second)
+		return nil
+	}, noop))
+	require.Nil(t, saga.Execute(context.Background()))
+	require.Equal(t, []string{"first before forward step1", "first after forward step1: <nil>"}, first.calls)
+	require.Equal(t, []string{"second after forward step1: <nil>"}, second.calls)
+}
+
+func TestNewObserverChain_CopiesObservers(t *testing.T) {
+	first := &recordingObserver{name: "first"}
+	observers := []StepObserver{first}
+	chain := NewObserverChain(observers...)
+	observers[0] = &recordingObserver{name: "second"}
+	chain.BeforeForward(context.Background(), NewStep("step1", noop, noop))
+	require.Equal(t, []string{"first before forward step1"}, first.calls)
+}
+
+// durationObserver records the durations of the actions it observes.
+type durationObserver struct {
+	forward, compensate []time.Duration
+}
+
+func (o *durationObserver) BeforeForward(ctx context.Context, step Step) {}
+
+func (o *durationObserver) AfterForward(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.forward = append(o.forward, dur)
+}
+
+func (o *durationObserver) BeforeCompensate(ctx context.Context, step Step) {}
+
+func (o *durationObserver) AfterCompensate(ctx context.Context, step Step, dur time.Duration, err error) {
+	o.compensate = append(o.compensate, dur)
+}
+
+func TestWithObservers_Durations(t *testing.T) {
+	clock := NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	limiter := NewResourceLimiter()
+	require.NoError(t, limiter.SetLimit("payment-gateway", 1))
+	observer := &durationObserver{}
+	saga := New(WithClock(clock), WithObservers(observer), WithResourceLimiter(limiter))
+	saga.AddStep(NewStepWithOptions("charge",
+		func(ctx context.Context) error {
+			clock.Advance(time.Minute)
+			return errors.New("charge error")
+		},
+		func(ctx context.Context) error {
+			clock.Advance(time.Second)
+			return nil
+		},
+		WithResourceTag("payment-gateway"),
+	))
+
+	// The time spent waiting for the resource is not observed.
+	sem := limiter.semaphore("payment-gateway")
+	require.NoError(t, sem.Acquire(context.Background(), 1))
+	done := make(chan error, 1)
+	go func() {
+		done <- saga.Execute(context.Background())
+	}()
+	time.Sleep(10 * time.Millisecond)
+	clock.Advance(time.Hour)
+	sem.Release(1)
+	require.NotNil(t, <-done)
+
+	require.Equal(t, []time.Duration{time.Minute}, observer.forward)
+	require.Equal(t, []time.Duration{time.Second}, observer.compensate)
+}
+
+func TestBuiltInObservers(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	reg := prometheus.NewRegistry()
+	metrics := NewMetricsObserver(reg)
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	saga := New(WithObservers(NewLoggingObserver(logger), metrics, NewTracingObserver(tp.Tracer("test"))))
+	saga.AddStep(NewStep("step1", noop, noop))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("step2 error")
+		},
+		noop,
+	))
+	require.NotNil(t, saga.Execute(context.Background()))
+
+	require.Equal(t, `level=DEBUG msg="executing step" step=step1
+level=DEBUG msg="step executed" step=step1
+level=DEBUG msg="executing step" step=step2
+level=WARN msg="step execution failed" step=step2 error="step2 error"
+level=DEBUG msg="compensating step" step=step2
+level=DEBUG msg="step compensated" step=step2
+level=DEBUG msg="compensating step" step=step1
+level=DEBUG msg="step compensated" step=step1
+`, buf.String())
+
+	// step1 and step2 forward, step2 and step1 compensate.
+	count, err := testutil.GatherAndCount(reg, "saga_step_duration_seconds")
+	require.Nil(t, err)
+	require.Equal(t, 4, count)
+	require.Equal(t, uint64(1), histogramCount(t, metrics, "step2", "forward", "failure"))
+
+	spans := sr.Ended()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name())
+		require.False(t, span.StartTime().After(span.EndTime()))
+	}
+	require.Equal(t, []string{"step1.forward", "step2.forward", "step2.compensate", "step1.compensate"}, names)
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, "step2 error", spans[1].Status().Description)
+}
+
+// histogramCount returns the number of observations of the
+// step duration histogram with the given labels.
+func histogramCount(t *testing.T, o *MetricsObserver, labels ...string) uint64 {
+	var m dto.Metric
+	require.Nil(t, o.durations.WithLabelValues(labels...).(prometheus.Histogram).Write(&m))
+	return m.GetHistogram().GetSampleCount()
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+// OptionalStep is implemented by steps that can be marked
+// as non-critical. When the forward action of an optional step
+// fails, the Saga records the failure and continues with
+// the next step instead of triggering compensation.
+type OptionalStep interface {
+	// Optional reports whether the step is non-critical.
+	Optional() bool
+}
+
+// optionalStep wraps a Step marking it as non-critical.
+type optionalStep struct {
+	Step
+}
+
+// AsOptional wraps the given step, marking it as non-critical.
+func AsOptional(step Step) Step {
+	return &optionalStep{Step: step}
+}
+
+func (s *optionalStep) Optional() bool {
+	return true
+}
+
+func (s *optionalStep) Unwrap() Step {
+	return s.Step
+}
+
+// isOptional reports whether the given step is non-critical.
+func isOptional(step Step) bool {
+	os, ok := stepAs[OptionalStep](step)
+	return ok && os.Optional()
+}
@@ -4,10 +4,34 @@
 
 package saga
 
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
 // Option defines a function type that applies a
 // configuration option to a Saga instance.
 type Option func(*saga)
 
+// WithSagaID option sets the identifier of the Saga.
+func WithSagaID(id string) Option {
+	return func(s *saga) {
+		s.id = id
+	}
+}
+
+// WithTenantID option isolates the state of the Saga per tenant,
+// by wrapping its StateManager (the default one or the one set with
+// WithStateManager) with a TenantAwareStateManager. If the StateManager
+// cannot keep the states of different tenants apart (see
+// NewTenantAwareStateManager), Execute fails without running any step.
+func WithTenantID(id string) Option {
+	return func(s *saga) {
+		s.tenantID = id
+	}
+}
+
 // WithStateManager option allows the Saga to use a
 // custom StateManager for tracking the state of each step,
 // replacing the default in-memory state manager.
@@ -16,3 +40,118 @@ func WithStateManager(sm StateManager) Option {
 		s.stateManager = sm
 	}
 }
+
+// WithEagerCompensation option makes the Saga compensate
+// previously executed steps as soon as a step fails,
+// before Execute returns. This is the default behavior.
+func WithEagerCompensation() Option {
+	return func(s *saga) {
+		s.lazyComp = false
+	}
+}
+
+// WithLazyCompensation option defers compensation when a step fails:
+// Execute returns the step error immediately, and compensation only
+// runs when Compensate is explicitly called by the caller.
+//
+// The position of the failed step is kept in memory by the Saga instance,
+// so Compensate must be called on the same instance that ran Execute.
+// If the process restarts before compensating, that position is lost;
+// only step states recorded by the StateManager survive, and only if it
+// is durable. Callers deferring compensation across restarts must use a
+// durable StateManager and persist enough information on their own to
+// rebuild the Saga and decide what to roll back.
+func WithLazyCompensation() Option {
+	return func(s *saga) {
+		s.lazyComp = true
+	}
+}
+
+// WithAsyncCompensation option defers compensation to the given
+// handler when a step fails: instead of compensating inline, Execute
+// calls the handler with the Saga and the name of the failed step, then
+// returns the step error immediately. The handler is responsible for
+// scheduling the compensation, e.g. through a job queue, a goroutine or
+// an external service, that eventually calls Compensate. The handler
+// is called once Execute releases the Saga, so it can also call
+// Compensate synchronously. If the handler fails, Execute returns its
+// error along with the step error.
+//
+// Compensate skips the steps already compensated, so it can safely be
+// retried. See WithLazyCompensation for the limits of deferring
+// compensation across process restarts.
+func WithAsyncCompensation(handler func(saga Saga, failedStep string) error) Option {
+	return func(s *saga) {
+		s.asyncCompensation = handler
+	}
+}
+
+// WithSkipCompensationOnSuccessfulSaga option marks the compensation
+// of all the steps as not needed once Execute completes without error,
+// so that a later call to Compensate skips their compensation actions.
+// It is meant for Sagas whose compensations, often expensive, only make
+// sense to recover from a failed execution, not to roll back a
+// successful one. The mark is cleared by the next execution.
+func WithSkipCompensationOnSuccessfulSaga() Option {
+	return func(s *saga) {
+		s.skipCompensationOnSuccess = true
+	}
+}
+
+// WithLogger option sets the logger used by the Saga to
+// report noteworthy events during its execution.
+// By default, the Saga does not log anything.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *saga) {
+		s.logger = logger
+	}
+}
+
+// WithLogSanitizer option sets a function used to transform every
+// value extracted by step input and output loggers before they are
+// logged, e.g. to redact personally identifiable information.
+func WithLogSanitizer(sanitize func(key string, val any) any) Option {
+	return func(s *saga) {
+		s.logSanitizer = sanitize
+	}
+}
+
+// WithPreflightContextCheck option makes the Saga check the context
+// before executing each step. If the context is already done, the step
+// is not executed and fails with the context error, triggering compensation.
+// Without this option, cancellation is only noticed by steps that
+// respect the context themselves.
+func WithPreflightContextCheck() Option {
+	return func(s *saga) {
+		s.preflightCtxCheck = true
+	}
+}
+
+// WithPanicRecovery option makes the Saga recover from panics in
+// the forward and compensation actions of its steps, turning them
+// into a *PanicError. A recovered forward panic is handled as any
+// other step failure, triggering compensation.
+func WithPanicRecovery() Option {
+	return func(s *saga) {
+		s.panicRecovery = true
+	}
+}
+
+// WithOnCheckpoint option sets a hook called whenever the Saga
+// reaches a checkpoint (see Saga.AddCheckpoint), with the checkpoint
+// name and the number of steps completed so far.
+func WithOnCheckpoint(hook func(ctx context.Context, name string, completedSteps int)) Option {
+	return func(s *saga) {
+		s.onCheckpoint = hook
+	}
+}
+
+// WithWaitGroup option makes the Saga track its executions in the
+// given WaitGroup: Execute calls wg.Add(1) when it starts and wg.Done()
+// when it returns, even if it panics. It allows a shutdown manager to
+// call wg.Wait() to block until all the active executions complete.
+func WithWaitGroup(wg *sync.WaitGroup) Option {
+	return func(s *saga) {
+		s.waitGroup = wg
+	}
+}
@@ -0,0 +1,324 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+// Package otlp exports saga telemetry as OTLP spans to an
+// OpenTelemetry Collector, without requiring the caller
+// to set up the OpenTelemetry SDK.
+package otlp
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/tiagomelo/go-saga"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+const (
+	// scopeName is the instrumentation scope of the exported spans.
+	scopeName = "github.com/tiagomelo/go-saga/otlp"
+
+	// eventDeadLetter is the type of the events exported
+	// for the items handled as dead letters.
+	eventDeadLetter = "dead_letter"
+
+	defaultServiceName   = "go-saga"
+	defaultBatchSize     = 512
+	defaultFlushInterval = 5 * time.Second
+)
+
+// OTLPExporter batches saga events and exports them as OTLP spans
+// over gRPC to an OpenTelemetry Collector. Events are exported when
+// a batch is full, periodically, and on Flush and Shutdown.
+//
+// It implements saga.DeadLetterHandler, exporting the steps whose
+// compensation failed, and can be registered as a saga event hook
+// with WithEventExporter.
+type OTLPExporter struct {
+	client        otlptrace.Client
+	grpcOpts      []otlptracegrpc.Option
+	serviceName   string
+	batchSize     int
+	flushInterval time.Duration
+	errorHandler  func(err error)
+
+	startOnce    sync.Once
+	startErr     error
+	shutdownOnce sync.Once
+
+	mu    sync.Mutex
+	batch []*tracepb.Span
+
+	flushCh chan struct{}
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// OTLPOption defines a function type that applies a
+// configuration option to an OTLPExporter instance.
+type OTLPOption func(*OTLPExporter)
+
+// WithInsecure option disables transport security
+// for the connection to the Collector.
+func WithInsecure() OTLPOption {
+	return func(e *OTLPExporter) {
+		e.grpcOpts = append(e.grpcOpts, otlptracegrpc.WithInsecure())
+	}
+}
+
+// WithHeaders option sets the gRPC headers sent with each export.
+func WithHeaders(headers map[string]string) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.grpcOpts = append(e.grpcOpts, otlptracegrpc.WithHeaders(headers))
+	}
+}
+
+// WithServiceName option sets the service.name resource attribute
+// of the exported spans. It defaults to "go-saga".
+func WithServiceName(name string) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.serviceName = name
+	}
+}
+
+// WithBatchSize option sets the number of events that triggers an
+// export. It defaults to 512.
+func WithBatchSize(size int) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.batchSize = size
+	}
+}
+
+// WithFlushInterval option sets the interval at which batched events
+// are exported, even if the batch is not full. It defaults to 5s.
+func WithFlushInterval(d time.Duration) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.flushInterval = d
+	}
+}
+
+// WithErrorHandler option sets a function called with the errors
+// of the exports made in the background. They are dropped by default.
+func WithErrorHandler(handler func(err error)) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.errorHandler = handler
+	}
+}
+
+// WithClient option sets the OTLP client used to export the spans,
+// replacing the gRPC one created for the endpoint.
+func WithClient(client otlptrace.Client) OTLPOption {
+	return func(e *OTLPExporter) {
+		e.client = client
+	}
+}
+
+// NewOTLPExporter creates a new OTLPExporter exporting
+// to the Collector listening at the given endpoint
+// (e.g. "localhost:4317"). Shutdown must be called
+// to export the remaining events and release resources.
+func NewOTLPExporter(endpoint string, opts ...OTLPOption) *OTLPExporter {
+	e := &OTLPExporter{
+		serviceName:   defaultServiceName,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		flushCh:       make(chan struct{}, 1),
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.client == nil {
+		grpcOpts := append([]otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}, e.grpcOpts...)
+		e.client = otlptracegrpc.NewClient(grpcOpts...)
+	}
+	e.wg.Add(1)
+	go e.run()
+	return e
+}
+
+// WithEventExporter option registers the given exporter
+// as an event hook of the Saga, exporting all its events.
+func WithEventExporter(exp *OTLPExporter) saga.Option {
+	return saga.WithEventHook(exp.Export)
+}
+
+// Export queues the given saga event for export.
+func (e *OTLPExporter) Export(ctx context.Context, event saga.SagaEvent) {
+	e.enqueue(newSpan(event))
+}
+
+// HandleDeadLetter queues the given dead letter item for export.
+func (e *OTLPExporter) HandleDeadLetter(ctx context.Context, item saga.DeadLetterItem) error {
+	e.enqueue(newSpan(saga.SagaEvent{
+		SagaID:    item.SagaID,
+		Type:      eventDeadLetter,
+		StepName:  item.StepName,
+		StepIndex: item.StepIndex,
+		Err:       item.Err,
+		Timestamp: item.FailedAt,
+	}))
+	return nil
+}
+
+// Flush exports all the queued events.
+func (e *OTLPExporter) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	batch := e.batch
+	e.batch = nil
+	e.mu.Unlock()
+	if len(batch) == 0 {
+		return nil
+	}
+	e.startOnce.Do(func() {
+		e.startErr = e.client.Start(ctx)
+	})
+	if e.startErr != nil {
+		return errors.Wrap(e.startErr, "starting OTLP client")
+	}
+	if err := e.client.UploadTraces(ctx, e.resourceSpans(batch)); err != nil {
+		return errors.Wrapf(err, "exporting %d spans", len(batch))
+	}
+	return nil
+}
+
+// Shutdown stops the periodic export, exports
+// the queued events and closes the connection.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	var err error
+	e.shutdownOnce.Do(func() {
+		close(e.done)
+		e.wg.Wait()
+		err = e.Flush(ctx)
+		if e.startErr == nil {
+			if stopErr := e.client.Stop(ctx); stopErr != nil && err == nil {
+				err = errors.Wrap(stopErr, "stopping OTLP client")
+			}
+		}
+	})
+	return err
+}
+
+// enqueue adds the given span to the batch,
+// triggering an export if the batch is full.
+func (e *OTLPExporter) enqueue(span *tracepb.Span) {
+	e.mu.Lock()
+	e.batch = append(e.batch, span)
+	full := len(e.batch) >= e.batchSize
+	e.mu.Unlock()
+	if full {
+		select {
+		case e.flushCh <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports the batched events periodically
+// and whenever the batch is full.
+func (e *OTLPExporter) run() {
+	defer e.wg.Done()
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		case <-e.flushCh:
+		}
+		if err := e.Flush(context.Background()); err != nil && e.errorHandler != nil {
+			e.errorHandler(err)
+		}
+	}
+}
+
+// resourceSpans wraps the given spans with the exporter resource.
+func (e *OTLPExporter) resourceSpans(spans []*tracepb.Span) []*tracepb.ResourceSpans {
+	return []*tracepb.ResourceSpans{{
+		Resource: &resourcepb.Resource{
+			Attributes: []*commonpb.KeyValue{stringAttr("service.name", e.serviceName)},
+		},
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Scope: &commonpb.InstrumentationScope{Name: scopeName},
+			Spans: spans,
+		}},
+	}}
+}
+
+// newSpan converts the given saga event into a span. Spans of the
+// same saga share the same trace ID, derived from the saga ID.
+func newSpan(event saga.SagaEvent) *tracepb.Span {
+	end := event.Timestamp
+	if end.IsZero() {
+		end = time.Now()
+	}
+	name := event.StepName
+	switch event.Type {
+	case saga.EventStepCompensated, saga.EventCompensationFailed, eventDeadLetter:
+		name = "compensate " + name
+	}
+	span := &tracepb.Span{
+		TraceId:           traceID(event.SagaID),
+		SpanId:            randomBytes(8),
+		Name:              name,
+		Kind:              tracepb.Span_SPAN_KIND_INTERNAL,
+		StartTimeUnixNano: uint64(end.Add(-event.Duration).UnixNano()),
+		EndTimeUnixNano:   uint64(end.UnixNano()),
+		Attributes: []*commonpb.KeyValue{
+			stringAttr("saga.id", event.SagaID),
+			stringAttr("saga.step.name", event.StepName),
+			intAttr("saga.step.index", int64(event.StepIndex)),
+			stringAttr("saga.event.type", event.Type),
+		},
+		Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_OK},
+	}
+	if event.Err != nil {
+		span.Status = &tracepb.Status{
+			Code:    tracepb.Status_STATUS_CODE_ERROR,
+			Message: event.Err.Error(),
+		}
+	}
+	return span
+}
+
+// traceID returns the trace ID of the given saga.
+// Sagas without an ID get a random trace ID.
+func traceID(sagaID string) []byte {
+	if sagaID == "" {
+		return randomBytes(16)
+	}
+	sum := sha256.Sum256([]byte(sagaID))
+	return sum[:16]
+}
+
+// randomBytes returns n random bytes.
+func randomBytes(n int) []byte {
+	b := make([]byte, n)
+	// crypto/rand.Read never returns an error.
+	_, _ = rand.Read(b)
+	return b
+}
+
+func stringAttr(key, val string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: val}},
+	}
+}
+
+func intAttr(key string, val int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: val}},
+	}
+}
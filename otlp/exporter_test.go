@@ -0,0 +1,143 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package otlp
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tiagomelo/go-saga"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+type mockClient struct {
+	mu        sync.Mutex
+	spans     []*tracepb.Span
+	uploads   int
+	uploadErr error
+	started   bool
+	stopped   bool
+}
+
+func (m *mockClient) Start(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.started = true
+	return nil
+}
+
+func (m *mockClient) Stop(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopped = true
+	return nil
+}
+
+func (m *mockClient) UploadTraces(ctx context.Context, rs []*tracepb.ResourceSpans) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploads++
+	if m.uploadErr != nil {
+		return m.uploadErr
+	}
+	for _, r := range rs {
+		for _, ss := range r.ScopeSpans {
+			m.spans = append(m.spans, ss.Spans...)
+		}
+	}
+	return nil
+}
+
+func (m *mockClient) exportedSpans() []*tracepb.Span {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*tracepb.Span(nil), m.spans...)
+}
+
+func noop(ctx context.Context) error {
+	return nil
+}
+
+func TestOTLPExporter(t *testing.T) {
+	client := &mockClient{}
+	exp := NewOTLPExporter("localhost:4317", WithClient(client), WithFlushInterval(time.Hour))
+	s := saga.New(
+		saga.WithSagaID("order-42"),
+		WithEventExporter(exp),
+		saga.WithDeadLetterHandler(exp),
+	)
+	s.AddStep(saga.NewStep("reserve",
+		noop,
+		func(ctx context.Context) error {
+			return errors.New("release failed")
+		},
+	))
+	s.AddStep(saga.NewStep("charge",
+		func(ctx context.Context) error {
+			return errors.New("card declined")
+		},
+		noop,
+	))
+	require.NotNil(t, s.Execute(context.Background()))
+	require.Empty(t, client.exportedSpans())
+
+	require.Nil(t, exp.Shutdown(context.Background()))
+	require.True(t, client.started)
+	require.True(t, client.stopped)
+
+	type span struct {
+		name      string
+		eventType string
+		status    tracepb.Status_StatusCode
+		message   string
+	}
+	expectedSpans := []span{
+		{name: "reserve", eventType: saga.EventStepCompleted, status: tracepb.Status_STATUS_CODE_OK},
+		{name: "charge", eventType: saga.EventStepFailed, status: tracepb.Status_STATUS_CODE_ERROR, message: "card declined"},
+		{name: "compensate charge", eventType: saga.EventStepCompensated, status: tracepb.Status_STATUS_CODE_OK},
+		{name: "compensate reserve", eventType: saga.EventCompensationFailed, status: tracepb.Status_STATUS_CODE_ERROR, message: "release failed"},
+		{name: "compensate reserve", eventType: "dead_letter", status: tracepb.Status_STATUS_CODE_ERROR, message: "release failed"},
+	}
+	spans := client.exportedSpans()
+	require.Len(t, spans, len(expectedSpans))
+	for i, expected := range expectedSpans {
+		require.Equal(t, expected.name, spans[i].Name)
+		require.Equal(t, expected.status, spans[i].Status.Code)
+		require.Equal(t, expected.message, spans[i].Status.Message)
+		require.Equal(t, expected.eventType, spans[i].Attributes[3].Value.GetStringValue())
+		require.Equal(t, "order-42", spans[i].Attributes[0].Value.GetStringValue())
+		require.Equal(t, spans[0].TraceId, spans[i].TraceId)
+		require.LessOrEqual(t, spans[i].StartTimeUnixNano, spans[i].EndTimeUnixNano)
+	}
+}
+
+func TestOTLPExporter_BatchSize(t *testing.T) {
+	client := &mockClient{}
+	exp := NewOTLPExporter("localhost:4317",
+		WithClient(client),
+		WithBatchSize(2),
+		WithFlushInterval(time.Hour),
+	)
+	defer exp.Shutdown(context.Background())
+	exp.Export(context.Background(), saga.SagaEvent{StepName: "step1", Type: saga.EventStepCompleted})
+	require.Never(t, func() bool { return len(client.exportedSpans()) > 0 }, 50*time.Millisecond, 10*time.Millisecond)
+	exp.Export(context.Background(), saga.SagaEvent{StepName: "step2", Type: saga.EventStepCompleted})
+	require.Eventually(t, func() bool { return len(client.exportedSpans()) == 2 }, time.Second, 10*time.Millisecond)
+}
+
+func TestOTLPExporter_FlushError(t *testing.T) {
+	client := &mockClient{uploadErr: errors.New("collector unavailable")}
+	exp := NewOTLPExporter("localhost:4317", WithClient(client), WithFlushInterval(time.Hour))
+	defer exp.Shutdown(context.Background())
+	require.Nil(t, exp.Flush(context.Background()))
+	exp.Export(context.Background(), saga.SagaEvent{StepName: "step1", Type: saga.EventStepCompleted})
+	err := exp.Flush(context.Background())
+	require.EqualError(t, err, "exporting 1 spans: collector unavailable")
+	require.Equal(t, 1, client.uploads)
+}
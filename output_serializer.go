@@ -0,0 +1,63 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// StepOutputSerializer serializes the step outputs
+// captured with WithOutputValueCapture.
+type StepOutputSerializer interface {
+	// Encode serializes the given value.
+	Encode(v any) ([]byte, error)
+
+	// Decode deserializes the given data into target,
+	// which must be a pointer.
+	Decode(data []byte, target any) error
+}
+
+// JSONOutputSerializer is a StepOutputSerializer using JSON.
+// It is the default one.
+type JSONOutputSerializer struct{}
+
+func (JSONOutputSerializer) Encode(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONOutputSerializer) Decode(data []byte, target any) error {
+	return json.Unmarshal(data, target)
+}
+
+// GobOutputSerializer is a StepOutputSerializer using encoding/gob.
+type GobOutputSerializer struct{}
+
+func (GobOutputSerializer) Encode(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobOutputSerializer) Decode(data []byte, target any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(target)
+}
+
+// MsgpackOutputSerializer is a StepOutputSerializer using MessagePack,
+// an efficient binary format suited to large outputs.
+type MsgpackOutputSerializer struct{}
+
+func (MsgpackOutputSerializer) Encode(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackOutputSerializer) Decode(data []byte, target any) error {
+	return msgpack.Unmarshal(data, target)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type sampleOutput struct {
+	OrderID int
+	Items   []string
+}
+
+func TestWithOutputSerializer(t *testing.T) {
+	testCases := []struct {
+		name       string
+		opts       []StepOption
+		serializer StepOutputSerializer
+	}{
+		{
+			name:       "default",
+			serializer: JSONOutputSerializer{},
+		},
+		{
+			name:       "json",
+			opts:       []StepOption{WithOutputSerializer(JSONOutputSerializer{})},
+			serializer: JSONOutputSerializer{},
+		},
+		{
+			name:       "gob",
+			opts:       []StepOption{WithOutputSerializer(GobOutputSerializer{})},
+			serializer: GobOutputSerializer{},
+		},
+		{
+			name:       "msgpack",
+			opts:       []StepOption{WithOutputSerializer(MsgpackOutputSerializer{})},
+			serializer: MsgpackOutputSerializer{},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			expected := sampleOutput{OrderID: 42, Items: []string{"book", "pen"}}
+			sm := NewInMemoryStateManager()
+			s := New(WithStateManager(sm))
+			opts := append([]StepOption{
+				WithOutputValueCapture(func(ctx context.Context) (any, error) {
+					return expected, nil
+				}),
+			}, tc.opts...)
+			s.AddStep(NewStepWithOptions("step1", noop, noop, opts...))
+			require.Nil(t, s.Execute(context.Background()))
+
+			data, err := sm.GetStepOutput(0)
+			require.Nil(t, err)
+			var output sampleOutput
+			require.Nil(t, tc.serializer.Decode(data, &output))
+			require.Equal(t, expected, output)
+		})
+	}
+}
+
+func TestWithOutputSerializer_EncodingError(t *testing.T) {
+	s := New()
+	s.AddStep(NewStepWithOptions("step1", noop, noop,
+		WithOutputValueCapture(func(ctx context.Context) (any, error) {
+			return make(chan int), nil
+		}),
+	))
+	require.EqualError(t, s.Execute(context.Background()),
+		"capturing output of step step1: encoding output of step step1: json: unsupported type: chan int")
+}
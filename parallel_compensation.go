@@ -0,0 +1,123 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+)
+
+// WithParallelCompensation option makes the Saga run the compensation
+// actions of its steps concurrently, with at most maxConcurrency of them
+// running at the same time (no limit if maxConcurrency <= 0). Their
+// errors are collected into a MultiError.
+//
+// The order of compensation is no longer guaranteed, so this should
+// only be used when the compensations of the steps are independent
+// from each other. Hooks such as WithEventHook are called concurrently.
+func WithParallelCompensation(maxConcurrency int) Option {
+	return func(s *saga) {
+		s.parallelCompensation = true
+		s.compensationConcurrency = maxConcurrency
+	}
+}
+
+// WithParallelCompensationGrouped option makes the Saga compensate its
+// steps in groups of step indexes: groups are compensated sequentially,
+// in the given order, while the steps within a group are compensated
+// concurrently. Steps that are not part of any group are compensated
+// sequentially, in reverse order, after all the groups.
+//
+// It can be combined with WithParallelCompensation to limit the
+// concurrency within each group. See WithParallelCompensation.
+func WithParallelCompensationGrouped(groups [][]int) Option {
+	return func(s *saga) {
+		s.parallelCompensation = true
+		s.compensationGroups = groups
+	}
+}
+
+// compensateInParallel compensates the steps at the given indexes
+// according to the parallel compensation configuration, returning
+// the compensation errors ordered as the given indexes.
+func (s *saga) compensateInParallel(ctx context.Context, indexes []int) []error {
+	if len(s.compensationGroups) == 0 {
+		return s.compensateConcurrently(ctx, indexes, s.compensationConcurrency)
+	}
+	pending := make(map[int]bool, len(indexes))
+	for _, i := range indexes {
+		pending[i] = true
+	}
+	var compensationErrors []error
+	for _, group := range s.compensationGroups {
+		var groupIndexes []int
+		for _, i := range group {
+			if pending[i] {
+				groupIndexes = append(groupIndexes, i)
+				delete(pending, i)
+			}
+		}
+		compensationErrors = append(compensationErrors, s.compensateConcurrently(ctx, groupIndexes, s.compensationConcurrency)...)
+	}
+	for _, i := range indexes {
+		if !pending[i] {
+			continue
+		}
+		if err := s.compensateStep(ctx, i); err != nil {
+			compensationErrors = append(compensationErrors, err)
+		}
+	}
+	return compensationErrors
+}
+
+// compensateConcurrently compensates the steps at the given indexes
+// concurrently, with at most maxConcurrency of them at the same time
+// (no limit if maxConcurrency <= 0), returning the compensation errors
+// ordered as the given indexes.
+func (s *saga) compensateConcurrently(ctx context.Context, indexes []int, maxConcurrency int) []error {
+	workers := maxConcurrency
+	if workers <= 0 || workers > len(indexes) {
+		workers = len(indexes)
+	}
+	errs := make([]error, len(indexes))
+	positions := make(chan int)
+	// Positions are fed while workers are started, since a full
+	// goroutine pool only frees a slot once a worker is done.
+	go func() {
+		for pos := range indexes {
+			positions <- pos
+		}
+		close(positions)
+	}()
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		err := s.goroutine(ctx, func() {
+			defer wg.Done()
+			for pos := range positions {
+				errs[pos] = s.compensateStep(ctx, indexes[pos])
+			}
+		})
+		if err == nil {
+			continue
+		}
+		wg.Done()
+		// Without any worker, no step can be compensated.
+		if w == 0 {
+			for pos := range positions {
+				errs[pos] = err
+			}
+		}
+		break
+	}
+	wg.Wait()
+	var compensationErrors []error
+	for _, err := range errs {
+		if err != nil {
+			compensationErrors = append(compensationErrors, err)
+		}
+	}
+	return compensationErrors
+}
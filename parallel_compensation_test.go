@@ -0,0 +1,99 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// concurrencyTracker tracks the maximum number
+// of compensations running at the same time.
+type concurrencyTracker struct {
+	mu      sync.Mutex
+	running int
+	max     int
+	order   []string
+}
+
+func (c *concurrencyTracker) compensate(name string, err error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		c.mu.Lock()
+		c.running++
+		if c.running > c.max {
+			c.max = c.running
+		}
+		c.mu.Unlock()
+		time.Sleep(20 * time.Millisecond)
+		c.mu.Lock()
+		c.running--
+		c.order = append(c.order, name)
+		c.mu.Unlock()
+		return err
+	}
+}
+
+func TestWithParallelCompensation(t *testing.T) {
+	testCases := []struct {
+		name           string
+		maxConcurrency int
+		expectedMax    int
+	}{
+		{name: "unbounded", maxConcurrency: 0, expectedMax: 4},
+		{name: "bounded", maxConcurrency: 2, expectedMax: 2},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := &concurrencyTracker{}
+			saga := New(WithParallelCompensation(tc.maxConcurrency))
+			for i := 1; i <= 4; i++ {
+				name := fmt.Sprintf("step%d", i)
+				var compErr error
+				if i%2 == 0 {
+					compErr = errors.New(name + " compensation error")
+				}
+				saga.AddStep(NewStep(name, noop, tracker.compensate(name, compErr)))
+			}
+			saga.AddStep(NewStep("step5",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				noop,
+			))
+			err := saga.Execute(context.Background())
+			require.EqualError(t, err, "compensating after failure in step step5: forward error: compensation failed with errors: [step4 compensation error step2 compensation error]")
+			require.Equal(t, tc.expectedMax, tracker.max)
+			require.Len(t, tracker.order, 4)
+		})
+	}
+}
+
+func TestWithParallelCompensationGrouped(t *testing.T) {
+	tracker := &concurrencyTracker{}
+	saga := New(WithParallelCompensationGrouped([][]int{{3, 2}, {0, 1}}))
+	for i := 0; i < 5; i++ {
+		name := fmt.Sprintf("step%d", i)
+		saga.AddStep(NewStep(name, noop, tracker.compensate(name, nil)))
+	}
+	saga.AddStep(NewStep("step5",
+		func(ctx context.Context) error {
+			return errors.New("forward error")
+		},
+		noop,
+	))
+	require.EqualError(t, saga.Execute(context.Background()), "executing step step5: forward error")
+	require.Equal(t, 2, tracker.max)
+	require.Len(t, tracker.order, 5)
+	require.ElementsMatch(t, []string{"step3", "step2"}, tracker.order[:2])
+	require.ElementsMatch(t, []string{"step0", "step1"}, tracker.order[2:4])
+	// ungrouped steps are compensated last.
+	require.Equal(t, "step4", tracker.order[4])
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"slices"
+)
+
+// PipelineStep is a step of a Pipeline, whose forward
+// action turns an input of type I into an output of type O.
+type PipelineStep[I, O any] interface {
+	// Name returns the name of the step.
+	Name() string
+
+	// Execute executes the main action of the step
+	// with the output of the previous step as input.
+	Execute(ctx context.Context, input I) (O, error)
+
+	// Compensate executes the compensation action of
+	// the step, with the output of its main action.
+	Compensate(ctx context.Context, output O) error
+}
+
+// pipelineStep is the concrete implementation of PipelineStep.
+type pipelineStep[I, O any] struct {
+	name       string
+	forward    func(ctx context.Context, input I) (O, error)
+	compensate func(ctx context.Context, output O) error
+}
+
+// NewPipelineStep creates a new PipelineStep instance with the
+// provided name, forward action, and compensation action.
+func NewPipelineStep[I, O any](name string, forward func(ctx context.Context, input I) (O, error), compensate func(ctx context.Context, output O) error) PipelineStep[I, O] {
+	return &pipelineStep[I, O]{
+		name:       name,
+		forward:    forward,
+		compensate: compensate,
+	}
+}
+
+func (s *pipelineStep[I, O]) Name() string {
+	return s.name
+}
+
+func (s *pipelineStep[I, O]) Execute(ctx context.Context, input I) (O, error) {
+	return s.forward(ctx, input)
+}
+
+func (s *pipelineStep[I, O]) Compensate(ctx context.Context, output O) error {
+	return s.compensate(ctx, output)
+}
+
+// pipelineStage is a PipelineStep with its types erased.
+type pipelineStage struct {
+	name       string
+	forward    func(ctx context.Context, input any) (any, error)
+	compensate func(ctx context.Context, output any) error
+}
+
+// newPipelineStage erases the types of the given PipelineStep.
+func newPipelineStage[I, O any](step PipelineStep[I, O]) pipelineStage {
+	return pipelineStage{
+		name: step.Name(),
+		forward: func(ctx context.Context, input any) (any, error) {
+			return step.Execute(ctx, input.(I))
+		},
+		compensate: func(ctx context.Context, output any) error {
+			return step.Compensate(ctx, output.(O))
+		},
+	}
+}
+
+// Pipeline is a strongly-typed Saga where the output of each step
+// is the input of the next one: the first step takes an input of
+// type I, and the last step returns an output of type O.
+// It is the type-safe alternative to sharing state through the context.
+type Pipeline[I, O any] struct {
+	stages []pipelineStage
+	opts   []Option
+}
+
+// NewPipeline creates a new Pipeline starting with the given step.
+// The options configure the Saga executing the pipeline.
+func NewPipeline[T any](firstStep PipelineStep[struct{}, T], opts ...Option) *Pipeline[struct{}, T] {
+	return &Pipeline[struct{}, T]{
+		stages: []pipelineStage{newPipelineStage(firstStep)},
+		opts:   opts,
+	}
+}
+
+// Then returns a new Pipeline running the steps of the given pipeline
+// followed by next, which takes the output of the last one as input.
+// It is a function rather than a method since Go methods cannot have
+// type parameters.
+func Then[I, T, U any](p *Pipeline[I, T], next PipelineStep[T, U]) *Pipeline[I, U] {
+	return &Pipeline[I, U]{
+		stages: append(slices.Clone(p.stages), newPipelineStage(next)),
+		opts:   p.opts,
+	}
+}
+
+// Execute runs the steps of the pipeline in sequence, passing the output
+// of each step to the next one. If a step fails, the steps that completed
+// are compensated with their output, as in Saga.Execute.
+func (p *Pipeline[I, O]) Execute(ctx context.Context) error {
+	s := New(p.opts...)
+	var input any = struct{}{}
+	outputs := make([]any, len(p.stages))
+	completed := make([]bool, len(p.stages))
+	for i, stage := range p.stages {
+		s.AddStep(NewStep(stage.name,
+			func(ctx context.Context) error {
+				output, err := stage.forward(ctx, input)
+				if err != nil {
+					return err
+				}
+				outputs[i], completed[i] = output, true
+				input = output
+				return nil
+			},
+			func(ctx context.Context) error {
+				// A failed step has no output to compensate.
+				if !completed[i] {
+					return nil
+				}
+				return stage.compensate(ctx, outputs[i])
+			},
+		))
+	}
+	return s.Execute(ctx)
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeline(t *testing.T) {
+	testCases := []struct {
+		name                string
+		shipErr             error
+		expectedShipped     string
+		expectedCompensated []string
+		expectedError       string
+	}{
+		{
+			name:            "happy path",
+			expectedShipped: "order 42 charged 84",
+		},
+		{
+			name:                "compensation with outputs",
+			shipErr:             errors.New("ship error"),
+			expectedCompensated: []string{"refund order 42 charged 84", "cancel 42"},
+			expectedError:       "executing step ship: ship error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var shipped string
+			var compensated []string
+			create := NewPipelineStep("create",
+				func(ctx context.Context, _ struct{}) (int, error) {
+					return 42, nil
+				},
+				func(ctx context.Context, orderID int) error {
+					compensated = append(compensated, "cancel "+strconv.Itoa(orderID))
+					return nil
+				},
+			)
+			charge := NewPipelineStep("charge",
+				func(ctx context.Context, orderID int) (string, error) {
+					return "order " + strconv.Itoa(orderID) + " charged " + strconv.Itoa(orderID*2), nil
+				},
+				func(ctx context.Context, receipt string) error {
+					compensated = append(compensated, "refund "+receipt)
+					return nil
+				},
+			)
+			ship := NewPipelineStep("ship",
+				func(ctx context.Context, receipt string) (bool, error) {
+					if tc.shipErr != nil {
+						return false, tc.shipErr
+					}
+					shipped = receipt
+					return true, nil
+				},
+				func(ctx context.Context, shipped bool) error {
+					compensated = append(compensated, "unship")
+					return nil
+				},
+			)
+			p := Then(Then(NewPipeline(create), charge), ship)
+			err := p.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, tc.expectedShipped, shipped)
+			require.Equal(t, tc.expectedCompensated, compensated)
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ErrPoolClosed is returned for sagas submitted to a SagaPool
+// that has been shut down.
+var ErrPoolClosed = errors.New("saga pool is closed")
+
+// PoolOption defines a function type that applies a
+// configuration option to a SagaPool instance.
+type PoolOption func(*SagaPool)
+
+// SubmitOption defines a function type that applies a
+// configuration option to a Saga submitted to a SagaPool.
+type SubmitOption func(*poolJob)
+
+// WithPoolMetrics option registers Prometheus metrics for the pool's
+// queue depth and throughput in the given registerer.
+// Registration errors cause NewSagaPool to panic.
+func WithPoolMetrics(reg prometheus.Registerer) PoolOption {
+	return func(p *SagaPool) {
+		p.metrics = newPoolMetrics(reg)
+	}
+}
+
+// poolJob is a saga execution request waiting in the pool's queue.
+type poolJob struct {
+	ctx       context.Context
+	configure func(Saga)
+	result    chan error
+	priority  Priority
+	seq       uint64
+}
+
+// SagaPool executes sagas built from the same template
+// concurrently, using a fixed number of worker goroutines.
+// It is safe for concurrent use.
+type SagaPool struct {
+	template        func() Saga
+	queue           jobQueue
+	seq             uint64
+	defaultPriority Priority
+	closed          bool
+	metrics         *poolMetrics
+	mu              sync.Mutex
+	cond            *sync.Cond
+	wg              sync.WaitGroup
+}
+
+// NewSagaPool creates a new SagaPool that runs the given number of
+// workers. Each job is executed on a fresh Saga returned by template.
+func NewSagaPool(template func() Saga, workers int, opts ...PoolOption) *SagaPool {
+	if workers < 1 {
+		workers = 1
+	}
+	p := &SagaPool{
+		template:        template,
+		defaultPriority: PriorityNormal,
+	}
+	p.cond = sync.NewCond(&p.mu)
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// Submit enqueues the execution of a new Saga. When a worker picks it
+// up, it builds the Saga from the template, calls configure to inject
+// item-specific data (such as steps bound to an order ID), and executes
+// it with the given context. The returned channel receives the result
+// of the execution and is then closed.
+//
+// Queued sagas are executed by decreasing priority, as set by the
+// WithPriority option (see WithDefaultPriority), and in submission
+// order for the same priority.
+func (p *SagaPool) Submit(ctx context.Context, configure func(Saga), opts ...SubmitOption) <-chan error {
+	result := make(chan error, 1)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		result <- ErrPoolClosed
+		close(result)
+		return result
+	}
+	p.seq++
+	job := poolJob{
+		ctx:       ctx,
+		configure: configure,
+		result:    result,
+		priority:  p.defaultPriority,
+		seq:       p.seq,
+	}
+	for _, opt := range opts {
+		opt(&job)
+	}
+	heap.Push(&p.queue, job)
+	p.metrics.setQueueDepth(len(p.queue))
+	p.cond.Signal()
+	return result
+}
+
+// QueueDepthByPriority returns the number of
+// sagas waiting to be executed, by priority.
+func (p *SagaPool) QueueDepthByPriority() map[Priority]int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	depths := make(map[Priority]int)
+	for _, job := range p.queue {
+		depths[job.priority]++
+	}
+	return depths
+}
+
+// Shutdown stops accepting new sagas and waits until the queued
+// and in-flight ones finish, or until the given context is done.
+func (p *SagaPool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	p.closed = true
+	p.cond.Broadcast()
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return errors.Wrap(ctx.Err(), "waiting for saga pool to drain")
+	}
+}
+
+// work dequeues and executes jobs until the pool is
+// closed and its queue is drained.
+func (p *SagaPool) work() {
+	defer p.wg.Done()
+	for {
+		job, ok := p.dequeue()
+		if !ok {
+			return
+		}
+		err := p.execute(job)
+		p.metrics.observeExecution(err)
+		job.result <- err
+		close(job.result)
+	}
+}
+
+// dequeue blocks until a job is available. It returns false
+// once the pool is closed and there are no more queued jobs.
+func (p *SagaPool) dequeue() (poolJob, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.queue) == 0 && !p.closed {
+		p.cond.Wait()
+	}
+	if len(p.queue) == 0 {
+		return poolJob{}, false
+	}
+	job := heap.Pop(&p.queue).(poolJob)
+	p.metrics.setQueueDepth(len(p.queue))
+	return job, true
+}
+
+// execute builds, configures and executes a Saga for the given job.
+func (p *SagaPool) execute(job poolJob) error {
+	if err := job.ctx.Err(); err != nil {
+		return err
+	}
+	s := p.template()
+	if job.configure != nil {
+		job.configure(s)
+	}
+	return s.Execute(job.ctx)
+}
+
+// poolMetrics holds the Prometheus metrics of a SagaPool.
+// A nil *poolMetrics records nothing.
+type poolMetrics struct {
+	queueDepth prometheus.Gauge
+	executions *prometheus.CounterVec
+	failures   *prometheus.CounterVec
+}
+
+// newPoolMetrics creates and registers the pool metrics.
+func newPoolMetrics(reg prometheus.Registerer) *poolMetrics {
+	m := &poolMetrics{
+		queueDepth: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "saga_pool_queue_depth",
+			Help: "Number of sagas waiting to be executed.",
+		}),
+		executions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "saga_pool_executions_total",
+			Help: "Number of sagas executed, by result.",
+		}, []string{"result"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "saga_pool_failures_total",
+			Help: "Number of sagas that failed, by error category.",
+		}, []string{"error_category"}),
+	}
+	reg.MustRegister(m.queueDepth, m.executions, m.failures)
+	return m
+}
+
+func (m *poolMetrics) setQueueDepth(depth int) {
+	if m == nil {
+		return
+	}
+	m.queueDepth.Set(float64(depth))
+}
+
+func (m *poolMetrics) observeExecution(err error) {
+	if m == nil {
+		return
+	}
+	result := "success"
+	if err != nil {
+		result = "failure"
+		m.failures.WithLabelValues(errorCategory(err).String()).Inc()
+	}
+	m.executions.WithLabelValues(result).Inc()
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSagaPool(t *testing.T) {
+	var mu sync.Mutex
+	processed := make(map[string]bool)
+	reg := prometheus.NewRegistry()
+	pool := NewSagaPool(func() Saga { return New() }, 3, WithPoolMetrics(reg))
+
+	var results []<-chan error
+	for i := 0; i < 10; i++ {
+		orderID := fmt.Sprintf("order-%d", i)
+		results = append(results, pool.Submit(context.Background(), func(s Saga) {
+			s.AddStep(NewStep("process",
+				func(ctx context.Context) error {
+					if orderID == "order-5" {
+						return errors.New("process error")
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					processed[orderID] = true
+					return nil
+				},
+				noop,
+			))
+		}))
+	}
+	require.Nil(t, pool.Shutdown(context.Background()))
+
+	failures := 0
+	for _, result := range results {
+		if err := <-result; err != nil {
+			require.Equal(t, "executing step process: process error", err.Error())
+			failures++
+		}
+	}
+	require.Equal(t, 1, failures)
+	require.Len(t, processed, 9)
+	require.Equal(t, float64(9), testutil.ToFloat64(pool.metrics.executions.WithLabelValues("success")))
+	require.Equal(t, float64(1), testutil.ToFloat64(pool.metrics.executions.WithLabelValues("failure")))
+	require.Equal(t, float64(1), testutil.ToFloat64(pool.metrics.failures.WithLabelValues("unknown")))
+	require.Equal(t, float64(0), testutil.ToFloat64(pool.metrics.queueDepth))
+
+	// Submissions after shutdown are rejected.
+	require.Equal(t, ErrPoolClosed, <-pool.Submit(context.Background(), nil))
+}
+
+func TestSagaPool_CancelledContext(t *testing.T) {
+	pool := NewSagaPool(func() Saga { return New() }, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := <-pool.Submit(ctx, nil)
+	require.Equal(t, context.Canceled, err)
+	require.Nil(t, pool.Shutdown(context.Background()))
+}
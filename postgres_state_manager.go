@@ -0,0 +1,176 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// PostgresStateManager is an implementation of the VersionedStateManager
+// interface that stores the state of each step in a PostgreSQL table,
+// along with a version number incremented on each update. It is also a
+// NamedStateManager, storing the states by key in a separate table, and
+// a StepMetadataManager, storing the metadata of each step as JSON.
+// The tables must have the following schema:
+//
+//	CREATE TABLE saga_step_states (
+//		saga_id    TEXT    NOT NULL,
+//		step_index INTEGER NOT NULL,
+//		success    BOOLEAN NOT NULL,
+//		version    INTEGER NOT NULL,
+//		PRIMARY KEY (saga_id, step_index)
+//	);
+//
+//	CREATE TABLE saga_named_step_states (
+//		saga_id  TEXT    NOT NULL,
+//		step_key TEXT    NOT NULL,
+//		success  BOOLEAN NOT NULL,
+//		PRIMARY KEY (saga_id, step_key)
+//	);
+//
+//	CREATE TABLE saga_step_metadata (
+//		saga_id    TEXT    NOT NULL,
+//		step_index INTEGER NOT NULL,
+//		metadata   JSONB   NOT NULL,
+//		PRIMARY KEY (saga_id, step_index)
+//	);
+type PostgresStateManager struct {
+	db     *sql.DB
+	sagaID string
+}
+
+// NewPostgresStateManager creates a new PostgresStateManager that
+// stores the state of the steps of the Saga identified by sagaID.
+func NewPostgresStateManager(db *sql.DB, sagaID string) *PostgresStateManager {
+	return &PostgresStateManager{
+		db:     db,
+		sagaID: sagaID,
+	}
+}
+
+const (
+	pgSelectStepState = `SELECT success, version FROM saga_step_states WHERE saga_id = $1 AND step_index = $2`
+	pgUpsertStepState = `INSERT INTO saga_step_states (saga_id, step_index, success, version) VALUES ($1, $2, $3, 1) ` +
+		`ON CONFLICT (saga_id, step_index) DO UPDATE SET success = EXCLUDED.success, version = saga_step_states.version + 1 ` +
+		`RETURNING version`
+	pgInsertStepState = `INSERT INTO saga_step_states (saga_id, step_index, success, version) VALUES ($1, $2, $3, 1) ` +
+		`ON CONFLICT (saga_id, step_index) DO NOTHING`
+	pgUpdateStepState = `UPDATE saga_step_states SET success = $3, version = version + 1 ` +
+		`WHERE saga_id = $1 AND step_index = $2 AND version = $4`
+	pgSelectNamedStepState = `SELECT success FROM saga_named_step_states WHERE saga_id = $1 AND step_key = $2`
+	pgUpsertNamedStepState = `INSERT INTO saga_named_step_states (saga_id, step_key, success) VALUES ($1, $2, $3) ` +
+		`ON CONFLICT (saga_id, step_key) DO UPDATE SET success = EXCLUDED.success`
+	pgSelectStepMetadata = `SELECT metadata FROM saga_step_metadata WHERE saga_id = $1 AND step_index = $2`
+	pgUpsertStepMetadata = `INSERT INTO saga_step_metadata (saga_id, step_index, metadata) VALUES ($1, $2, $3) ` +
+		`ON CONFLICT (saga_id, step_index) DO UPDATE SET metadata = EXCLUDED.metadata`
+)
+
+func (m *PostgresStateManager) SetStepState(stepIndex int, success bool) error {
+	var version int
+	if err := m.db.QueryRow(pgUpsertStepState, m.sagaID, stepIndex, success).Scan(&version); err != nil {
+		return errors.Wrapf(err, "setting state of step %d in postgres", stepIndex)
+	}
+	return nil
+}
+
+func (m *PostgresStateManager) StepState(stepIndex int) (bool, error) {
+	success, _, err := m.StepStateWithVersion(stepIndex)
+	return success, err
+}
+
+func (m *PostgresStateManager) SetStepStateVersioned(stepIndex int, success bool, expectedVersion int) (int, error) {
+	var (
+		res sql.Result
+		err error
+	)
+	if expectedVersion == 0 {
+		res, err = m.db.Exec(pgInsertStepState, m.sagaID, stepIndex, success)
+	} else {
+		res, err = m.db.Exec(pgUpdateStepState, m.sagaID, stepIndex, success, expectedVersion)
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "setting state of step %d in postgres", stepIndex)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrapf(err, "setting state of step %d in postgres", stepIndex)
+	}
+	if n == 0 {
+		_, actual, err := m.StepStateWithVersion(stepIndex)
+		if err != nil {
+			return 0, err
+		}
+		return 0, &ConcurrentModificationError{
+			StepIndex:       stepIndex,
+			ExpectedVersion: expectedVersion,
+			ActualVersion:   actual,
+		}
+	}
+	return expectedVersion + 1, nil
+}
+
+func (m *PostgresStateManager) StepStateWithVersion(stepIndex int) (bool, int, error) {
+	var (
+		success bool
+		version int
+	)
+	err := m.db.QueryRow(pgSelectStepState, m.sagaID, stepIndex).Scan(&success, &version)
+	if err == sql.ErrNoRows {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, errors.Wrapf(err, "getting state of step %d from postgres", stepIndex)
+	}
+	return success, version, nil
+}
+
+func (m *PostgresStateManager) SetNamedStepState(key string, success bool) error {
+	if _, err := m.db.Exec(pgUpsertNamedStepState, m.sagaID, key, success); err != nil {
+		return errors.Wrapf(err, "setting state of step %s in postgres", key)
+	}
+	return nil
+}
+
+func (m *PostgresStateManager) NamedStepState(key string) (bool, error) {
+	var success bool
+	err := m.db.QueryRow(pgSelectNamedStepState, m.sagaID, key).Scan(&success)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting state of step %s from postgres", key)
+	}
+	return success, nil
+}
+
+func (m *PostgresStateManager) SetStepMetadata(stepIndex int, metadata map[string]string) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return errors.Wrapf(err, "marshaling metadata of step %d", stepIndex)
+	}
+	if _, err := m.db.Exec(pgUpsertStepMetadata, m.sagaID, stepIndex, string(data)); err != nil {
+		return errors.Wrapf(err, "setting metadata of step %d in postgres", stepIndex)
+	}
+	return nil
+}
+
+func (m *PostgresStateManager) GetStepMetadata(stepIndex int) (map[string]string, error) {
+	var data []byte
+	err := m.db.QueryRow(pgSelectStepMetadata, m.sagaID, stepIndex).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting metadata of step %d from postgres", stepIndex)
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, errors.Wrapf(err, "unmarshaling metadata of step %d", stepIndex)
+	}
+	return metadata, nil
+}
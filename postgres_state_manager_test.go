@@ -0,0 +1,312 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// pgRow is a row of the saga_step_states table of a pgDriver.
+type pgRow struct {
+	success bool
+	version int
+}
+
+// pgDriver is a database/sql driver emulating the
+// queries issued by PostgresStateManager in memory.
+type pgDriver struct {
+	mu       sync.Mutex
+	rows     map[int]*pgRow
+	named    map[string]bool
+	metadata map[int]string
+	// beforeUpdate is called before each versioned update,
+	// to simulate concurrent modifications.
+	beforeUpdate func(rows map[int]*pgRow)
+}
+
+func (d *pgDriver) Open(name string) (driver.Conn, error) {
+	return &pgConn{driver: d}, nil
+}
+
+type pgConn struct {
+	driver *pgDriver
+}
+
+func (c *pgConn) Prepare(query string) (driver.Stmt, error) {
+	return &pgStmt{driver: c.driver, query: query}, nil
+}
+
+func (c *pgConn) Close() error {
+	return nil
+}
+
+func (c *pgConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+type pgStmt struct {
+	driver *pgDriver
+	query  string
+}
+
+func (s *pgStmt) Close() error {
+	return nil
+}
+
+func (s *pgStmt) NumInput() int {
+	return -1
+}
+
+func (s *pgStmt) Exec(args []driver.Value) (driver.Result, error) {
+	d := s.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch s.query {
+	case pgUpsertNamedStepState:
+		d.named[args[1].(string)] = args[2].(bool)
+		return driver.RowsAffected(1), nil
+	case pgUpsertStepMetadata:
+		d.metadata[int(args[1].(int64))] = args[2].(string)
+		return driver.RowsAffected(1), nil
+	}
+	index := int(args[1].(int64))
+	success := args[2].(bool)
+	row, exists := d.rows[index]
+	switch s.query {
+	case pgInsertStepState:
+		if exists {
+			return driver.RowsAffected(0), nil
+		}
+		d.rows[index] = &pgRow{success: success, version: 1}
+	case pgUpdateStepState:
+		if d.beforeUpdate != nil {
+			d.beforeUpdate(d.rows)
+		}
+		if !exists || row.version != int(args[3].(int64)) {
+			return driver.RowsAffected(0), nil
+		}
+		row.success = success
+		row.version++
+	default:
+		return nil, errors.New("unexpected exec: " + s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *pgStmt) Query(args []driver.Value) (driver.Rows, error) {
+	d := s.driver
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	switch s.query {
+	case pgSelectNamedStepState:
+		success, exists := d.named[args[1].(string)]
+		if !exists {
+			return &pgRows{columns: []string{"success"}}, nil
+		}
+		return &pgRows{
+			columns: []string{"success"},
+			values:  [][]driver.Value{{success}},
+		}, nil
+	case pgSelectStepMetadata:
+		metadata, exists := d.metadata[int(args[1].(int64))]
+		if !exists {
+			return &pgRows{columns: []string{"metadata"}}, nil
+		}
+		return &pgRows{
+			columns: []string{"metadata"},
+			values:  [][]driver.Value{{[]byte(metadata)}},
+		}, nil
+	}
+	index := int(args[1].(int64))
+	row, exists := d.rows[index]
+	switch s.query {
+	case pgSelectStepState:
+		if !exists {
+			return &pgRows{columns: []string{"success", "version"}}, nil
+		}
+		return &pgRows{
+			columns: []string{"success", "version"},
+			values:  [][]driver.Value{{row.success, int64(row.version)}},
+		}, nil
+	case pgUpsertStepState:
+		if !exists {
+			row = &pgRow{}
+			d.rows[index] = row
+		}
+		row.success = args[2].(bool)
+		row.version++
+		return &pgRows{
+			columns: []string{"version"},
+			values:  [][]driver.Value{{int64(row.version)}},
+		}, nil
+	}
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+type pgRows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *pgRows) Columns() []string {
+	return r.columns
+}
+
+func (r *pgRows) Close() error {
+	return nil
+}
+
+func (r *pgRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
+
+// openPgDriver registers the given driver under a unique
+// name and opens a database using it.
+func openPgDriver(t *testing.T, name string, d *pgDriver) *sql.DB {
+	d.rows = make(map[int]*pgRow)
+	d.named = make(map[string]bool)
+	d.metadata = make(map[int]string)
+	sql.Register(name, d)
+	db, err := sql.Open(name, "")
+	require.Nil(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPostgresStateManager(t *testing.T) {
+	db := openPgDriver(t, "pgdriver", &pgDriver{})
+	sm := NewPostgresStateManager(db, "order-42")
+
+	success, version, err := sm.StepStateWithVersion(0)
+	require.Nil(t, err)
+	require.False(t, success)
+	require.Equal(t, 0, version)
+
+	version, err = sm.SetStepStateVersioned(0, false, 0)
+	require.Nil(t, err)
+	require.Equal(t, 1, version)
+
+	version, err = sm.SetStepStateVersioned(0, true, 1)
+	require.Nil(t, err)
+	require.Equal(t, 2, version)
+
+	_, err = sm.SetStepStateVersioned(0, false, 1)
+	require.Equal(t, &ConcurrentModificationError{StepIndex: 0, ExpectedVersion: 1, ActualVersion: 2}, err)
+
+	_, err = sm.SetStepStateVersioned(0, false, 0)
+	require.Equal(t, &ConcurrentModificationError{StepIndex: 0, ExpectedVersion: 0, ActualVersion: 2}, err)
+
+	require.Nil(t, sm.SetStepState(0, false))
+	success, version, err = sm.StepStateWithVersion(0)
+	require.Nil(t, err)
+	require.False(t, success)
+	require.Equal(t, 3, version)
+}
+
+func TestPostgresStateManager_NamedStepState(t *testing.T) {
+	db := openPgDriver(t, "pgdrivernamed", &pgDriver{})
+	sm := NewPostgresStateManager(db, "order-42")
+
+	completed, err := sm.NamedStepState("reserve")
+	require.Nil(t, err)
+	require.False(t, completed)
+
+	require.Nil(t, sm.SetNamedStepState("reserve", true))
+	completed, err = sm.NamedStepState("reserve")
+	require.Nil(t, err)
+	require.True(t, completed)
+
+	// States stored by key and by index are kept apart.
+	completed, err = sm.StepState(0)
+	require.Nil(t, err)
+	require.False(t, completed)
+}
+
+func TestPostgresStateManager_StepMetadata(t *testing.T) {
+	d := &pgDriver{}
+	db := openPgDriver(t, "pgdrivermetadata", d)
+	sm := NewPostgresStateManager(db, "order-42")
+	s := New(WithStateManager(sm))
+	s.AddStep(NewStepWithOptions("step1", noop, noop,
+		WithStepMetadata(map[string]string{"orderID": "42"}),
+	))
+	require.Nil(t, s.Execute(context.Background()))
+
+	metadata, err := sm.GetStepMetadata(0)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"orderID": "42"}, metadata)
+	require.JSONEq(t, `{"orderID":"42"}`, d.metadata[0])
+
+	// Steps without metadata have none.
+	metadata, err = sm.GetStepMetadata(1)
+	require.Nil(t, err)
+	require.Nil(t, metadata)
+}
+
+func TestSaga_VersionedStateManager(t *testing.T) {
+	testCases := []struct {
+		name          string
+		conflicts     int
+		expectedError string
+	}{
+		{
+			name:      "no conflict",
+			conflicts: 0,
+		},
+		{
+			name:      "conflicts resolved by retrying",
+			conflicts: 2,
+		},
+		{
+			name:          "too many conflicts",
+			conflicts:     10,
+			expectedError: "state of step 0 was modified concurrently: expected version 4, got 5",
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conflicts := tc.conflicts
+			d := &pgDriver{
+				beforeUpdate: func(rows map[int]*pgRow) {
+					if conflicts > 0 {
+						conflicts--
+						rows[0].version++
+					}
+				},
+			}
+			db := openPgDriver(t, "pgdriversaga"+string(rune('a'+i)), d)
+			sm := NewPostgresStateManager(db, "order-42")
+			// Another runner already recorded a failed attempt of step1.
+			_, err := sm.SetStepStateVersioned(0, false, 0)
+			require.Nil(t, err)
+
+			s := New(WithStateManager(sm))
+			s.AddStep(NewStep("step1", noop, noop))
+			err = s.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tc.expectedError)
+				return
+			}
+			require.Nil(t, err)
+			success, _, err := sm.StepStateWithVersion(0)
+			require.Nil(t, err)
+			require.True(t, success)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+// Priority is the priority of a Saga submitted to a SagaPool:
+// sagas with a higher priority are executed first.
+type Priority int
+
+const (
+	// PriorityLow is for background sagas.
+	PriorityLow Priority = 0
+	// PriorityNormal is the default priority.
+	PriorityNormal Priority = 50
+	// PriorityHigh is for sagas to execute first.
+	PriorityHigh Priority = 100
+)
+
+// WithPriority option sets the priority of the Saga submitted to
+// SagaPool.Submit, which executes the queued sagas by decreasing priority.
+func WithPriority(p Priority) SubmitOption {
+	return func(j *poolJob) {
+		j.priority = p
+	}
+}
+
+// WithDefaultPriority option sets the priority of the sagas submitted
+// to the SagaPool without WithPriority. It defaults to PriorityNormal.
+func WithDefaultPriority(p Priority) PoolOption {
+	return func(sp *SagaPool) {
+		sp.defaultPriority = p
+	}
+}
+
+// jobQueue is a heap of jobs ordered by decreasing priority and,
+// for the same priority, by submission order.
+// It implements heap.Interface.
+type jobQueue []poolJob
+
+func (q jobQueue) Len() int {
+	return len(q)
+}
+
+func (q jobQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q jobQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *jobQueue) Push(x any) {
+	*q = append(*q, x.(poolJob))
+}
+
+func (q *jobQueue) Pop() any {
+	old := *q
+	n := len(old)
+	job := old[n-1]
+	old[n-1] = poolJob{}
+	*q = old[:n-1]
+	return job
+}
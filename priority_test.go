@@ -0,0 +1,83 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSagaPool_Priority(t *testing.T) {
+	testCases := []struct {
+		name           string
+		poolOptions    []PoolOption
+		expectedDepths map[Priority]int
+		expectedOrder  []string
+	}{
+		{
+			name: "default priority",
+			expectedDepths: map[Priority]int{
+				PriorityLow:    1,
+				PriorityNormal: 2,
+				PriorityHigh:   2,
+			},
+			expectedOrder: []string{"high1", "high2", "normal1", "default", "low1"},
+		},
+		{
+			name:        "custom default priority",
+			poolOptions: []PoolOption{WithDefaultPriority(PriorityLow)},
+			expectedDepths: map[Priority]int{
+				PriorityLow:    2,
+				PriorityNormal: 1,
+				PriorityHigh:   2,
+			},
+			expectedOrder: []string{"high1", "high2", "normal1", "low1", "default"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pool := NewSagaPool(func() Saga { return New() }, 1, tc.poolOptions...)
+
+			// Keep the only worker busy while the sagas are submitted.
+			started, release := make(chan struct{}), make(chan struct{})
+			blocker := pool.Submit(context.Background(), func(s Saga) {
+				s.AddStep(NewStep("block", func(ctx context.Context) error {
+					close(started)
+					<-release
+					return nil
+				}, noop))
+			})
+			<-started
+
+			var mu sync.Mutex
+			var order []string
+			record := func(name string) func(Saga) {
+				return func(s Saga) {
+					s.AddStep(NewStep(name, func(ctx context.Context) error {
+						mu.Lock()
+						defer mu.Unlock()
+						order = append(order, name)
+						return nil
+					}, noop))
+				}
+			}
+			pool.Submit(context.Background(), record("low1"), WithPriority(PriorityLow))
+			pool.Submit(context.Background(), record("high1"), WithPriority(PriorityHigh))
+			pool.Submit(context.Background(), record("normal1"), WithPriority(PriorityNormal))
+			pool.Submit(context.Background(), record("default"))
+			pool.Submit(context.Background(), record("high2"), WithPriority(PriorityHigh))
+			require.Equal(t, tc.expectedDepths, pool.QueueDepthByPriority())
+
+			close(release)
+			require.Nil(t, <-blocker)
+			require.Nil(t, pool.Shutdown(context.Background()))
+			require.Equal(t, tc.expectedOrder, order)
+			require.Empty(t, pool.QueueDepthByPriority())
+		})
+	}
+}
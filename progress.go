@@ -0,0 +1,55 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"time"
+)
+
+// Step progress statuses reported in StepProgress.
+const (
+	StepStatusCompleted = "completed"
+	StepStatusFailed    = "failed"
+	StepStatusSkipped   = "skipped"
+)
+
+// StepProgress describes the progress of a Saga
+// after one of its steps was executed.
+type StepProgress struct {
+	SagaID          string    `json:"saga_id,omitempty"`
+	StepName        string    `json:"step_name"`
+	StepIndex       int       `json:"step_index"`
+	TotalSteps      int       `json:"total_steps"`
+	Status          string    `json:"status"`
+	ProgressPercent float64   `json:"progress_percent"`
+	Error           string    `json:"error,omitempty"`
+	CorrelationID   string    `json:"correlation_id,omitempty"`
+	Timestamp       time.Time `json:"timestamp"`
+}
+
+// emitProgress reports the progress of the Saga after the
+// current step was executed to all the progress hooks.
+func (s *saga) emitProgress(ctx context.Context, step Step, status string, stepErr error) {
+	if len(s.progressHooks) == 0 {
+		return
+	}
+	p := StepProgress{
+		SagaID:          s.id,
+		StepName:        s.stepName(step),
+		StepIndex:       s.currentStep,
+		TotalSteps:      len(s.steps),
+		Status:          status,
+		ProgressPercent: s.ProgressPercent(),
+		CorrelationID:   s.correlationID,
+		Timestamp:       time.Now(),
+	}
+	if stepErr != nil {
+		p.Error = s.sanitizeError(step, stepErr).Error()
+	}
+	for _, hook := range s.progressHooks {
+		hook(ctx, p)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// sagaIDPlaceholder is replaced by the saga ID in
+// the name of Redis progress broadcast channels.
+const sagaIDPlaceholder = "{sagaID}"
+
+// WithRedisProgressBroadcast option makes the Saga publish a StepProgress
+// JSON message to the given Redis channel after each executed step.
+// Occurrences of "{sagaID}" in the channel name are replaced by the saga ID,
+// allowing subscribers to filter by saga. Publishing errors do not fail
+// the Saga; they are logged if a logger is configured.
+func WithRedisProgressBroadcast(client *redis.Client, channel string) Option {
+	return func(s *saga) {
+		s.progressHooks = append(s.progressHooks, func(ctx context.Context, p StepProgress) {
+			ch := strings.ReplaceAll(channel, sagaIDPlaceholder, p.SagaID)
+			if err := publishProgress(ctx, client, ch, p); err != nil && s.logger != nil {
+				s.logger.WarnContext(ctx, "publishing step progress", "channel", ch, "error", err)
+			}
+		})
+	}
+}
+
+// publishProgress publishes the given progress as JSON to a Redis channel.
+func publishProgress(ctx context.Context, client *redis.Client, channel string, p StepProgress) error {
+	payload, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	return client.Publish(ctx, channel, payload).Err()
+}
+
+// SagaProgressSubscriber subscribes to the given Redis channel and returns
+// a channel receiving the StepProgress messages published to it, along with
+// a function that unsubscribes and closes the progress channel.
+// Messages that cannot be decoded are ignored.
+func SagaProgressSubscriber(client *redis.Client, channel string) (<-chan StepProgress, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pubsub := client.Subscribe(ctx, channel)
+	progress := make(chan StepProgress)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer close(progress)
+		for msg := range pubsub.Channel() {
+			var p StepProgress
+			if err := json.Unmarshal([]byte(msg.Payload), &p); err != nil {
+				continue
+			}
+			select {
+			case progress <- p:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	unsubscribe := func() {
+		cancel()
+		pubsub.Close()
+		<-done
+	}
+	return progress, unsubscribe
+}
@@ -0,0 +1,57 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisProgressBroadcast(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	progress, unsubscribe := SagaProgressSubscriber(client, "saga:order-1:progress")
+	defer unsubscribe()
+	// waits for the subscription to be active before publishing.
+	require.Eventually(t, func() bool {
+		return len(mr.PubSubChannels("")) == 1
+	}, time.Second, time.Millisecond)
+
+	saga := New(
+		WithSagaID("order-1"),
+		WithRedisProgressBroadcast(client, "saga:{sagaID}:progress"),
+	)
+	saga.AddStep(NewStep("step1", noop, noop))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("step2 error")
+		},
+		noop,
+	))
+	require.NotNil(t, saga.Execute(context.Background()))
+
+	expected := []StepProgress{
+		{SagaID: "order-1", StepName: "step1", StepIndex: 0, TotalSteps: 2, Status: StepStatusCompleted, ProgressPercent: 50},
+		{SagaID: "order-1", StepName: "step2", StepIndex: 1, TotalSteps: 2, Status: StepStatusFailed, ProgressPercent: 50, Error: "step2 error"},
+	}
+	for _, e := range expected {
+		select {
+		case p := <-progress:
+			require.False(t, p.Timestamp.IsZero())
+			p.Timestamp = time.Time{}
+			require.Equal(t, e, p)
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for step progress")
+		}
+	}
+}
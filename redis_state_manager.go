@@ -0,0 +1,167 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// consistencyRetries is the number of times the state of a step
+	// is read again when a write is not visible yet.
+	consistencyRetries = 3
+
+	// consistencyBackoff is the delay between those reads.
+	consistencyBackoff = 10 * time.Millisecond
+
+	// namedFieldPrefix prefixes the hash fields of the states stored
+	// by key, to keep them apart from the ones stored by index.
+	namedFieldPrefix = "n:"
+
+	// metadataFieldPrefix prefixes the hash fields
+	// of the metadata of the steps.
+	metadataFieldPrefix = "m:"
+)
+
+// RedisStateManagerOption defines a function type that applies
+// a configuration option to a RedisStateManager instance.
+type RedisStateManagerOption func(*RedisStateManager)
+
+// WithReadYourWritesConsistency option makes SetStepState and
+// SetNamedStepState verify that the written state is visible, by reading it back immediately. If it
+// is not (e.g. the read is served by a stale replica), the read is
+// retried up to 3 times, 10ms apart, before returning a
+// *ConsistencyError.
+func WithReadYourWritesConsistency() RedisStateManagerOption {
+	return func(m *RedisStateManager) {
+		m.readYourWrites = true
+	}
+}
+
+// RedisStateManager is an implementation of the StateManager
+// interface that stores the state of each step in a Redis hash.
+// States stored by key (see NamedStateManager) are stored in
+// fields prefixed with "n:", apart from the ones stored by index,
+// and the metadata of each step (see StepMetadataManager) is stored
+// as JSON in fields prefixed with "m:".
+type RedisStateManager struct {
+	client         redis.UniversalClient
+	key            string
+	readYourWrites bool
+}
+
+// NewRedisStateManager creates a new RedisStateManager that stores
+// the state of each step as a field of the Redis hash at key.
+func NewRedisStateManager(client redis.UniversalClient, key string, opts ...RedisStateManagerOption) *RedisStateManager {
+	m := &RedisStateManager{
+		client: client,
+		key:    key,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+func (m *RedisStateManager) SetStepState(stepIndex int, success bool) error {
+	ctx := context.Background()
+	if err := m.client.HSet(ctx, m.key, strconv.Itoa(stepIndex), success).Err(); err != nil {
+		return errors.Wrapf(err, "setting state of step %d in redis", stepIndex)
+	}
+	if !m.readYourWrites {
+		return nil
+	}
+	return m.verifyWrite(ctx, func() (bool, error) {
+		return m.StepState(stepIndex)
+	}, success, &ConsistencyError{StepIndex: stepIndex})
+}
+
+func (m *RedisStateManager) StepState(stepIndex int) (bool, error) {
+	state, err := m.client.HGet(context.Background(), m.key, strconv.Itoa(stepIndex)).Bool()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting state of step %d from redis", stepIndex)
+	}
+	return state, nil
+}
+
+func (m *RedisStateManager) SetNamedStepState(key string, success bool) error {
+	ctx := context.Background()
+	if err := m.client.HSet(ctx, m.key, namedFieldPrefix+key, success).Err(); err != nil {
+		return errors.Wrapf(err, "setting state of step %s in redis", key)
+	}
+	if !m.readYourWrites {
+		return nil
+	}
+	return m.verifyWrite(ctx, func() (bool, error) {
+		return m.NamedStepState(key)
+	}, success, &ConsistencyError{StepKey: key})
+}
+
+func (m *RedisStateManager) NamedStepState(key string) (bool, error) {
+	state, err := m.client.HGet(context.Background(), m.key, namedFieldPrefix+key).Bool()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrapf(err, "getting state of step %s from redis", key)
+	}
+	return state, nil
+}
+
+func (m *RedisStateManager) SetStepMetadata(stepIndex int, metadata map[string]string) error {
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return errors.Wrapf(err, "marshaling metadata of step %d", stepIndex)
+	}
+	if err := m.client.HSet(context.Background(), m.key, metadataFieldPrefix+strconv.Itoa(stepIndex), data).Err(); err != nil {
+		return errors.Wrapf(err, "setting metadata of step %d in redis", stepIndex)
+	}
+	return nil
+}
+
+func (m *RedisStateManager) GetStepMetadata(stepIndex int) (map[string]string, error) {
+	data, err := m.client.HGet(context.Background(), m.key, metadataFieldPrefix+strconv.Itoa(stepIndex)).Bytes()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "getting metadata of step %d from redis", stepIndex)
+	}
+	var metadata map[string]string
+	if err := json.Unmarshal(data, &metadata); err != nil {
+		return nil, errors.Wrapf(err, "unmarshaling metadata of step %d", stepIndex)
+	}
+	return metadata, nil
+}
+
+// verifyWrite reads the state of a step back with read until it
+// reflects the given written state, or the retries are exhausted,
+// in which case it returns notVisible.
+func (m *RedisStateManager) verifyWrite(ctx context.Context, read func() (bool, error), written bool, notVisible *ConsistencyError) error {
+	for retry := 0; ; retry++ {
+		state, err := read()
+		if err != nil {
+			return err
+		}
+		if state == written {
+			return nil
+		}
+		if retry == consistencyRetries {
+			return notVisible
+		}
+		if err := sleepContext(ctx, consistencyBackoff); err != nil {
+			return err
+		}
+	}
+}
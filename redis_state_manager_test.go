@@ -0,0 +1,165 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+// staleReadHook simulates a stale replica by answering
+// the given number of HGET commands with no value.
+type staleReadHook struct {
+	staleReads int
+}
+
+func (h *staleReadHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (h *staleReadHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		if cmd.Name() == "hget" && h.staleReads > 0 {
+			h.staleReads--
+			cmd.SetErr(redis.Nil)
+			return redis.Nil
+		}
+		return next(ctx, cmd)
+	}
+}
+
+func (h *staleReadHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return next
+}
+
+func TestRedisStateManager(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	sm := NewRedisStateManager(client, "saga:order-1")
+	saga := New(WithStateManager(sm))
+	saga.AddStep(NewStep("step1", noop, noop))
+	saga.AddStep(NewStep("step2", noop, noop))
+	require.Nil(t, saga.Execute(context.Background()))
+
+	for i := 0; i < 2; i++ {
+		completed, err := sm.StepState(i)
+		require.Nil(t, err)
+		require.True(t, completed)
+	}
+	completed, err := sm.StepState(2)
+	require.Nil(t, err)
+	require.False(t, completed)
+}
+
+func TestRedisStateManager_NamedStepState(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	sm := NewRedisStateManager(client, "saga:order-1")
+	require.Nil(t, sm.SetNamedStepState("0", true))
+	completed, err := sm.NamedStepState("0")
+	require.Nil(t, err)
+	require.True(t, completed)
+
+	// States stored by key and by index are kept apart.
+	completed, err = sm.StepState(0)
+	require.Nil(t, err)
+	require.False(t, completed)
+	require.Nil(t, sm.SetStepState(1, true))
+	completed, err = sm.NamedStepState("1")
+	require.Nil(t, err)
+	require.False(t, completed)
+}
+
+func TestRedisStateManager_StepMetadata(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	sm := NewRedisStateManager(client, "saga:order-1")
+	saga := New(WithStateManager(sm))
+	saga.AddStep(NewStepWithOptions("step1", noop, noop,
+		WithStepMetadata(map[string]string{"orderID": "42"}),
+	))
+	require.Nil(t, saga.Execute(context.Background()))
+
+	metadata, err := sm.GetStepMetadata(0)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"orderID": "42"}, metadata)
+	require.JSONEq(t, `{"orderID":"42"}`, mr.HGet("saga:order-1", "m:0"))
+
+	// Steps without metadata have none.
+	metadata, err = sm.GetStepMetadata(1)
+	require.Nil(t, err)
+	require.Nil(t, metadata)
+
+	// Metadata is kept apart from the state of the step.
+	completed, err := sm.StepState(0)
+	require.Nil(t, err)
+	require.True(t, completed)
+}
+
+func TestWithReadYourWritesConsistency(t *testing.T) {
+	testCases := []struct {
+		name          string
+		named         bool
+		staleReads    int
+		expectedError string
+	}{
+		{
+			name: "write visible",
+		},
+		{
+			name:       "write visible after retries",
+			staleReads: 3,
+		},
+		{
+			name:          "write not visible",
+			staleReads:    4,
+			expectedError: "state of step 0 not visible after write",
+		},
+		{
+			name:       "named write visible after retries",
+			named:      true,
+			staleReads: 3,
+		},
+		{
+			name:          "named write not visible",
+			named:         true,
+			staleReads:    4,
+			expectedError: `state of step "charge" not visible after write`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+			defer client.Close()
+			client.AddHook(&staleReadHook{staleReads: tc.staleReads})
+
+			sm := NewRedisStateManager(client, "saga:order-1", WithReadYourWritesConsistency())
+			var err error
+			if tc.named {
+				err = sm.SetNamedStepState("charge", true)
+			} else {
+				err = sm.SetStepState(0, true)
+			}
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				var consistencyErr *ConsistencyError
+				require.ErrorAs(t, err, &consistencyErr)
+				return
+			}
+			require.Nil(t, err)
+		})
+	}
+}
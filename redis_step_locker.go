@@ -0,0 +1,81 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrStepLocked is returned by a RedisStepLocker
+// when the lock of a step is held by someone else.
+var ErrStepLocked = errors.New("step is locked")
+
+// releaseLockScript deletes the lock key only if it
+// still holds the token of the caller.
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisStepLocker is a StepLocker backed by Redis. Each lock is
+// a key set with SET NX EX, which expires after the lock TTL
+// if it is not released.
+type RedisStepLocker struct {
+	client    redis.UniversalClient
+	keyPrefix string
+	ttl       time.Duration
+}
+
+// NewRedisStepLocker creates a new RedisStepLocker storing the lock
+// of each step at keyPrefix followed by the step name. Locks expire
+// after ttl, so that a crashed holder does not keep them forever.
+func NewRedisStepLocker(client redis.UniversalClient, keyPrefix string, ttl time.Duration) *RedisStepLocker {
+	return &RedisStepLocker{
+		client:    client,
+		keyPrefix: keyPrefix,
+		ttl:       ttl,
+	}
+}
+
+// Lock acquires the lock of the given step, failing
+// with ErrStepLocked if it is already held.
+func (l *RedisStepLocker) Lock(ctx context.Context, stepName string) (func(ctx context.Context) error, error) {
+	token, err := lockToken()
+	if err != nil {
+		return nil, errors.Wrap(err, "generating lock token")
+	}
+	key := l.keyPrefix + stepName
+	acquired, err := l.client.SetNX(ctx, key, token, l.ttl).Result()
+	if err != nil {
+		return nil, errors.Wrapf(err, "setting lock key %s", key)
+	}
+	if !acquired {
+		return nil, ErrStepLocked
+	}
+	unlock := func(ctx context.Context) error {
+		if err := releaseLockScript.Run(ctx, l.client, []string{key}, token).Err(); err != nil {
+			return errors.Wrapf(err, "deleting lock key %s", key)
+		}
+		return nil
+	}
+	return unlock, nil
+}
+
+// lockToken returns a random token identifying a lock holder.
+func lockToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
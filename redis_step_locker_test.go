@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedisStepLocker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	locker := NewRedisStepLocker(client, "saga:lock:", time.Minute)
+
+	unlock, err := locker.Lock(ctx, "charge")
+	require.Nil(t, err)
+	require.True(t, mr.Exists("saga:lock:charge"))
+	require.Equal(t, time.Minute, mr.TTL("saga:lock:charge"))
+
+	_, err = locker.Lock(ctx, "charge")
+	require.ErrorIs(t, err, ErrStepLocked)
+
+	// other steps are not locked.
+	unlockShip, err := locker.Lock(ctx, "ship")
+	require.Nil(t, err)
+	require.Nil(t, unlockShip(ctx))
+
+	require.Nil(t, unlock(ctx))
+	require.False(t, mr.Exists("saga:lock:charge"))
+
+	// expired locks can be acquired again, and are not
+	// released by their previous holder.
+	_, err = locker.Lock(ctx, "charge")
+	require.Nil(t, err)
+	mr.FastForward(time.Minute)
+	_, err = locker.Lock(ctx, "charge")
+	require.Nil(t, err)
+	require.Nil(t, unlock(ctx))
+	require.True(t, mr.Exists("saga:lock:charge"))
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
+)
+
+// WithResourceTag option sets the resource the step uses (e.g.
+// "db-write"), to limit the concurrent executions of the steps
+// using it (see WithResourceLimiter).
+func WithResourceTag(tag string) StepOption {
+	return func(s *step) {
+		s.resourceTag = tag
+	}
+}
+
+// ResourceLimiter limits the concurrent executions of the forward
+// action of the steps tagged with each resource (see WithResourceTag),
+// e.g. to avoid starving a database connection pool. A ResourceLimiter
+// is shared by all the Sagas it is passed to (see WithResourceLimiter).
+type ResourceLimiter struct {
+	mu         sync.Mutex
+	limits     map[string]int
+	semaphores map[string]*semaphore.Weighted
+}
+
+// NewResourceLimiter creates a new ResourceLimiter
+// without any limit (see SetLimit).
+func NewResourceLimiter() *ResourceLimiter {
+	return &ResourceLimiter{
+		limits:     make(map[string]int),
+		semaphores: make(map[string]*semaphore.Weighted),
+	}
+}
+
+// SetLimit limits the concurrent executions of the steps tagged with
+// the given resource. It returns an error if the limit is lower than 1,
+// or if a different limit was already set for the resource.
+func (l *ResourceLimiter) SetLimit(tag string, limit int) error {
+	if limit < 1 {
+		return errors.Errorf("invalid limit %d for resource %s: it must be at least 1", limit, tag)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if current, ok := l.limits[tag]; ok {
+		if current != limit {
+			return errors.Errorf("resource %s is already limited to %d concurrent executions", tag, current)
+		}
+		return nil
+	}
+	l.limits[tag] = limit
+	l.semaphores[tag] = semaphore.NewWeighted(int64(limit))
+	return nil
+}
+
+// semaphore returns the semaphore of the given
+// resource, or nil if it has no limit.
+func (l *ResourceLimiter) semaphore(tag string) *semaphore.Weighted {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.semaphores[tag]
+}
+
+// WithResourceLimiter option limits the concurrent executions of the
+// forward action of the steps of the Saga with the given limiter. Before
+// running a tagged step, the Saga waits for a slot of its resource, or
+// for the context to be done, and releases the slot once the step
+// completes, successfully or not. Steps without a resource tag, or
+// tagged with a resource without a limit, are not limited.
+func WithResourceLimiter(limiter *ResourceLimiter) Option {
+	return func(s *saga) {
+		s.resourceLimiter = limiter
+	}
+}
+
+// resourceTaggedStep is implemented by steps that
+// may be tagged with a resource (see WithResourceTag).
+type resourceTaggedStep interface {
+	// resource returns the resource tag of the step, if any.
+	resource() string
+}
+
+func (s *step) resource() string {
+	return s.resourceTag
+}
+
+// acquireResource waits for a slot of the resource of the given step,
+// if it has a limited one, returning the function that releases it.
+func (s *saga) acquireResource(ctx context.Context, step Step) (func(), error) {
+	r, ok := stepAs[resourceTaggedStep](step)
+	if !ok || r.resource() == "" || s.resourceLimiter == nil {
+		return func() {}, nil
+	}
+	tag := r.resource()
+	sem := s.resourceLimiter.semaphore(tag)
+	if sem == nil {
+		return func() {}, nil
+	}
+	if err := sem.Acquire(ctx, 1); err != nil {
+		return nil, errors.Wrapf(err, "acquiring slot of resource %s", tag)
+	}
+	return func() { sem.Release(1) }, nil
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithResourceLimiter(t *testing.T) {
+	testCases := []struct {
+		name        string
+		stepTag     string
+		limitTag    string
+		limit       int
+		expectedMax int
+	}{
+		{
+			name:        "limited resource",
+			stepTag:     "db-write",
+			limitTag:    "db-write",
+			limit:       2,
+			expectedMax: 2,
+		},
+		{
+			name:        "resource without limit",
+			stepTag:     "cache-write",
+			limitTag:    "other",
+			limit:       2,
+			expectedMax: 6,
+		},
+		{
+			name:        "untagged step",
+			limitTag:    "queue-write",
+			limit:       2,
+			expectedMax: 6,
+		},
+		{
+			name:        "without limiter",
+			stepTag:     "file-write",
+			expectedMax: 6,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := &concurrencyTracker{}
+			var opts []Option
+			if tc.limitTag != "" {
+				limiter := NewResourceLimiter()
+				require.NoError(t, limiter.SetLimit(tc.limitTag, tc.limit))
+				opts = append(opts, WithResourceLimiter(limiter))
+			}
+			var wg sync.WaitGroup
+			errs := make(chan error, 6)
+			for i := 0; i < 6; i++ {
+				saga := New(opts...)
+				saga.AddStep(NewStep("prepare", noop, noop))
+				saga.AddStep(NewStepWithOptions("write",
+					tracker.compensate(fmt.Sprintf("write%d", i), nil),
+					noop,
+					WithResourceTag(tc.stepTag),
+				))
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					errs <- saga.Execute(context.Background())
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				require.NoError(t, err)
+			}
+			require.Len(t, tracker.order, 6)
+			require.Equal(t, tc.expectedMax, tracker.max)
+		})
+	}
+}
+
+func TestWithResourceLimiter_ContextDone(t *testing.T) {
+	limiter := NewResourceLimiter()
+	require.NoError(t, limiter.SetLimit("payment-gateway", 1))
+	started, release := make(chan struct{}), make(chan struct{})
+	holder := New(WithResourceLimiter(limiter))
+	holder.AddStep(NewStepWithOptions("charge",
+		func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		},
+		noop,
+		WithResourceTag("payment-gateway"),
+	))
+	done := make(chan error, 1)
+	go func() {
+		done <- holder.Execute(context.Background())
+	}()
+	<-started
+
+	var compensated bool
+	saga := New(WithResourceLimiter(limiter))
+	saga.AddStep(NewStep("reserve", noop, func(ctx context.Context) error {
+		compensated = true
+		return nil
+	}))
+	saga.AddStep(NewStepWithOptions("charge", noop, noop, WithResourceTag("payment-gateway")))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := saga.Execute(ctx)
+	require.EqualError(t, err, "executing step charge: acquiring slot of resource payment-gateway: context deadline exceeded")
+	require.True(t, compensated)
+
+	close(release)
+	require.NoError(t, <-done)
+}
+
+func TestResourceLimiter_SetLimit(t *testing.T) {
+	testCases := []struct {
+		name          string
+		limits        []int
+		expectedError string
+	}{
+		{
+			name:   "same limit set twice",
+			limits: []int{2, 2},
+		},
+		{
+			name:          "conflicting limits",
+			limits:        []int{2, 3},
+			expectedError: "resource db-write is already limited to 2 concurrent executions",
+		},
+		{
+			name:          "invalid limit",
+			limits:        []int{0},
+			expectedError: "invalid limit 0 for resource db-write: it must be at least 1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := NewResourceLimiter()
+			var err error
+			for _, limit := range tc.limits {
+				if err = limiter.SetLimit("db-write", limit); err != nil {
+					break
+				}
+			}
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
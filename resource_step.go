@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Resource is a resource, such as a database connection,
+// a file handle or a lock, used by a resource step.
+type Resource any
+
+// resourceStep is a step that acquires a resource, uses it,
+// and always releases it afterwards.
+type resourceStep struct {
+	*step
+	acquire func(ctx context.Context) (Resource, error)
+	use     func(ctx context.Context, r Resource) error
+	release func(ctx context.Context, r Resource) error
+}
+
+// NewResourceStep creates a new Step whose forward action acquires
+// a resource, uses it and releases it. The resource is released
+// whether use succeeds, fails or panics (the panic is propagated,
+// and can be turned into an error with WithPanicRecovery).
+// If acquire fails, neither use nor release are called.
+func NewResourceStep(name string, acquire func(ctx context.Context) (Resource, error), use func(ctx context.Context, r Resource) error, release func(ctx context.Context, r Resource) error, compensate func(ctx context.Context) error) Step {
+	return &resourceStep{
+		step:    newStep(name, nil, compensate, nil),
+		acquire: acquire,
+		use:     use,
+		release: release,
+	}
+}
+
+func (s *resourceStep) ExecuteForward(ctx context.Context) (err error) {
+	r, err := s.acquire(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "acquiring resource for step %s", s.name)
+	}
+	defer func() {
+		if releaseErr := s.release(ctx, r); releaseErr != nil {
+			releaseErr = errors.Wrapf(releaseErr, "releasing resource for step %s", s.name)
+			if err != nil {
+				err = &MultiError{Errors: []error{err, releaseErr}}
+				return
+			}
+			err = releaseErr
+		}
+	}()
+	return s.use(ctx, r)
+}
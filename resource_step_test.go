@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResourceStep(t *testing.T) {
+	testCases := []struct {
+		name             string
+		acquireErr       error
+		use              func(ctx context.Context, r Resource) error
+		releaseErr       error
+		expectedReleased bool
+		expectedError    error
+	}{
+		{
+			name: "use succeeds",
+			use: func(ctx context.Context, r Resource) error {
+				return nil
+			},
+			expectedReleased: true,
+		},
+		{
+			name: "use fails",
+			use: func(ctx context.Context, r Resource) error {
+				return errors.New("use error")
+			},
+			expectedReleased: true,
+			expectedError:    errors.New("use error"),
+		},
+		{
+			name: "use panics",
+			use: func(ctx context.Context, r Resource) error {
+				panic("boom")
+			},
+			expectedReleased: true,
+			expectedError:    errors.New("step resource panicked: boom"),
+		},
+		{
+			name:          "acquire fails",
+			acquireErr:    errors.New("acquire error"),
+			expectedError: errors.New("acquiring resource for step resource: acquire error"),
+		},
+		{
+			name: "release fails",
+			use: func(ctx context.Context, r Resource) error {
+				return nil
+			},
+			releaseErr:       errors.New("release error"),
+			expectedReleased: true,
+			expectedError:    errors.New("releasing resource for step resource: release error"),
+		},
+		{
+			name: "use and release fail",
+			use: func(ctx context.Context, r Resource) error {
+				return errors.New("use error")
+			},
+			releaseErr:       errors.New("release error"),
+			expectedReleased: true,
+			expectedError:    errors.New("[use error releasing resource for step resource: release error]"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			released := false
+			step := NewResourceStep("resource",
+				func(ctx context.Context) (Resource, error) {
+					return "conn", tc.acquireErr
+				},
+				tc.use,
+				func(ctx context.Context, r Resource) error {
+					require.Equal(t, "conn", r)
+					released = true
+					return tc.releaseErr
+				},
+				noop,
+			)
+			saga := New(WithPanicRecovery(), WithLazyCompensation())
+			saga.AddStep(step)
+			err := saga.Execute(context.Background())
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.Equal(t, "executing step resource: "+tc.expectedError.Error(), err.Error())
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, tc.expectedReleased, released)
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// forwardWithRetry runs the forward action, retrying it according to
+// the step's retry configuration while the returned error is retriable.
+// The token bucket and the pre and post execution delays apply to each attempt.
+// At-most-once steps are never retried.
+func (s *step) forwardWithRetry(ctx context.Context) error {
+	clock := clockFromContext(ctx)
+	var deadline time.Time
+	if s.retryMaxElapsed > 0 {
+		deadline = clock.Now().Add(s.retryMaxElapsed)
+	}
+	maxAttempts := s.maxAttempts
+	switch {
+	case s.atMostOnce:
+		maxAttempts = 1
+	case maxAttempts < 1 && !deadline.IsZero():
+		// Bounded by the max elapsed time only.
+		maxAttempts = math.MaxInt
+	case maxAttempts < 1:
+		maxAttempts = 1
+	}
+	forward := s.forwardAction()
+	if s.forwardTimeout > 0 && s.retryResetTimeout {
+		forward = withTimeout(s.name, s.forwardTimeout, forward)
+	}
+	delay := s.retryDelay
+	if s.backoffPolicy != nil {
+		s.backoffPolicy.Reset()
+	}
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		s.setAttempts(attempt)
+		if err = s.waitToken(ctx); err != nil {
+			return err
+		}
+		attemptCtx := s.withForwardCorrelationID(ctx, attempt)
+		if err = s.withDelays(attemptCtx, forward); err == nil {
+			return nil
+		}
+		s.observeError(attemptCtx, err)
+		// Do not retry once the context is done (e.g. the timeout
+		// shared by all the attempts fired).
+		if attempt == maxAttempts || !s.isRetriable(attempt, err) || ctx.Err() != nil {
+			return err
+		}
+		if s.backoffPolicy != nil {
+			delay = s.backoffPolicy.NextDelay(attempt)
+		}
+		// Do not retry if the next attempt would start after the deadline.
+		if !deadline.IsZero() && clock.Now().Add(delay).After(deadline) {
+			return err
+		}
+		s.notifyRetry(ctx, attempt, err, delay)
+		if sleepErr := sleepContext(ctx, delay); sleepErr != nil {
+			return err
+		}
+		if s.backoffPolicy == nil && s.retryMultiplier > 0 {
+			delay = time.Duration(float64(delay) * s.retryMultiplier)
+		}
+	}
+	return err
+}
+
+// notifyRetry calls the retry notification callback, if any, with the
+// failed attempt. Calls are serialized, even for concurrent executions.
+func (s *step) notifyRetry(ctx context.Context, attempt int, err error, nextDelay time.Duration) {
+	if s.retryNotify == nil {
+		return
+	}
+	s.retryNotifyMu.Lock()
+	defer s.retryNotifyMu.Unlock()
+	s.retryNotify(ctx, s.name, attempt, err, nextDelay)
+}
+
+// attemptCounter is implemented by steps that
+// count the attempts of their forward action.
+type attemptCounter interface {
+	// attempts returns the number of attempts of
+	// the last execution of the forward action.
+	attempts() int
+}
+
+func (s *step) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastAttempts
+}
+
+// setAttempts records the number of attempts
+// of the current execution of the forward action.
+func (s *step) setAttempts(attempts int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastAttempts = attempts
+}
+
+// isRetriable reports whether the given forward error of the given
+// attempt can be retried. Input validation errors are never retried.
+func (s *step) isRetriable(attempt int, err error) bool {
+	if _, ok := err.(*InputValidationError); ok {
+		return false
+	}
+	if s.attemptErrorClassifier != nil && !s.attemptErrorClassifier(attempt, err) {
+		return false
+	}
+	if s.errorClassifier == nil {
+		return true
+	}
+	return s.errorClassifier(err)
+}
+
+// sleepContext waits for the given duration, according to the clock
+// of the context, returning early with the context error if the
+// context is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := clockFromContext(ctx).NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
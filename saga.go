@@ -6,9 +6,18 @@ package saga
 
 import (
 	"context"
+	"database/sql"
+	"log/slog"
+	"runtime/debug"
+	"slices"
+	"sort"
 	"sync"
+	"time"
 
+	"github.com/panjf2000/ants/v2"
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Saga defines the interface for a Saga pattern implementation.
@@ -17,6 +26,14 @@ type Saga interface {
 	// its forward and compensation actions.
 	AddStep(step Step)
 
+	// AddCheckpoint adds a named checkpoint after the steps added so far.
+	// Checkpoints are no-op steps that fire the WithOnCheckpoint hook
+	// when reached. They are never retried nor compensated, and their
+	// state is not stored in the state manager. Note that, like any step,
+	// a checkpoint takes an index, which shifts the state manager indexes
+	// of the steps added after it.
+	AddCheckpoint(name string)
+
 	// Execute runs the Saga, executing each step in sequence.
 	// If any step fails, the Saga triggers compensation
 	// for all previously successful steps.
@@ -24,15 +41,108 @@ type Saga interface {
 
 	// Compensate rolls back all successfully executed steps if any
 	// subsequent step fails during the Saga's execution.
+	// When the Saga is configured with WithLazyCompensation,
+	// this must be called explicitly by the caller after Execute fails.
+	// Steps already compensated since the last Execute are skipped,
+	// so it can safely be called again, e.g. after a partial failure.
 	Compensate(ctx context.Context) error
+
+	// IsCompensationAsync reports whether the Saga is configured with
+	// WithAsyncCompensation.
+	IsCompensationAsync() bool
+
+	// ProgressPercent returns the current progress of the Saga,
+	// computed as the sum of the weights of the completed steps
+	// divided by the sum of the weights of all steps, times 100.
+	ProgressPercent() float64
+
+	// ID returns the identifier of the Saga, if any.
+	ID() string
+
+	// Summary returns a summary of the current (or last) execution.
+	Summary() Summary
+
+	// Steps returns the steps of the Saga, including checkpoints,
+	// in the order they were added.
+	Steps() []Step
 }
 
 // saga is the concrete implementation of the Saga interface.
 type saga struct {
-	steps        []Step
-	currentStep  int
-	stateManager StateManager
-	mu           sync.Mutex
+	id                        string
+	tenantID                  string
+	steps                     []Step
+	currentStep               int
+	stateManager              StateManager
+	lazyComp                  bool
+	asyncCompensation         func(saga Saga, failedStep string) error
+	asyncFailure              *asyncFailure
+	skipCompensationOnSuccess bool
+	resourceLimiter           *ResourceLimiter
+	observers                 ObserverChain
+	compensationNotNeeded     bool
+	preflightCtxCheck         bool
+	contextInheritance        func(parent, child context.Context) context.Context
+	forwardedContextKeys      []any
+	panicRecovery             bool
+	logger                    *slog.Logger
+	logSanitizer              func(key string, val any) any
+	progressHooks             []func(ctx context.Context, p StepProgress)
+	eventHooks                []func(ctx context.Context, e SagaEvent)
+	deadLetterHandler         DeadLetterHandler
+	featureFlagCheck          func(ctx context.Context, stepName string) bool
+	lazyStepLoading           bool
+	stepNameFormatter         func(name string) string
+	serviceName               string
+	waitGroup                 *sync.WaitGroup
+	errorSanitizer            func(err error) error
+	db                        *sql.DB
+	txOpts                    *sql.TxOptions
+	parallelCompensation      bool
+	compensationConcurrency   int
+	compensationGroups        [][]int
+	compensationGroupLimits   map[string]int
+	onCheckpoint              func(ctx context.Context, name string, completedSteps int)
+	tracer                    trace.Tracer
+	baggageKeys               []string
+	inheritBaggage            bool
+	spanAttributeKeys         []attribute.Key
+	inheritSpanAttributes     bool
+	traceContextExtractor     func(ctx context.Context) context.Context
+	clock                     Clock
+	stepOrderer               func(steps []Step) []Step
+	nameKeyedState            bool
+	stepOrder                 []int
+	middlewares               []StepMiddleware
+	middlewareSelectors       []middlewareSelector
+	stepMiddlewares           [][]StepMiddleware
+	stepNameResolver          StepNameResolver
+	stateKeyStrategy          StateKeyStrategy
+	validator                 Validator
+	errorWrapper              func(err error, stepName string, stepIndex int) error
+	goroutineLocalStore       GoroutineLocalStore
+	goroutinePool             *ants.Pool
+	correlationIDGen          func() string
+	cbFailureThreshold        int
+	cbOpenDuration            time.Duration
+	cbFallbackToMemory        bool
+	stateBatchSize            int
+	stateFlushInterval        time.Duration
+	circuitBreaker            *circuitBreakerStateManager
+	mu                        sync.Mutex
+
+	// configErr is the error of the options
+	// that could not be applied by New.
+	configErr error
+
+	summary       Summary
+	summaryMu     sync.RWMutex
+	skipped       map[int]bool
+	forwardErr    error
+	correlationID string
+
+	deadLetterFailures map[int]deadLetterFailure
+	deadLetterMu       sync.Mutex
 }
 
 // new creates a new saga instance with the given options.
@@ -40,12 +150,38 @@ type saga struct {
 // by default, but this can be overridden with the provided options.
 func new(options []Option) Saga {
 	s := &saga{
-		steps:        []Step{},
-		stateManager: NewInMemoryStateManager(),
+		steps:              []Step{},
+		stateManager:       NewInMemoryStateManager(),
+		skipped:            make(map[int]bool),
+		deadLetterFailures: make(map[int]deadLetterFailure),
 	}
 	for _, option := range options {
 		option(s)
 	}
+	if s.tenantID != "" {
+		sm, err := NewTenantAwareStateManager(s.stateManager, s.tenantID)
+		if err != nil {
+			s.configErr = errors.Wrap(err, "isolating tenant state")
+		} else {
+			s.stateManager = sm
+		}
+	}
+	if s.cbFailureThreshold > 0 {
+		s.circuitBreaker = newCircuitBreakerStateManager(s.stateManager, s.cbFailureThreshold, s.cbOpenDuration, s.clock)
+		if s.cbFallbackToMemory {
+			s.circuitBreaker.fallback = NewInMemoryStateManager()
+			s.circuitBreaker.onFallback = s.warnStateManagerFallback
+		}
+		s.stateManager = s.circuitBreaker
+	}
+	if s.stateBatchSize > 0 {
+		s.stateManager = NewBatchedStateManager(s.stateManager, s.stateBatchSize, s.stateFlushInterval)
+	}
+	if s.stateKeyStrategy != nil && s.configErr == nil {
+		if _, ok := stateManagerAs[NamedStateManager](s.stateManager); !ok {
+			s.configErr = errors.New("keying step states requires a NamedStateManager (see WithStateKeyStrategy)")
+		}
+	}
 	return s
 }
 
@@ -55,66 +191,747 @@ func New(options ...Option) Saga {
 	return new(options)
 }
 
+func (s *saga) ID() string {
+	return s.id
+}
+
 func (s *saga) AddStep(step Step) {
+	if l, ok := stepAs[lazyLoader](step); ok && !s.lazyStepLoading {
+		l.load()
+	}
 	s.steps = append(s.steps, step)
 }
 
+func (s *saga) AddCheckpoint(name string) {
+	s.steps = append(s.steps, &checkpointStep{name: name})
+}
+
 func (s *saga) Execute(ctx context.Context) error {
+	if s.waitGroup != nil {
+		s.waitGroup.Add(1)
+		defer s.waitGroup.Done()
+	}
+	failure, err := s.executeHeld(ctx)
+	// With async compensation, the handler schedules it. It is called
+	// once the Saga is released, so that it can call Compensate.
+	if failure != nil {
+		if errSched := s.asyncCompensation(s, failure.stepName); errSched != nil {
+			return errors.Wrapf(errSched, "scheduling compensation after failure in step %s: %v", failure.stepName, failure.err)
+		}
+	}
+	return err
+}
+
+// asyncFailure is a step failure whose compensation is to be scheduled
+// by the handler of WithAsyncCompensation once Execute releases the Saga.
+type asyncFailure struct {
+	stepName string
+	err      error
+}
+
+// executeHeld runs the steps of the Saga while holding it, returning
+// the step failure whose compensation is to be scheduled, if any.
+func (s *saga) executeHeld(ctx context.Context) (failure *asyncFailure, err error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	defer func() {
+		failure, s.asyncFailure = s.asyncFailure, nil
+	}()
+	if bm, ok := s.stateManager.(*BatchedStateManager); ok {
+		defer func() {
+			if flushErr := bm.Flush(); flushErr != nil && err == nil {
+				err = errors.Wrap(flushErr, "flushing state writes")
+			}
+		}()
+	}
+	ctx = withClock(ctx, s.clock)
+	if s.tracer != nil && s.traceContextExtractor != nil {
+		ctx = s.traceContextExtractor(ctx)
+	}
+	return nil, s.withDatabaseTransaction(ctx, s.execute)
+}
+
+// execute runs the steps of the Saga. It must be called with s.mu held.
+func (s *saga) execute(ctx context.Context) error {
+	if s.configErr != nil {
+		return s.configErr
+	}
+	if s.validator != nil {
+		if err := s.validator.Validate(s); err != nil {
+			return errors.Wrap(err, "validating saga")
+		}
+	}
+	if err := s.orderSteps(); err != nil {
+		return err
+	}
+	s.buildMiddlewareChains()
+	weights, err := s.stepWeights()
+	if err != nil {
+		return err
+	}
+	if err := s.checkCompensations(); err != nil {
+		return err
+	}
+	if err := s.checkStateKeyStrategy(); err != nil {
+		return err
+	}
+	var totalWeight, completedWeight float64
+	for _, w := range weights {
+		totalWeight += w
+	}
+	completedSteps := 0
+	clock := clockFromContext(ctx)
+	s.resetSummary()
+	s.skipped = make(map[int]bool)
+	s.resetDeadLetterFailures()
+	s.forwardErr = nil
+	s.compensationNotNeeded = false
+	if s.circuitBreaker != nil {
+		s.circuitBreaker.reset()
+	}
+
+	// advance accounts for the current step in the Saga's progress.
+	advance := func() {
+		completedSteps++
+		completedWeight += weights[s.declaredIndex(s.currentStep)]
+		s.setProgress(completedWeight / totalWeight * 100)
+	}
 
 	for s.currentStep = 0; s.currentStep < len(s.steps); s.currentStep++ {
-		step := s.steps[s.currentStep]
+		step := s.stepAt(s.currentStep)
+
+		// Checkpoints only notify that they were reached.
+		if isCheckpoint(step) {
+			s.reachCheckpoint(ctx, step, completedSteps)
+			continue
+		}
 
 		// Skip steps that have already been completed.
-		stepCompleted, err := s.stateManager.StepState(s.currentStep)
+		stepCompleted, err := s.stepState(s.currentStep, step)
 		if err != nil {
-			return errors.Wrapf(err, "retrieving state for step %s", step.Name())
+			return errors.Wrapf(err, "retrieving state for step %s", s.stepName(step))
 		}
 		if stepCompleted {
+			if sk, ok := stepAs[skippable](step); ok {
+				sk.markSkipped()
+			}
+			advance()
 			continue
 		}
 
+		// Steps disabled by a feature flag are skipped.
+		if !s.stepEnabled(ctx, step) {
+			if err := s.skipDisabledStep(ctx, step); err != nil {
+				return err
+			}
+			advance()
+			s.emitProgress(ctx, step, StepStatusSkipped, nil)
+			s.emitEvent(ctx, EventStepSkipped, step, s.currentStep, nil, 0)
+			continue
+		}
+
+		if err := s.persistStepMetadata(step); err != nil {
+			return errors.Wrapf(err, "setting metadata for step %s", s.stepName(step))
+		}
+
 		// Try executing the current step.
-		if err := step.ExecuteForward(ctx); err != nil {
+		start := clock.Now()
+		err = s.executeForward(ctx, step)
+		duration := clock.Now().Sub(start)
+		s.recordForwardTiming(step, duration)
+		if err != nil {
+			// Optional steps do not trigger compensation:
+			// they are marked as done and the Saga moves on.
+			if isOptional(step) {
+				if err := s.skipOptionalStep(ctx, step, err); err != nil {
+					return err
+				}
+				advance()
+				s.emitProgress(ctx, step, StepStatusSkipped, err)
+				s.emitEvent(ctx, EventStepSkipped, step, s.currentStep, err, duration)
+				continue
+			}
+
+			s.forwardErr = err
+			s.logStepFailure(ctx, step, "step failed", err)
+			s.recordFailure(step, err)
+			s.emitProgress(ctx, step, StepStatusFailed, err)
+			s.emitEvent(ctx, EventStepFailed, step, s.currentStep, err, duration)
+
 			// Mark this step as failed.
-			if err := s.stateManager.SetStepState(s.currentStep, false); err != nil {
-				return errors.Wrapf(err, "setting state for step %s", step.Name())
+			if err := s.setStepState(s.currentStep, step, false); err != nil {
+				return errors.Wrapf(err, "setting state for step %s", s.stepName(step))
+			}
+
+			// With lazy compensation, the caller decides when to compensate.
+			if s.lazyComp {
+				return s.wrapStepError(err, step)
+			}
+
+			// With async compensation, the handler schedules it
+			// once Execute releases the Saga.
+			if s.asyncCompensation != nil {
+				s.asyncFailure = &asyncFailure{stepName: s.stepName(step), err: err}
+				return s.wrapStepError(err, step)
 			}
 
 			// Trigger compensation for all previously successful steps.
-			if errComp := s.Compensate(ctx); errComp != nil {
-				return errors.Wrapf(errComp, "compensating after failure in step %s: %v", step.Name(), err)
+			if errComp := s.compensate(ctx); errComp != nil {
+				return errors.Wrapf(errComp, "compensating after failure in step %s: %v", s.stepName(step), err)
 			}
 
 			// Return the original error.
-			return errors.Wrapf(err, "executing step %s", step.Name())
+			return s.wrapStepError(err, step)
+		}
+
+		if err := s.persistStepOutput(ctx, step); err != nil {
+			return errors.Wrapf(err, "capturing output of step %s", s.stepName(step))
 		}
 
 		// Mark this step as successfully completed.
-		if err := s.stateManager.SetStepState(s.currentStep, true); err != nil {
-			return errors.Wrapf(err, "setting state for step %s", step.Name())
+		if err := s.setStepState(s.currentStep, step, true); err != nil {
+			return errors.Wrapf(err, "setting state for step %s", s.stepName(step))
 		}
+		advance()
+		s.emitProgress(ctx, step, StepStatusCompleted, nil)
+		s.emitEvent(ctx, EventStepCompleted, step, s.currentStep, nil, duration)
 	}
 
+	s.compensationNotNeeded = s.skipCompensationOnSuccess
 	return nil
 }
 
+// executeForward runs the forward action of the given step,
+// which is the current one.
+func (s *saga) executeForward(parent context.Context, step Step) error {
+	// Do not start the step if the context is already done.
+	if s.preflightCtxCheck {
+		if err := parent.Err(); err != nil {
+			return err
+		}
+	}
+	ctx := withStepPosition(parent, len(s.steps), s.currentStep)
+	ctx = withSagaID(ctx, s.id)
+	ctx = s.withCorrelationID(ctx, step)
+	ctx, span := s.startStepSpan(ctx, step)
+	if s.contextInheritance != nil {
+		ctx = s.contextInheritance(parent, ctx)
+	}
+	ctx = s.forwardContext(parent, ctx)
+	s.logStepInput(ctx, step)
+	forward := s.withMiddleware(s.currentStep, step, step.ExecuteForward)
+	s.observers.BeforeForward(ctx, step)
+	// The time spent waiting for a resource slot is not observed.
+	var duration time.Duration
+	release, err := s.acquireResource(ctx, step)
+	if err == nil {
+		clock := clockFromContext(ctx)
+		start := clock.Now()
+		err = s.recoverPanic(ctx, step, func() error {
+			ctx := s.setGoroutineLocals(ctx, step, s.currentStep)
+			return forward(ctx)
+		})
+		duration = clock.Now().Sub(start)
+		release()
+	}
+	s.observers.AfterForward(ctx, step, duration, s.sanitizeError(step, err))
+	s.logStepOutput(ctx, step, err)
+	s.logForwardCorrelationIDs(ctx, step)
+	endStepSpan(span, s.sanitizeError(step, err))
+	return err
+}
+
+// recoverPanic calls the given step action. If the step has a panic
+// handler, a panic in the action is handed to it. Otherwise, if panic
+// recovery is enabled, it is returned as a *PanicError.
+func (s *saga) recoverPanic(ctx context.Context, step Step, action func() error) (err error) {
+	if h, ok := stepAs[panicHandlingStep](step); ok && h.hasPanicHandler() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = h.handlePanic(ctx, r, debug.Stack())
+			}
+		}()
+		return action()
+	}
+	if !s.panicRecovery {
+		return action()
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{StepName: s.stepName(step), Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return action()
+}
+
+func (s *saga) ProgressPercent() float64 {
+	s.summaryMu.RLock()
+	defer s.summaryMu.RUnlock()
+	return s.summary.ProgressPercent
+}
+
+func (s *saga) Summary() Summary {
+	s.summaryMu.RLock()
+	defer s.summaryMu.RUnlock()
+	summary := s.summary
+	summary.SkippedSteps = append([]StepResult(nil), s.summary.SkippedSteps...)
+	summary.Checkpoints = append([]string(nil), s.summary.Checkpoints...)
+	summary.CompensatedSteps = append([]StepResult(nil), s.summary.CompensatedSteps...)
+	summary.StepTimings = append([]StepTiming(nil), s.summary.StepTimings...)
+	if s.summary.FailedStep != nil {
+		failedStep := *s.summary.FailedStep
+		summary.FailedStep = &failedStep
+	}
+	return summary
+}
+
+func (s *saga) Steps() []Step {
+	return append([]Step(nil), s.steps...)
+}
+
+// warnStateManagerFallback reports that the Saga fell back
+// to an in-memory state manager.
+func (s *saga) warnStateManagerFallback() {
+	if s.logger != nil {
+		s.logger.Warn("state manager circuit open, falling back to in-memory state manager")
+	}
+}
+
+// recordFailure records the step that caused the Saga to fail.
+func (s *saga) recordFailure(step Step, stepErr error) {
+	s.summaryMu.Lock()
+	defer s.summaryMu.Unlock()
+	s.summary.FailedStep = &StepResult{
+		StepName:  s.stepName(step),
+		StepIndex: s.currentStep,
+		Err:       s.sanitizeError(step, stepErr),
+		Category:  errorCategory(stepErr),
+	}
+}
+
+// recordForwardTiming records the duration of the forward
+// action of the current step, along with its attempts.
+func (s *saga) recordForwardTiming(step Step, duration time.Duration) {
+	attempts := 1
+	if c, ok := stepAs[attemptCounter](step); ok {
+		attempts = c.attempts()
+	}
+	s.summaryMu.Lock()
+	defer s.summaryMu.Unlock()
+	s.summary.StepTimings = append(s.summary.StepTimings, StepTiming{
+		StepName:        s.stepName(step),
+		StepIndex:       s.currentStep,
+		ForwardDuration: duration,
+		TotalAttempts:   attempts,
+	})
+}
+
+// recordCompensateTiming records the duration of the
+// compensation action of the step at the given index.
+func (s *saga) recordCompensateTiming(step Step, stepIndex int, duration time.Duration) {
+	s.summaryMu.Lock()
+	defer s.summaryMu.Unlock()
+	for i := range s.summary.StepTimings {
+		if s.summary.StepTimings[i].StepIndex == stepIndex {
+			s.summary.StepTimings[i].CompensateDuration = duration
+			return
+		}
+	}
+	// the forward action was run by a previous execution.
+	i := sort.Search(len(s.summary.StepTimings), func(i int) bool {
+		return s.summary.StepTimings[i].StepIndex > stepIndex
+	})
+	s.summary.StepTimings = slices.Insert(s.summary.StepTimings, i, StepTiming{
+		StepName:           s.stepName(step),
+		StepIndex:          stepIndex,
+		CompensateDuration: duration,
+	})
+}
+
+// isCompensated reports whether the step at the given
+// index was compensated since the last execution.
+func (s *saga) isCompensated(stepIndex int) bool {
+	s.summaryMu.RLock()
+	defer s.summaryMu.RUnlock()
+	for _, r := range s.summary.CompensatedSteps {
+		if r.StepIndex == stepIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// recordCompensation records a successfully compensated step.
+func (s *saga) recordCompensation(step Step, stepIndex int) {
+	s.summaryMu.Lock()
+	defer s.summaryMu.Unlock()
+	s.summary.CompensatedSteps = append(s.summary.CompensatedSteps, StepResult{
+		StepName:  s.stepName(step),
+		StepIndex: stepIndex,
+	})
+}
+
+// resetSummary clears the summary at the start of an execution.
+func (s *saga) resetSummary() {
+	s.summaryMu.Lock()
+	defer s.summaryMu.Unlock()
+	s.summary = Summary{SagaID: s.id}
+}
+
+// setProgress updates the current progress percentage.
+func (s *saga) setProgress(percent float64) {
+	s.summaryMu.Lock()
+	defer s.summaryMu.Unlock()
+	s.summary.ProgressPercent = percent
+}
+
+// skipOptionalStep records the failure of an optional step and
+// marks it as done, so that it is neither retried nor compensated.
+func (s *saga) skipOptionalStep(ctx context.Context, step Step, stepErr error) error {
+	s.logStepFailure(ctx, step, "optional step failed, continuing", stepErr)
+	if err := s.setStepState(s.currentStep, step, true); err != nil {
+		return errors.Wrapf(err, "setting state for step %s", s.stepName(step))
+	}
+	s.skipped[s.declaredIndex(s.currentStep)] = true
+	s.summaryMu.Lock()
+	defer s.summaryMu.Unlock()
+	s.summary.SkippedSteps = append(s.summary.SkippedSteps, StepResult{
+		StepName:  s.stepName(step),
+		StepIndex: s.currentStep,
+		Err:       s.sanitizeError(step, stepErr),
+		Category:  errorCategory(stepErr),
+	})
+	return nil
+}
+
+// stepState retrieves the completion state of the given step, at the
+// given execution position, using the step's own state manager if it
+// overrides the Saga's one.
+func (s *saga) stepState(stepIndex int, step Step) (bool, error) {
+	return s.declaredStepState(s.declaredIndex(stepIndex), step)
+}
+
+// declaredStepState retrieves the completion state of the given step,
+// at the given declaration index, using the step's own state manager
+// if it overrides the Saga's one.
+func (s *saga) declaredStepState(i int, step Step) (bool, error) {
+	if sm := stepStateManager(step); sm != nil {
+		return sm.IsCompleted()
+	}
+	if nm, key, ok := s.namedStateManager(i, step); ok {
+		return nm.NamedStepState(key)
+	}
+	return s.stateManager.StepState(i)
+}
+
+// setStepState records the completion state of the given step, at the
+// given execution position, using the step's own state manager if it
+// overrides the Saga's one.
+func (s *saga) setStepState(stepIndex int, step Step, success bool) error {
+	return s.setDeclaredStepState(s.declaredIndex(stepIndex), step, success)
+}
+
+// setDeclaredStepState records the completion state of the given step,
+// at the given declaration index, using the step's own state manager
+// if it overrides the Saga's one.
+// Step state managers only record successful completions.
+func (s *saga) setDeclaredStepState(i int, step Step, success bool) error {
+	if sm := stepStateManager(step); sm != nil {
+		if !success {
+			return nil
+		}
+		return sm.SetCompleted()
+	}
+	if nm, key, ok := s.namedStateManager(i, step); ok {
+		if vm, ok := stateManagerAs[NamedVersionedStateManager](s.stateManager); ok {
+			return setNamedStepStateVersioned(vm, key, success)
+		}
+		return nm.SetNamedStepState(key, success)
+	}
+	if vm, ok := stateManagerAs[VersionedStateManager](s.stateManager); ok {
+		return setStepStateVersioned(vm, i, success)
+	}
+	return s.stateManager.SetStepState(i, success)
+}
+
+// stateConflictRetries is the number of times the state of a step is
+// written again when another runner modified it concurrently.
+const stateConflictRetries = 3
+
+// setStepStateVersioned records the completion state of the given
+// step with compare-and-swap semantics, reading the current version
+// again and retrying when another runner modified it concurrently.
+func setStepStateVersioned(vm VersionedStateManager, stepIndex int, success bool) error {
+	return compareAndSwapState(
+		func() (int, error) {
+			_, version, err := vm.StepStateWithVersion(stepIndex)
+			return version, err
+		},
+		func(version int) error {
+			_, err := vm.SetStepStateVersioned(stepIndex, success, version)
+			return err
+		},
+	)
+}
+
+// setNamedStepStateVersioned records the completion state of the step
+// stored under the given key with compare-and-swap semantics, like
+// setStepStateVersioned.
+func setNamedStepStateVersioned(vm NamedVersionedStateManager, key string, success bool) error {
+	return compareAndSwapState(
+		func() (int, error) {
+			_, version, err := vm.NamedStepStateWithVersion(key)
+			return version, err
+		},
+		func(version int) error {
+			_, err := vm.SetNamedStepStateVersioned(key, success, version)
+			return err
+		},
+	)
+}
+
+// compareAndSwapState reads the current version of the state of a step
+// and writes it if the version did not change, retrying when another
+// runner modified it concurrently.
+func compareAndSwapState(readVersion func() (int, error), write func(version int) error) error {
+	for retry := 0; ; retry++ {
+		version, err := readVersion()
+		if err != nil {
+			return err
+		}
+		err = write(version)
+		var conflictErr *ConcurrentModificationError
+		if !errors.As(err, &conflictErr) || retry == stateConflictRetries {
+			return err
+		}
+	}
+}
+
+// persistStepMetadata records the metadata of the current step, if it
+// has any and the Saga's StateManager supports step metadata.
+func (s *saga) persistStepMetadata(step Step) error {
+	p, ok := stepAs[MetadataProvider](step)
+	if !ok {
+		return nil
+	}
+	metadata := p.Metadata()
+	if len(metadata) == 0 {
+		return nil
+	}
+	mm, ok := s.stateManager.(StepMetadataManager)
+	if !ok {
+		return nil
+	}
+	if err := mm.SetStepMetadata(s.declaredIndex(s.currentStep), metadata); err != nil && !errors.Is(err, ErrStepMetadataNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// persistStepOutput records the output of the current step, if it
+// captures it and the Saga's StateManager supports step outputs.
+func (s *saga) persistStepOutput(ctx context.Context, step Step) error {
+	c, ok := stepAs[outputCapturingStep](step)
+	if !ok {
+		return nil
+	}
+	om, ok := s.stateManager.(StepOutputManager)
+	if !ok {
+		return nil
+	}
+	data, err := c.captureOutput(ctx)
+	if err != nil {
+		return err
+	}
+	if data == nil {
+		return nil
+	}
+	if err := om.SetStepOutput(s.declaredIndex(s.currentStep), data); err != nil && !errors.Is(err, ErrStepOutputNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// logStepInput logs, at debug level, the input extracted
+// by the step's input logger, if both are configured.
+func (s *saga) logStepInput(ctx context.Context, step Step) {
+	if s.logger == nil {
+		return
+	}
+	l, ok := stepAs[ioLoggingStep](step)
+	if !ok {
+		return
+	}
+	if input := l.logInput(ctx); input != nil {
+		s.logger.DebugContext(ctx, "step input", "step", s.stepName(step), "step.input", s.sanitize(input))
+	}
+}
+
+// logStepOutput logs, at debug level, the output extracted
+// by the step's output logger, if both are configured.
+func (s *saga) logStepOutput(ctx context.Context, step Step, err error) {
+	if s.logger == nil {
+		return
+	}
+	l, ok := stepAs[ioLoggingStep](step)
+	if !ok {
+		return
+	}
+	if output := l.logOutput(ctx, err); output != nil {
+		s.logger.DebugContext(ctx, "step output", "step", s.stepName(step), "step.output", s.sanitize(output))
+	}
+}
+
+// sanitize returns a copy of the given values transformed
+// by the configured log sanitizer, if any.
+func (s *saga) sanitize(values map[string]any) map[string]any {
+	if s.logSanitizer == nil {
+		return values
+	}
+	sanitized := make(map[string]any, len(values))
+	for key, val := range values {
+		sanitized[key] = s.logSanitizer(key, val)
+	}
+	return sanitized
+}
+
+// stepWeights returns the weight of each step, validating that
+// explicitly set weights are positive. Steps without an explicit
+// weight are treated as having weight 1.0.
+func (s *saga) stepWeights() ([]float64, error) {
+	weights := make([]float64, len(s.steps))
+	anyWeightSet := false
+	for i, step := range s.steps {
+		if isCheckpoint(step) {
+			continue
+		}
+		weights[i] = 1.0
+		ws, ok := stepAs[WeightedStep](step)
+		if !ok {
+			continue
+		}
+		w, set := ws.Weight()
+		if !set {
+			continue
+		}
+		if w <= 0 {
+			return nil, errors.Errorf("invalid weight %v for step %s: must be positive", w, s.stepName(step))
+		}
+		weights[i] = w
+		anyWeightSet = true
+	}
+	if !anyWeightSet && s.logger != nil {
+		s.logger.Warn("no step weights set, treating all steps as weight 1.0")
+	}
+	return weights, nil
+}
+
+func (s *saga) IsCompensationAsync() bool {
+	return s.asyncCompensation != nil
+}
+
 func (s *saga) Compensate(ctx context.Context) error {
-	var compensationErrors []error
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// See WithSkipCompensationOnSuccessfulSaga.
+	if s.compensationNotNeeded {
+		if s.logger != nil {
+			s.logger.DebugContext(ctx, "skipping compensation of successful saga")
+		}
+		return nil
+	}
+	return s.compensate(withClock(ctx, s.clock))
+}
 
-	// Compensate from the current step backwards.
-	for i := s.currentStep; i >= 0; i-- {
-		step := s.steps[i]
-		if err := step.ExecuteCompensate(ctx); err != nil {
-			compensationErrors = append(compensationErrors, err)
+// compensate rolls back the steps from the current step backwards.
+// The caller must hold s.mu.
+func (s *saga) compensate(ctx context.Context) error {
+	var compensationErrors []error
+	indexes := s.stepsToCompensate()
+	if s.parallelCompensation {
+		compensationErrors = s.compensateInParallel(ctx, indexes)
+	} else if units := s.compensationUnits(indexes); units != nil {
+		compensationErrors = s.compensateByGroup(ctx, units)
+	} else {
+		for _, i := range indexes {
+			if err := s.compensateStep(ctx, i); err != nil {
+				compensationErrors = append(compensationErrors, err)
+			}
 		}
 	}
 
 	if len(compensationErrors) > 0 {
 		// Aggregate all compensation errors into a single error.
-		return errors.Errorf("compensation failed with errors: %v", compensationErrors)
+		return errors.Wrap(&MultiError{Errors: compensationErrors}, "compensation failed with errors")
 	}
 
 	return nil
 }
+
+// stepsToCompensate returns the indexes of the steps to
+// compensate, from the current step backwards.
+func (s *saga) stepsToCompensate() []int {
+	start := s.currentStep
+	if start >= len(s.steps) {
+		start = len(s.steps) - 1
+	}
+	var indexes []int
+	for i := start; i >= 0; i-- {
+		// Skipped steps and checkpoints have nothing to compensate.
+		step := s.stepAt(i)
+		if s.skipped[s.declaredIndex(i)] || isCheckpoint(step) {
+			continue
+		}
+		if !shouldCompensate(step, s.forwardErr) {
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes
+}
+
+// compensateStep runs the compensation action of the step at the
+// given index, returning the error to report, if any.
+func (s *saga) compensateStep(ctx context.Context, i int) error {
+	if s.isCompensated(i) {
+		return nil
+	}
+	step := s.stepAt(i)
+	s.observers.BeforeCompensate(ctx, step)
+	clock := clockFromContext(ctx)
+	start := clock.Now()
+	err := s.recoverPanic(ctx, step, func() error {
+		ctx := s.forwardContext(ctx, s.setGoroutineLocals(withSagaID(ctx, s.id), step, i))
+		return step.ExecuteCompensate(ctx)
+	})
+	if err != nil {
+		err = handleCompensationError(ctx, step, err)
+	} else {
+		err = s.verifyCompensation(ctx, step)
+	}
+	duration := clock.Now().Sub(start)
+	s.observers.AfterCompensate(ctx, step, duration, s.sanitizeError(step, err))
+	s.recordCompensateTiming(step, i, duration)
+	if err != nil {
+		s.emitEvent(ctx, EventCompensationFailed, step, i, err, duration)
+		s.sendToDeadLetter(ctx, step, i, err)
+		return err
+	}
+	s.recordCompensation(step, i)
+	s.emitEvent(ctx, EventStepCompensated, step, i, nil, duration)
+	return nil
+}
+
+// verifyCompensation verifies the compensation of the given step,
+// if it has a compensation verifier, logging the failure.
+func (s *saga) verifyCompensation(ctx context.Context, step Step) error {
+	v, ok := stepAs[compensationVerifyingStep](step)
+	if !ok {
+		return nil
+	}
+	err := v.verifyCompensation(ctx)
+	if err != nil && s.logger != nil {
+		s.logger.WarnContext(ctx, "compensation verification failed", "step", s.stepName(step), "error", err)
+	}
+	return err
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+func BenchmarkExecute(b *testing.B) {
+	for _, numSteps := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("%d steps", numSteps), func(b *testing.B) {
+			steps := make([]Step, numSteps)
+			for i := range steps {
+				steps[i] = NewStep(fmt.Sprintf("step%d", i+1), noop, noop)
+			}
+			ctx := context.Background()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				saga := New()
+				for _, step := range steps {
+					saga.AddStep(step)
+				}
+				if err := saga.Execute(ctx); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SagaPipeline orchestrates Sagas depending on each other's results.
+// Sagas run once all the Sagas they depend on completed, independent
+// Sagas running in parallel. Each Saga receives the summaries of the
+// Sagas it depends on through the context of its steps (see
+// DependencySummaryFromContext). If a Saga fails, the Sagas that
+// completed are compensated in reverse topological order.
+type SagaPipeline struct {
+	mu      sync.Mutex
+	names   []string
+	nodes   map[string]*pipelineNode
+	summary PipelineSummary
+}
+
+// pipelineNode is a Saga of a SagaPipeline.
+type pipelineNode struct {
+	saga      Saga
+	dependsOn []string
+}
+
+// PipelineSummary holds information about the
+// current (or last) execution of a SagaPipeline.
+type PipelineSummary struct {
+	// Sagas holds the summary of each executed Saga, by name.
+	Sagas map[string]Summary
+
+	// FailedSaga is the name of the Saga that caused
+	// the pipeline to fail, if any.
+	FailedSaga string
+
+	// CompensatedSagas lists the names of the Sagas successfully
+	// compensated, in the order they were compensated.
+	CompensatedSagas []string
+}
+
+// NewSagaPipeline creates a new, empty SagaPipeline.
+func NewSagaPipeline() *SagaPipeline {
+	return &SagaPipeline{
+		nodes: make(map[string]*pipelineNode),
+	}
+}
+
+// AddSaga adds the given Saga to the pipeline under the given name,
+// to run after the Sagas with the given names. Dependencies may be
+// added later, but must all be added before Execute.
+func (p *SagaPipeline) AddSaga(name string, saga Saga, dependsOn ...string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if name == "" {
+		return errors.New("saga name must not be empty")
+	}
+	if _, exists := p.nodes[name]; exists {
+		return errors.Errorf("saga %s already added", name)
+	}
+	p.names = append(p.names, name)
+	p.nodes[name] = &pipelineNode{saga: saga, dependsOn: dependsOn}
+	return nil
+}
+
+// DependencySummaryFromContext returns the summary of the Saga with the
+// given name, if the Saga executing the step that received the given
+// context depends on it within a SagaPipeline.
+func DependencySummaryFromContext(ctx context.Context, name string) (Summary, bool) {
+	summaries, _ := ctx.Value(dependencySummariesKey).(map[string]Summary)
+	summary, ok := summaries[name]
+	return summary, ok
+}
+
+// Execute runs the Sagas of the pipeline in dependency order. It fails
+// without running any Saga if a dependency is unknown or cyclic.
+func (p *SagaPipeline) Execute(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	levels, err := p.levels()
+	if err != nil {
+		return err
+	}
+	p.summary = PipelineSummary{Sagas: make(map[string]Summary)}
+	var completed []string
+	for _, level := range levels {
+		errs := make([]error, len(level))
+		var wg sync.WaitGroup
+		for i, name := range level {
+			wg.Add(1)
+			go func(i int, name string) {
+				defer wg.Done()
+				errs[i] = p.nodes[name].saga.Execute(p.dependencyContext(ctx, name))
+			}(i, name)
+		}
+		wg.Wait()
+		var failed string
+		var failure error
+		for i, name := range level {
+			p.summary.Sagas[name] = p.nodes[name].saga.Summary()
+			if errs[i] == nil {
+				completed = append(completed, name)
+			} else if failure == nil {
+				failed, failure = name, errs[i]
+			}
+		}
+		if failure != nil {
+			p.summary.FailedSaga = failed
+			if errComp := p.compensate(ctx, completed); errComp != nil {
+				return errors.Wrapf(errComp, "compensating after failure in saga %s: %v", failed, failure)
+			}
+			return errors.Wrapf(failure, "executing saga %s", failed)
+		}
+	}
+	return nil
+}
+
+// Summary returns a summary of the current (or last) execution.
+func (p *SagaPipeline) Summary() PipelineSummary {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	summary := p.summary
+	summary.Sagas = make(map[string]Summary, len(p.summary.Sagas))
+	for name, s := range p.summary.Sagas {
+		summary.Sagas[name] = s
+	}
+	summary.CompensatedSagas = append([]string(nil), p.summary.CompensatedSagas...)
+	return summary
+}
+
+// levels sorts the Sagas topologically, grouping them in levels whose
+// Sagas only depend on Sagas of previous levels. Within a level, Sagas
+// are in the order they were added.
+func (p *SagaPipeline) levels() ([][]string, error) {
+	remaining := make(map[string]int, len(p.nodes))
+	for _, name := range p.names {
+		for _, dep := range p.nodes[name].dependsOn {
+			if _, exists := p.nodes[dep]; !exists {
+				return nil, errors.Errorf("saga %s depends on unknown saga %s", name, dep)
+			}
+		}
+		remaining[name] = len(p.nodes[name].dependsOn)
+	}
+	var levels [][]string
+	done := make(map[string]bool, len(p.nodes))
+	for len(done) < len(p.nodes) {
+		var level []string
+		for _, name := range p.names {
+			if !done[name] && remaining[name] == 0 {
+				level = append(level, name)
+			}
+		}
+		if len(level) == 0 {
+			return nil, errors.New("sagas have cyclic dependencies")
+		}
+		for _, name := range level {
+			done[name] = true
+		}
+		for _, name := range p.names {
+			for _, dep := range p.nodes[name].dependsOn {
+				if slices.Contains(level, dep) {
+					remaining[name]--
+				}
+			}
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// dependencyContext returns a copy of ctx carrying the summaries
+// of the Sagas the Saga with the given name depends on.
+func (p *SagaPipeline) dependencyContext(ctx context.Context, name string) context.Context {
+	summaries := make(map[string]Summary)
+	for _, dep := range p.nodes[name].dependsOn {
+		summaries[dep] = p.summary.Sagas[dep]
+	}
+	return context.WithValue(ctx, dependencySummariesKey, summaries)
+}
+
+// compensate compensates the given completed Sagas,
+// in reverse order, aggregating the errors.
+func (p *SagaPipeline) compensate(ctx context.Context, completed []string) error {
+	var compensationErrors []error
+	for i := len(completed) - 1; i >= 0; i-- {
+		name := completed[i]
+		if err := p.nodes[name].saga.Compensate(ctx); err != nil {
+			compensationErrors = append(compensationErrors, errors.Wrapf(err, "compensating saga %s", name))
+			continue
+		}
+		p.summary.CompensatedSagas = append(p.summary.CompensatedSagas, name)
+	}
+	if len(compensationErrors) > 0 {
+		return errors.Wrap(&MultiError{Errors: compensationErrors}, "compensation failed with errors")
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSagaPipeline(t *testing.T) {
+	testCases := []struct {
+		name                string
+		failing             string
+		expectedError       string
+		expectedCompensated []string
+	}{
+		{
+			name: "success",
+		},
+		{
+			name:                "failure compensates completed sagas",
+			failing:             "shipping",
+			expectedError:       "executing saga shipping: executing step ship: ship error",
+			expectedCompensated: []string{"payment", "inventory", "order"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var compensations []string
+			var orderSagaID string
+			newSaga := func(name, stepName string) Saga {
+				saga := New(WithSagaID(name + "-1"))
+				saga.AddStep(NewStep(stepName,
+					func(ctx context.Context) error {
+						if summary, ok := DependencySummaryFromContext(ctx, "order"); ok {
+							mu.Lock()
+							orderSagaID = summary.SagaID
+							mu.Unlock()
+						}
+						if name == tc.failing {
+							return errors.New(stepName + " error")
+						}
+						return nil
+					},
+					func(ctx context.Context) error {
+						mu.Lock()
+						defer mu.Unlock()
+						compensations = append(compensations, stepName)
+						return nil
+					},
+				))
+				return saga
+			}
+			pipeline := NewSagaPipeline()
+			// Dependencies may be added after their dependents.
+			require.Nil(t, pipeline.AddSaga("shipping", newSaga("shipping", "ship"), "payment", "inventory"))
+			require.Nil(t, pipeline.AddSaga("order", newSaga("order", "create")))
+			require.Nil(t, pipeline.AddSaga("inventory", newSaga("inventory", "reserve"), "order"))
+			require.Nil(t, pipeline.AddSaga("payment", newSaga("payment", "charge"), "order"))
+
+			err := pipeline.Execute(context.Background())
+			summary := pipeline.Summary()
+			require.Equal(t, "order-1", orderSagaID)
+			require.Len(t, summary.Sagas, 4)
+			require.Equal(t, "payment-1", summary.Sagas["payment"].SagaID)
+			if tc.expectedError == "" {
+				require.Nil(t, err)
+				require.Empty(t, summary.FailedSaga)
+				return
+			}
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedError, err.Error())
+			require.Equal(t, tc.failing, summary.FailedSaga)
+			require.Equal(t, tc.expectedCompensated, summary.CompensatedSagas)
+			// The failed saga compensates itself, then the completed
+			// ones are compensated in reverse topological order.
+			require.Equal(t, []string{"ship", "charge", "reserve", "create"}, compensations)
+		})
+	}
+}
+
+func TestSagaPipeline_InvalidDependencies(t *testing.T) {
+	testCases := []struct {
+		name          string
+		add           func(p *SagaPipeline) error
+		expectedError string
+	}{
+		{
+			name: "duplicate saga",
+			add: func(p *SagaPipeline) error {
+				if err := p.AddSaga("order", New()); err != nil {
+					return err
+				}
+				return p.AddSaga("order", New())
+			},
+			expectedError: "saga order already added",
+		},
+		{
+			name: "unknown dependency",
+			add: func(p *SagaPipeline) error {
+				if err := p.AddSaga("order", New(), "customer"); err != nil {
+					return err
+				}
+				return p.Execute(context.Background())
+			},
+			expectedError: "saga order depends on unknown saga customer",
+		},
+		{
+			name: "cyclic dependencies",
+			add: func(p *SagaPipeline) error {
+				if err := p.AddSaga("order", New(), "payment"); err != nil {
+					return err
+				}
+				if err := p.AddSaga("payment", New(), "order"); err != nil {
+					return err
+				}
+				return p.Execute(context.Background())
+			},
+			expectedError: "sagas have cyclic dependencies",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.add(NewSagaPipeline())
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedError, err.Error())
+		})
+	}
+}
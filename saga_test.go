@@ -5,9 +5,14 @@
 package saga
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 )
@@ -300,6 +305,929 @@ func TestSaga_RetryPartialExecution(t *testing.T) {
 	require.Equal(t, 5, ss.X)
 }
 
+func TestSaga_LazyCompensation(t *testing.T) {
+	ss := &sampleState{}
+	saga := New(WithLazyCompensation())
+	saga.AddStep(NewStep("step1",
+		func(ctx context.Context) error {
+			ss.X = 1
+			return nil
+		},
+		func(ctx context.Context) error {
+			ss.X = 0
+			return nil
+		},
+	))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("step2 error")
+		},
+		func(ctx context.Context) error {
+			return nil
+		},
+	))
+
+	// Compensation is deferred, so step 1 effects remain.
+	err := saga.Execute(context.Background())
+	require.NotNil(t, err)
+	require.Equal(t, "executing step step2: step2 error", err.Error())
+	require.Equal(t, 1, ss.X)
+
+	// Explicitly compensating rolls back step 1.
+	err = saga.Compensate(context.Background())
+	require.Nil(t, err)
+	require.Equal(t, 0, ss.X)
+}
+
+func TestSaga_AsyncCompensation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		handlerErr    error
+		expectedError string
+	}{
+		{
+			name:          "compensation scheduled",
+			expectedError: "executing step step3: step3 error",
+		},
+		{
+			name:          "scheduling fails",
+			handlerErr:    errors.New("queue unavailable"),
+			expectedError: "scheduling compensation after failure in step step3: step3 error: queue unavailable",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var mu sync.Mutex
+			compensations := map[string]int{}
+			compensate := func(name string) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					mu.Lock()
+					defer mu.Unlock()
+					compensations[name]++
+					if name == "step1" && compensations[name] == 1 {
+						return errors.New("step1 compensation error")
+					}
+					return nil
+				}
+			}
+			var failedStep string
+			compensated := make(chan error, 1)
+			saga := New(WithAsyncCompensation(func(s Saga, step string) error {
+				failedStep = step
+				if tc.handlerErr != nil {
+					return tc.handlerErr
+				}
+				go func() {
+					compensated <- s.Compensate(context.Background())
+				}()
+				return nil
+			}))
+			require.True(t, saga.IsCompensationAsync())
+			saga.AddStep(NewStep("step1", noop, compensate("step1")))
+			saga.AddStep(NewStep("step2", noop, compensate("step2")))
+			saga.AddStep(NewStep("step3",
+				func(ctx context.Context) error {
+					return errors.New("step3 error")
+				},
+				noop,
+			))
+			err := saga.Execute(context.Background())
+			require.EqualError(t, err, tc.expectedError)
+			require.Equal(t, "step3", failedStep)
+			if tc.handlerErr != nil {
+				require.Empty(t, compensations)
+				return
+			}
+
+			// step1 fails to compensate, so compensation is retried:
+			// step2 is not compensated again.
+			require.NotNil(t, <-compensated)
+			require.Nil(t, saga.Compensate(context.Background()))
+			require.Equal(t, map[string]int{"step1": 2, "step2": 1}, compensations)
+		})
+	}
+}
+
+func TestSaga_AsyncCompensation_SynchronousHandler(t *testing.T) {
+	var compensated []string
+	var compensateErr error
+	saga := New(WithAsyncCompensation(func(s Saga, step string) error {
+		compensateErr = s.Compensate(context.Background())
+		return nil
+	}))
+	saga.AddStep(NewStep("step1", noop, func(ctx context.Context) error {
+		compensated = append(compensated, "step1")
+		return nil
+	}))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("step2 error")
+		},
+		noop,
+	))
+	err := saga.Execute(context.Background())
+	require.EqualError(t, err, "executing step step2: step2 error")
+	require.Nil(t, compensateErr)
+	require.Equal(t, []string{"step1"}, compensated)
+}
+
+func TestSaga_SkipCompensationOnSuccessfulSaga(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		options               []Option
+		failStep2             bool
+		expectedCompensations []string
+	}{
+		{
+			name:                  "successful saga",
+			options:               []Option{WithSkipCompensationOnSuccessfulSaga()},
+			expectedCompensations: nil,
+		},
+		{
+			name:                  "failed saga",
+			options:               []Option{WithSkipCompensationOnSuccessfulSaga(), WithLazyCompensation()},
+			failStep2:             true,
+			expectedCompensations: []string{"step2", "step1"},
+		},
+		{
+			name:                  "without option",
+			expectedCompensations: []string{"step2", "step1"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var compensations []string
+			compensate := func(name string) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					compensations = append(compensations, name)
+					return nil
+				}
+			}
+			saga := New(tc.options...)
+			saga.AddStep(NewStep("step1", noop, compensate("step1")))
+			saga.AddStep(NewStep("step2",
+				func(ctx context.Context) error {
+					if tc.failStep2 {
+						return errors.New("step2 error")
+					}
+					return nil
+				},
+				compensate("step2"),
+			))
+			err := saga.Execute(context.Background())
+			require.Equal(t, tc.failStep2, err != nil)
+			require.Nil(t, saga.Compensate(context.Background()))
+			require.Equal(t, tc.expectedCompensations, compensations)
+		})
+	}
+}
+
+func TestSaga_ProgressPercent(t *testing.T) {
+	testCases := []struct {
+		name             string
+		steps            []Step
+		expectedProgress float64
+		expectedError    error
+	}{
+		{
+			name: "no weights, all steps succeed",
+			steps: []Step{
+				NewStep("step1", noop, noop),
+				NewStep("step2", noop, noop),
+			},
+			expectedProgress: 100,
+		},
+		{
+			name: "weighted steps, second fails",
+			steps: []Step{
+				NewStepWithOptions("step1", noop, noop, WithStepWeight(3)),
+				NewStepWithOptions("step2",
+					func(ctx context.Context) error {
+						return errors.New("step2 error")
+					},
+					noop,
+					WithStepWeight(1),
+				),
+			},
+			expectedProgress: 75,
+			expectedError:    errors.New("executing step step2: step2 error"),
+		},
+		{
+			name: "mixed weights, last fails",
+			steps: []Step{
+				NewStepWithOptions("step1", noop, noop, WithStepWeight(2)),
+				NewStep("step2", noop, noop),
+				NewStep("step3",
+					func(ctx context.Context) error {
+						return errors.New("step3 error")
+					},
+					noop,
+				),
+			},
+			expectedProgress: 75,
+			expectedError:    errors.New("executing step step3: step3 error"),
+		},
+		{
+			name: "invalid weight",
+			steps: []Step{
+				NewStepWithOptions("step1", noop, noop, WithStepWeight(0)),
+			},
+			expectedError: errors.New("invalid weight 0 for step step1: must be positive"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			saga := New()
+			for _, step := range tc.steps {
+				saga.AddStep(step)
+			}
+			err := saga.Execute(context.Background())
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError.Error(), err.Error())
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, tc.expectedProgress, saga.ProgressPercent())
+		})
+	}
+}
+
+func TestSaga_OptionalStep(t *testing.T) {
+	testCases := []struct {
+		name          string
+		optionalStep  func(forward, compensate func(ctx context.Context) error) Step
+		failLastStep  bool
+		expectedCalls []string
+		expectedError error
+	}{
+		{
+			name: "optional step option, saga succeeds",
+			optionalStep: func(forward, compensate func(ctx context.Context) error) Step {
+				return NewStepWithOptions("email", forward, compensate, WithOptionalStep())
+			},
+			expectedCalls: []string{"account", "email", "profile"},
+		},
+		{
+			name: "optional step wrapper, saga succeeds",
+			optionalStep: func(forward, compensate func(ctx context.Context) error) Step {
+				return AsOptional(NewStep("email", forward, compensate))
+			},
+			expectedCalls: []string{"account", "email", "profile"},
+		},
+		{
+			name: "optional step is not compensated",
+			optionalStep: func(forward, compensate func(ctx context.Context) error) Step {
+				return NewStepWithOptions("email", forward, compensate, WithOptionalStep())
+			},
+			failLastStep:  true,
+			expectedCalls: []string{"account", "email", "profile", "compensate profile", "compensate account"},
+			expectedError: errors.New("executing step profile: profile error"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			record := func(name string, err error) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					calls = append(calls, name)
+					return err
+				}
+			}
+			var profileErr error
+			if tc.failLastStep {
+				profileErr = errors.New("profile error")
+			}
+			saga := New()
+			saga.AddStep(NewStep("account", record("account", nil), record("compensate account", nil)))
+			saga.AddStep(tc.optionalStep(record("email", errors.New("email error")), record("compensate email", nil)))
+			saga.AddStep(NewStep("profile", record("profile", profileErr), record("compensate profile", nil)))
+
+			err := saga.Execute(context.Background())
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError.Error(), err.Error())
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, tc.expectedCalls, calls)
+
+			summary := saga.Summary()
+			require.Len(t, summary.SkippedSteps, 1)
+			require.Equal(t, "email", summary.SkippedSteps[0].StepName)
+			require.Equal(t, 1, summary.SkippedSteps[0].StepIndex)
+			require.Equal(t, "email error", summary.SkippedSteps[0].Err.Error())
+		})
+	}
+}
+
+func TestSaga_StepStateManager(t *testing.T) {
+	stepSM := &mockStepStateManager{}
+	sagaSM := NewInMemoryStateManager()
+	calls := 0
+	fail := true
+	saga := New(WithStateManager(sagaSM), WithLazyCompensation())
+	saga.AddStep(NewStepWithOptions("step1",
+		func(ctx context.Context) error {
+			calls++
+			return nil
+		},
+		noop,
+		WithStepStateManager(stepSM),
+	))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			if fail {
+				return errors.New("step2 error")
+			}
+			return nil
+		},
+		noop,
+	))
+
+	require.NotNil(t, saga.Execute(context.Background()))
+	require.True(t, stepSM.completed)
+
+	// Step 1 state is not kept in the saga-level state manager.
+	completed, err := sagaSM.StepState(0)
+	require.Nil(t, err)
+	require.False(t, completed)
+
+	// On retry, step 1 is skipped based on its own state manager.
+	fail = false
+	require.Nil(t, saga.Execute(context.Background()))
+	require.Equal(t, 1, calls)
+}
+
+func TestSaga_CompensationCondition(t *testing.T) {
+	errValidation := errors.New("validation error")
+	testCases := []struct {
+		name                string
+		forwardErr          error
+		expectedCompensated bool
+	}{
+		{
+			name:                "predicate returns true",
+			forwardErr:          errors.New("database error"),
+			expectedCompensated: true,
+		},
+		{
+			name:                "predicate returns false",
+			forwardErr:          errValidation,
+			expectedCompensated: false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			compensated := false
+			saga := New()
+			saga.AddStep(NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					return tc.forwardErr
+				},
+				func(ctx context.Context) error {
+					compensated = true
+					return nil
+				},
+				WithCompensationCondition(func(forwardErr error) bool {
+					return !errors.Is(forwardErr, errValidation)
+				}),
+			))
+			err := saga.Execute(context.Background())
+			require.NotNil(t, err)
+			require.Equal(t, tc.expectedCompensated, compensated)
+		})
+	}
+}
+
+func TestSaga_StepInputOutputLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	saga := New(
+		WithLogger(logger),
+		WithLogSanitizer(func(key string, val any) any {
+			if key == "email" {
+				return "[redacted]"
+			}
+			return val
+		}),
+	)
+	saga.AddStep(NewStepWithOptions("step1",
+		func(ctx context.Context) error {
+			return errors.New("step1 error")
+		},
+		noop,
+		WithStepInputLogger(func(ctx context.Context) map[string]any {
+			return map[string]any{"email": "john@doe.com"}
+		}),
+		WithStepOutputLogger(func(ctx context.Context, err error) map[string]any {
+			return map[string]any{"error": err.Error()}
+		}),
+	))
+	require.NotNil(t, saga.Execute(context.Background()))
+	require.Contains(t, buf.String(), `level=DEBUG msg="step input" step=step1 step.input=map[email:[redacted]]`)
+	require.Contains(t, buf.String(), `level=DEBUG msg="step output" step=step1 step.output="map[error:step1 error]"`)
+}
+
+func TestSaga_PreflightContextCheck(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls []string
+	saga := New(WithPreflightContextCheck())
+	saga.AddStep(NewStep("step1",
+		func(ctx context.Context) error {
+			calls = append(calls, "step1")
+			cancel()
+			return nil
+		},
+		func(ctx context.Context) error {
+			calls = append(calls, "compensate step1")
+			return nil
+		},
+	))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			calls = append(calls, "step2")
+			return nil
+		},
+		func(ctx context.Context) error {
+			calls = append(calls, "compensate step2")
+			return nil
+		},
+	))
+	err := saga.Execute(ctx)
+	require.NotNil(t, err)
+	require.Equal(t, "executing step step2: context canceled", err.Error())
+	require.Equal(t, []string{"step1", "compensate step2", "compensate step1"}, calls)
+}
+
+func TestSaga_PanicRecovery(t *testing.T) {
+	compensated := false
+	saga := New(WithPanicRecovery())
+	saga.AddStep(NewStep("step1",
+		noop,
+		func(ctx context.Context) error {
+			compensated = true
+			return nil
+		},
+	))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			panic("boom")
+		},
+		func(ctx context.Context) error {
+			panic("compensation boom")
+		},
+	))
+	err := saga.Execute(context.Background())
+	require.NotNil(t, err)
+	require.Equal(t, "compensating after failure in step step2: step step2 panicked: boom: compensation failed with errors: [step step2 panicked: compensation boom]", err.Error())
+	var panicErr *PanicError
+	require.True(t, errors.As(err, &panicErr))
+	require.NotEmpty(t, panicErr.Stack)
+	require.True(t, compensated)
+}
+
+func TestSaga_PanicHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		handler       func(ctx context.Context, stepName string, recovered any, stack []byte) error
+		expectedError string
+		compensated   bool
+	}{
+		{
+			name: "treat as success",
+			handler: func(ctx context.Context, stepName string, recovered any, stack []byte) error {
+				return nil
+			},
+		},
+		{
+			name: "treat as failure",
+			handler: func(ctx context.Context, stepName string, recovered any, stack []byte) error {
+				return fmt.Errorf("%s recovered from %v", stepName, recovered)
+			},
+			expectedError: "executing step step2: step2 recovered from boom",
+			compensated:   true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			compensated := false
+			saga := New(WithPanicRecovery())
+			saga.AddStep(NewStep("step1",
+				noop,
+				func(ctx context.Context) error {
+					compensated = true
+					return nil
+				},
+			))
+			saga.AddStep(NewStepWithOptions("step2",
+				func(ctx context.Context) error {
+					panic("boom")
+				},
+				noop,
+				WithPanicHandler(tc.handler),
+			))
+			err := saga.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError, err.Error())
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, tc.compensated, compensated)
+		})
+	}
+}
+
+func TestSaga_PanicHandlerRepanic(t *testing.T) {
+	saga := New(WithPanicRecovery())
+	saga.AddStep(NewStepWithOptions("step1",
+		func(ctx context.Context) error {
+			panic("boom")
+		},
+		noop,
+		WithPanicHandler(func(ctx context.Context, stepName string, recovered any, stack []byte) error {
+			panic(recovered)
+		}),
+	))
+	require.PanicsWithValue(t, "boom", func() {
+		_ = saga.Execute(context.Background())
+	})
+}
+
+func TestSaga_CompensationVerifier(t *testing.T) {
+	var buf bytes.Buffer
+	handler := &mockDeadLetterHandler{}
+	compensations := 0
+	saga := New(
+		WithDeadLetterHandler(handler),
+		WithLogger(slog.New(slog.NewTextHandler(&buf, nil))),
+	)
+	saga.AddStep(NewStepWithOptions("step1",
+		noop,
+		func(ctx context.Context) error {
+			compensations++
+			return nil
+		},
+		WithCompensationVerifier(func(ctx context.Context) error {
+			return errors.New("reservation still active")
+		}),
+	))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("step2 error")
+		},
+		noop,
+	))
+	err := saga.Execute(context.Background())
+	require.NotNil(t, err)
+	require.Equal(t, "compensating after failure in step step2: step2 error: compensation failed with errors: [verification of compensation of step step1 failed: reservation still active]", err.Error())
+	var verificationErr *CompensationVerificationError
+	require.True(t, errors.As(err, &verificationErr))
+	require.Equal(t, "step1", verificationErr.StepName)
+	require.Equal(t, 1, compensations)
+	require.Len(t, handler.items, 1)
+	require.Equal(t, "step1", handler.items[0].StepName)
+	require.Contains(t, buf.String(), "compensation verification failed")
+}
+
+func TestSaga_WaitGroup(t *testing.T) {
+	var wg sync.WaitGroup
+	release := make(chan struct{})
+	started := make(chan struct{})
+	saga := New(WithWaitGroup(&wg))
+	saga.AddStep(NewStep("step1",
+		func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		},
+		noop,
+	))
+	go func() {
+		_ = saga.Execute(context.Background())
+	}()
+	<-started
+
+	waited := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("wait group released before the saga completed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("wait group not released after the saga completed")
+	}
+
+	// A panicking execution releases the wait group too.
+	saga = New(WithWaitGroup(&wg))
+	saga.AddStep(NewStep("step1",
+		func(ctx context.Context) error {
+			panic("boom")
+		},
+		noop,
+	))
+	require.Panics(t, func() {
+		_ = saga.Execute(context.Background())
+	})
+	wg.Wait()
+}
+
+func TestSaga_Checkpoint(t *testing.T) {
+	type reached struct {
+		name           string
+		completedSteps int
+	}
+	var checkpoints []reached
+	sm := NewInMemoryStateManager()
+	saga := New(
+		WithStateManager(sm),
+		WithOnCheckpoint(func(ctx context.Context, name string, completedSteps int) {
+			checkpoints = append(checkpoints, reached{name: name, completedSteps: completedSteps})
+		}),
+	)
+	saga.AddStep(NewStep("step1", noop, noop))
+	saga.AddStep(NewStep("step2", noop, noop))
+	saga.AddCheckpoint("halfway")
+	saga.AddStep(NewStep("step3", noop, noop))
+	saga.AddCheckpoint("done")
+
+	require.Nil(t, saga.Execute(context.Background()))
+	require.Equal(t, []reached{{name: "halfway", completedSteps: 2}, {name: "done", completedSteps: 3}}, checkpoints)
+	require.Equal(t, []string{"halfway", "done"}, saga.Summary().Checkpoints)
+	require.Equal(t, float64(100), saga.ProgressPercent())
+
+	// Checkpoint state is not stored.
+	completed, err := sm.StepState(2)
+	require.Nil(t, err)
+	require.False(t, completed)
+}
+
+func TestSaga_InputValidationTriggersCompensation(t *testing.T) {
+	compensated := false
+	saga := New()
+	saga.AddStep(NewStep("step1",
+		noop,
+		func(ctx context.Context) error {
+			compensated = true
+			return nil
+		},
+	))
+	saga.AddStep(NewStepWithOptions("step2", noop, noop,
+		WithInputValidation(func(ctx context.Context) error {
+			return errors.New("missing order id")
+		}),
+	))
+	err := saga.Execute(context.Background())
+	require.NotNil(t, err)
+	require.Equal(t, "executing step step2: invalid input for step step2: missing order id", err.Error())
+	var validationErr *InputValidationError
+	require.True(t, errors.As(err, &validationErr))
+	require.True(t, compensated)
+}
+
+func TestSaga_PostconditionTriggersCompensation(t *testing.T) {
+	testCases := []struct {
+		name                string
+		assertErr           error
+		expectedCompensated bool
+		expectedError       string
+	}{
+		{
+			name: "postcondition holds",
+		},
+		{
+			name:                "postcondition fails",
+			assertErr:           errors.New("order not found"),
+			expectedCompensated: true,
+			expectedError:       "executing step step2: postcondition failed for step step2: order not found",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			compensated := false
+			saga := New()
+			saga.AddStep(NewStep("step1",
+				noop,
+				func(ctx context.Context) error {
+					compensated = true
+					return nil
+				},
+			))
+			saga.AddStep(NewStepWithOptions("step2", noop, noop,
+				WithPostconditionAssertion(func(ctx context.Context) error {
+					return tc.assertErr
+				}),
+			))
+			err := saga.Execute(context.Background())
+			require.Equal(t, tc.expectedCompensated, compensated)
+			if tc.expectedError == "" {
+				require.Nil(t, err)
+				return
+			}
+			require.EqualError(t, err, tc.expectedError)
+			var postconditionErr *PostconditionFailedError
+			require.True(t, errors.As(err, &postconditionErr))
+			require.Equal(t, "step2", postconditionErr.StepName)
+		})
+	}
+}
+
+func TestSaga_StepMetadata(t *testing.T) {
+	testCases := []struct {
+		name             string
+		stateManager     func() (StateManager, StepMetadataManager)
+		expectedMetadata map[string]string
+		expectedError    string
+	}{
+		{
+			name: "in-memory state manager",
+			stateManager: func() (StateManager, StepMetadataManager) {
+				sm := NewInMemoryStateManager()
+				return sm, sm
+			},
+			expectedMetadata: map[string]string{"orderID": "42"},
+		},
+		{
+			name: "unsupported by tenant-aware state manager",
+			stateManager: func() (StateManager, StepMetadataManager) {
+				sm, err := NewTenantAwareStateManager(NewInMemoryStateManager(), "acme")
+				require.Nil(t, err)
+				_, ok := sm.(StepMetadataManager)
+				require.False(t, ok)
+				return sm, nil
+			},
+		},
+		{
+			name: "error",
+			stateManager: func() (StateManager, StepMetadataManager) {
+				return &mockMetadataStateManager{err: errors.New("metadata error")}, nil
+			},
+			expectedError: "setting metadata for step step1: metadata error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sm, mm := tc.stateManager()
+			saga := New(WithStateManager(sm))
+			saga.AddStep(NewStepWithOptions("step1", noop, noop,
+				WithStepMetadata(map[string]string{"orderID": "42"}),
+			))
+			saga.AddStep(NewStep("step2", noop, noop))
+			err := saga.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.Nil(t, err)
+			if mm == nil {
+				return
+			}
+			metadata, err := mm.GetStepMetadata(0)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedMetadata, metadata)
+			metadata, err = mm.GetStepMetadata(1)
+			require.Nil(t, err)
+			require.Nil(t, metadata)
+		})
+	}
+}
+
+func TestSaga_OutputCapture(t *testing.T) {
+	testCases := []struct {
+		name           string
+		stateManager   func() (StateManager, StepOutputManager)
+		serializeErr   error
+		expectedOutput []byte
+		expectedError  string
+	}{
+		{
+			name: "in-memory state manager",
+			stateManager: func() (StateManager, StepOutputManager) {
+				sm := NewInMemoryStateManager()
+				return sm, sm
+			},
+			expectedOutput: []byte(`{"orderID":42}`),
+		},
+		{
+			name: "unsupported by tenant-aware state manager",
+			stateManager: func() (StateManager, StepOutputManager) {
+				sm, err := NewTenantAwareStateManager(NewInMemoryStateManager(), "acme")
+				require.Nil(t, err)
+				_, ok := sm.(StepOutputManager)
+				require.False(t, ok)
+				return sm, nil
+			},
+		},
+		{
+			name: "serialization error",
+			stateManager: func() (StateManager, StepOutputManager) {
+				sm := NewInMemoryStateManager()
+				return sm, sm
+			},
+			serializeErr:  errors.New("serialization error"),
+			expectedError: "capturing output of step step1: serialization error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sm, om := tc.stateManager()
+			saga := New(WithStateManager(sm))
+			saga.AddStep(NewStepWithOptions("step1", noop, noop,
+				WithOutputCapture(func(ctx context.Context) ([]byte, error) {
+					return []byte(`{"orderID":42}`), tc.serializeErr
+				}),
+			))
+			saga.AddStep(NewStep("step2", noop, noop))
+			err := saga.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.Nil(t, err)
+			if om == nil {
+				return
+			}
+			output, err := om.GetStepOutput(0)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedOutput, output)
+			output, err = om.GetStepOutput(1)
+			require.Nil(t, err)
+			require.Nil(t, output)
+		})
+	}
+}
+
+func TestSaga_CompensationErrorHandler(t *testing.T) {
+	errNotFound := errors.New("not found")
+	handler := func(ctx context.Context, stepName string, err error) error {
+		if errors.Is(err, errNotFound) {
+			return nil
+		}
+		return fmt.Errorf("%s: %w", stepName, err)
+	}
+	testCases := []struct {
+		name              string
+		compensationErr   error
+		expectedError     string
+		expectedCompSteps int
+	}{
+		{
+			name:              "ignored error",
+			compensationErr:   errNotFound,
+			expectedError:     "executing step step2: forward error",
+			expectedCompSteps: 2,
+		},
+		{
+			name:              "transformed error",
+			compensationErr:   errors.New("connection refused"),
+			expectedError:     "compensating after failure in step step2: forward error: compensation failed with errors: [step1: connection refused]",
+			expectedCompSteps: 1,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			saga := New()
+			saga.AddStep(NewStepWithOptions("step1",
+				noop,
+				func(ctx context.Context) error {
+					return tc.compensationErr
+				},
+				WithCompensationErrorHandler(handler),
+			))
+			saga.AddStep(NewStep("step2",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				noop,
+			))
+			err := saga.Execute(context.Background())
+			require.EqualError(t, err, tc.expectedError)
+			require.Len(t, saga.Summary().CompensatedSteps, tc.expectedCompSteps)
+		})
+	}
+}
+
+func noop(ctx context.Context) error {
+	return nil
+}
+
 type mockStateManager struct {
 	setStepStateErr error
 	stepState       bool
@@ -313,3 +1241,29 @@ func (m *mockStateManager) SetStepState(stepIndex int, success bool) error {
 func (m *mockStateManager) StepState(stepIndex int) (bool, error) {
 	return m.stepState, m.stepStateErr
 }
+
+type mockStepStateManager struct {
+	completed bool
+}
+
+func (m *mockStepStateManager) SetCompleted() error {
+	m.completed = true
+	return nil
+}
+
+func (m *mockStepStateManager) IsCompleted() (bool, error) {
+	return m.completed, nil
+}
+
+type mockMetadataStateManager struct {
+	mockStateManager
+	err error
+}
+
+func (m *mockMetadataStateManager) SetStepMetadata(stepIndex int, metadata map[string]string) error {
+	return m.err
+}
+
+func (m *mockMetadataStateManager) GetStepMetadata(stepIndex int) (map[string]string, error) {
+	return nil, m.err
+}
@@ -0,0 +1,197 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+// Package sagatesting provides utilities for testing sagas.
+package sagatesting
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tiagomelo/go-saga"
+)
+
+// Types of the recorded events.
+const (
+	EventForward    = "forward"
+	EventCompensate = "compensate"
+	EventState      = "state"
+)
+
+// RecordedEvent is an event recorded during the execution of a saga:
+// a call to the forward or compensation action of a step, or a change
+// of the state of a step. It holds no timing information, so that
+// recordings of deterministic sagas are deterministic too.
+type RecordedEvent struct {
+	Type      string `json:"type"`
+	StepName  string `json:"step_name,omitempty"`
+	StepIndex int    `json:"step_index"`
+	Success   *bool  `json:"success,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Recorder is a saga.Saga that records its execution events,
+// to be compared against golden files with Golden.
+type Recorder struct {
+	saga.Saga
+
+	mu     sync.Mutex
+	events []RecordedEvent
+	steps  int
+}
+
+// NewRecorder creates a new Recorder wrapping a saga created with the
+// given options, recording the state changes made to sm (an in-memory
+// state manager if nil). The options must not include
+// saga.WithStateManager: sm is used instead.
+func NewRecorder(sm saga.StateManager, opts ...saga.Option) *Recorder {
+	if sm == nil {
+		sm = saga.NewInMemoryStateManager()
+	}
+	r := &Recorder{}
+	opts = append(opts, saga.WithStateManager(&recordingStateManager{StateManager: sm, recorder: r}))
+	r.Saga = saga.New(opts...)
+	return r
+}
+
+// AddStep adds the given step to the saga, recording
+// the calls to its forward and compensation actions.
+func (r *Recorder) AddStep(step saga.Step) {
+	r.mu.Lock()
+	index := r.steps
+	r.steps++
+	r.mu.Unlock()
+	r.Saga.AddStep(&recordingStep{Step: step, index: index, recorder: r})
+}
+
+// AddCheckpoint adds a checkpoint to the saga.
+func (r *Recorder) AddCheckpoint(name string) {
+	r.mu.Lock()
+	r.steps++
+	r.mu.Unlock()
+	r.Saga.AddCheckpoint(name)
+}
+
+// Events returns the events recorded so far.
+func (r *Recorder) Events() []RecordedEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedEvent(nil), r.events...)
+}
+
+// Reset clears the events recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+// Golden compares the recorded events against the JSON golden file
+// with the given name in the testdata directory, failing the test
+// with a diff if they do not match. The golden file is created
+// if it does not exist.
+func (r *Recorder) Golden(t testing.TB, filename string) {
+	t.Helper()
+	path := filepath.Join("testdata", filename)
+	actual := r.marshal(t)
+	expected, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		r.writeGolden(t, path, actual)
+		return
+	}
+	require.NoError(t, err, "reading golden file %s", path)
+	require.Equal(t, string(expected), string(actual), "recorded events do not match golden file %s", path)
+}
+
+// UpdateGolden overwrites the JSON golden file with the given
+// name in the testdata directory with the recorded events.
+func (r *Recorder) UpdateGolden(t testing.TB, filename string) {
+	t.Helper()
+	r.writeGolden(t, filepath.Join("testdata", filename), r.marshal(t))
+}
+
+// marshal returns the recorded events as indented JSON.
+func (r *Recorder) marshal(t testing.TB) []byte {
+	t.Helper()
+	events := r.Events()
+	if events == nil {
+		events = []RecordedEvent{}
+	}
+	data, err := json.MarshalIndent(events, "", "  ")
+	require.NoError(t, err, "marshaling recorded events")
+	return append(data, '\n')
+}
+
+// writeGolden writes the given data to the golden file at the given path.
+func (r *Recorder) writeGolden(t testing.TB, path string, data []byte) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755), "creating golden file directory")
+	require.NoError(t, os.WriteFile(path, data, 0644), "writing golden file %s", path)
+}
+
+// record appends the given event to the recording.
+func (r *Recorder) record(e RecordedEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+}
+
+// recordingStep is a saga.Step that records
+// the calls to the actions of the step it wraps.
+type recordingStep struct {
+	saga.Step
+	index    int
+	recorder *Recorder
+}
+
+func (s *recordingStep) ExecuteForward(ctx context.Context) error {
+	err := s.Step.ExecuteForward(ctx)
+	s.recorder.record(newRecordedEvent(EventForward, s.Name(), s.index, err))
+	return err
+}
+
+func (s *recordingStep) ExecuteCompensate(ctx context.Context) error {
+	err := s.Step.ExecuteCompensate(ctx)
+	s.recorder.record(newRecordedEvent(EventCompensate, s.Name(), s.index, err))
+	return err
+}
+
+// Unwrap returns the wrapped step, so that the saga
+// discovers its capabilities (e.g. optional steps).
+func (s *recordingStep) Unwrap() saga.Step {
+	return s.Step
+}
+
+// recordingStateManager is a saga.StateManager that records
+// the state changes made to the state manager it wraps.
+type recordingStateManager struct {
+	saga.StateManager
+	recorder *Recorder
+}
+
+func (m *recordingStateManager) SetStepState(stepIndex int, success bool) error {
+	err := m.StateManager.SetStepState(stepIndex, success)
+	e := newRecordedEvent(EventState, "", stepIndex, err)
+	e.Success = &success
+	m.recorder.record(e)
+	return err
+}
+
+// newRecordedEvent creates a new RecordedEvent.
+func newRecordedEvent(eventType, stepName string, stepIndex int, err error) RecordedEvent {
+	e := RecordedEvent{
+		Type:      eventType,
+		StepName:  stepName,
+		StepIndex: stepIndex,
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	return e
+}
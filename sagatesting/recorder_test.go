@@ -0,0 +1,122 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package sagatesting
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tiagomelo/go-saga"
+)
+
+func noop(ctx context.Context) error {
+	return nil
+}
+
+// newOrderSaga creates a recorded saga whose
+// step with the given name fails, if any.
+func newOrderSaga(failingStep string) *Recorder {
+	r := NewRecorder(nil)
+	for _, name := range []string{"reserve", "charge", "ship"} {
+		forward := noop
+		if name == failingStep {
+			forward = func(ctx context.Context) error {
+				return errors.New(name + " failed")
+			}
+		}
+		r.AddStep(saga.NewStep(name, forward, noop))
+	}
+	return r
+}
+
+func TestRecorder_Golden(t *testing.T) {
+	testCases := []struct {
+		name        string
+		failingStep string
+		goldenFile  string
+	}{
+		{
+			name:       "successful saga",
+			goldenFile: "successful_saga.golden.json",
+		},
+		{
+			name:        "compensated saga",
+			failingStep: "ship",
+			goldenFile:  "compensated_saga.golden.json",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := newOrderSaga(tc.failingStep)
+			_ = r.Execute(context.Background())
+			r.Golden(t, tc.goldenFile)
+		})
+	}
+}
+
+func TestRecorder_GoldenMismatch(t *testing.T) {
+	r := newOrderSaga("charge")
+	_ = r.Execute(context.Background())
+	mockT := &mockTB{TB: t}
+	func() {
+		defer func() {
+			// require stops the test with runtime.Goexit,
+			// which is simulated by mockTB with a panic.
+			_ = recover()
+		}()
+		r.Golden(mockT, "successful_saga.golden.json")
+	}()
+	require.True(t, mockT.failed)
+	require.Contains(t, mockT.message, "recorded events do not match golden file")
+	require.Contains(t, mockT.message, "Diff:")
+}
+
+func TestRecorder_UpdateGolden(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	require.Nil(t, err)
+	require.Nil(t, os.Chdir(dir))
+	defer func() {
+		require.Nil(t, os.Chdir(wd))
+	}()
+
+	// the golden file is created if absent.
+	r := newOrderSaga("")
+	require.Nil(t, r.Execute(context.Background()))
+	r.Golden(t, "order.golden.json")
+	_, err = os.Stat(filepath.Join(dir, "testdata", "order.golden.json"))
+	require.Nil(t, err)
+
+	// and overwritten by UpdateGolden.
+	r = newOrderSaga("charge")
+	require.NotNil(t, r.Execute(context.Background()))
+	r.UpdateGolden(t, "order.golden.json")
+	r.Golden(t, "order.golden.json")
+}
+
+type mockTB struct {
+	testing.TB
+	failed  bool
+	message string
+}
+
+func (m *mockTB) Helper() {}
+
+func (m *mockTB) Errorf(format string, args ...any) {
+	m.failed = true
+	if len(args) > 0 {
+		if s, ok := args[0].(string); ok {
+			m.message = s
+		}
+	}
+}
+
+func (m *mockTB) FailNow() {
+	panic("FailNow")
+}
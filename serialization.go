@@ -0,0 +1,311 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// SerializationFormat is the encoding of a serialized Saga.
+type SerializationFormat int
+
+const (
+	// FormatJSON encodes the Saga as JSON.
+	FormatJSON SerializationFormat = iota
+	// FormatGob encodes the Saga with encoding/gob.
+	FormatGob
+	// FormatProto encodes the Saga as a Protocol Buffers
+	// message with the following schema:
+	//
+	//	message Saga {
+	//		string id = 1;
+	//		int64 current_step = 2;
+	//		repeated Step steps = 3;
+	//	}
+	//
+	//	message Step {
+	//		string name = 1;
+	//		bool completed = 2;
+	//		bool checkpoint = 3;
+	//		string type = 4;
+	//		bytes params = 5; // JSON-encoded
+	//	}
+	FormatProto
+)
+
+// Serializable is implemented by Sagas that can be serialized, to hand
+// off a partially executed Saga from one process to another, where it
+// is restored with Deserialize. Sagas created by New implement it.
+type Serializable interface {
+	Saga
+
+	// Serialize encodes the Saga ID, the current step, the name,
+	// type and parameters of the steps and the state of each of them
+	// in the given format. The type and parameters of a step are the
+	// ones it was built from by StepRegistry.Build; steps built
+	// otherwise are serialized with their name as type and without
+	// parameters. Parameters are encoded as JSON.
+	Serialize(format SerializationFormat) ([]byte, error)
+}
+
+// serializedSaga is the serialized form of a Saga.
+type serializedSaga struct {
+	ID          string           `json:"id"`
+	CurrentStep int              `json:"current_step"`
+	Steps       []serializedStep `json:"steps"`
+}
+
+// serializedStep is the serialized form of a step of a Saga.
+type serializedStep struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type,omitempty"`
+	Params     json.RawMessage `json:"params,omitempty"`
+	Completed  bool            `json:"completed,omitempty"`
+	Checkpoint bool            `json:"checkpoint,omitempty"`
+}
+
+func (s *saga) Serialize(format SerializationFormat) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ss := serializedSaga{
+		ID:          s.id,
+		CurrentStep: s.currentStep,
+		Steps:       make([]serializedStep, len(s.steps)),
+	}
+	for i, step := range s.steps {
+		if isCheckpoint(step) {
+			ss.Steps[i] = serializedStep{Name: step.Name(), Checkpoint: true}
+			continue
+		}
+		completed, err := s.declaredStepState(i, step)
+		if err != nil {
+			return nil, errors.Wrapf(err, "retrieving state for step %s", s.stepName(step))
+		}
+		sst, err := serializeStep(step)
+		if err != nil {
+			return nil, err
+		}
+		sst.Completed = completed
+		ss.Steps[i] = sst
+	}
+	data, err := encodeSaga(ss, format)
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding saga")
+	}
+	return data, nil
+}
+
+// serializeStep returns the serialized form of the given step,
+// without its state.
+func serializeStep(step Step) (serializedStep, error) {
+	rs, ok := stepAs[*registeredStep](step)
+	if !ok {
+		return serializedStep{Name: step.Name(), Type: step.Name()}, nil
+	}
+	sst := serializedStep{Name: step.Name(), Type: rs.stepType}
+	if len(rs.params) > 0 {
+		params, err := json.Marshal(rs.params)
+		if err != nil {
+			return serializedStep{}, errors.Wrapf(err, "encoding params of step %s", step.Name())
+		}
+		sst.Params = params
+	}
+	return sst, nil
+}
+
+// Deserialize restores a Saga serialized with Serializable.Serialize,
+// in the given format. Each step is rebuilt with the factory registered
+// in the given registry under the step type, from the step name and
+// parameters (decoded from JSON), and the state of the
+// completed steps is restored into the state manager of the Saga, so
+// that executing it resumes from the first step that did not complete.
+// The saga ID is applied before the provided options.
+func Deserialize(data []byte, format SerializationFormat, registry *StepRegistry, opts ...Option) (Saga, error) {
+	if registry == nil {
+		return nil, errors.New("step registry is required")
+	}
+	ss, err := decodeSaga(data, format)
+	if err != nil {
+		return nil, errors.Wrap(err, "decoding saga")
+	}
+	s := new(append([]Option{WithSagaID(ss.ID)}, opts...)).(*saga)
+	for i, sst := range ss.Steps {
+		if sst.Checkpoint {
+			s.AddCheckpoint(sst.Name)
+			continue
+		}
+		var params map[string]any
+		if len(sst.Params) > 0 {
+			if err := json.Unmarshal(sst.Params, &params); err != nil {
+				return nil, errors.Wrapf(err, "decoding params of step %d", i)
+			}
+		}
+		step, err := registry.Build(sst.Type, sst.Name, params)
+		if err != nil {
+			return nil, errors.Wrapf(err, "restoring step %d", i)
+		}
+		s.AddStep(step)
+		if !sst.Completed {
+			continue
+		}
+		if err := s.setDeclaredStepState(i, step, true); err != nil {
+			return nil, errors.Wrapf(err, "restoring state for step %s", s.stepName(step))
+		}
+	}
+	s.currentStep = ss.CurrentStep
+	return s, nil
+}
+
+// encodeSaga encodes the given serialized Saga in the given format.
+func encodeSaga(ss serializedSaga, format SerializationFormat) ([]byte, error) {
+	switch format {
+	case FormatJSON:
+		return json.Marshal(ss)
+	case FormatGob:
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(ss); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatProto:
+		return encodeSagaProto(ss), nil
+	}
+	return nil, errors.Errorf("unsupported serialization format %d", format)
+}
+
+// decodeSaga decodes a serialized Saga in the given format.
+func decodeSaga(data []byte, format SerializationFormat) (serializedSaga, error) {
+	var ss serializedSaga
+	switch format {
+	case FormatJSON:
+		err := json.Unmarshal(data, &ss)
+		return ss, err
+	case FormatGob:
+		err := gob.NewDecoder(bytes.NewReader(data)).Decode(&ss)
+		return ss, err
+	case FormatProto:
+		return decodeSagaProto(data)
+	}
+	return ss, errors.Errorf("unsupported serialization format %d", format)
+}
+
+// encodeSagaProto encodes the given serialized Saga as
+// a Protocol Buffers message (see FormatProto).
+func encodeSagaProto(ss serializedSaga) []byte {
+	var b []byte
+	b = protowire.AppendTag(b, 1, protowire.BytesType)
+	b = protowire.AppendString(b, ss.ID)
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(ss.CurrentStep))
+	for _, st := range ss.Steps {
+		var sb []byte
+		sb = protowire.AppendTag(sb, 1, protowire.BytesType)
+		sb = protowire.AppendString(sb, st.Name)
+		sb = protowire.AppendTag(sb, 2, protowire.VarintType)
+		sb = protowire.AppendVarint(sb, protowire.EncodeBool(st.Completed))
+		sb = protowire.AppendTag(sb, 3, protowire.VarintType)
+		sb = protowire.AppendVarint(sb, protowire.EncodeBool(st.Checkpoint))
+		sb = protowire.AppendTag(sb, 4, protowire.BytesType)
+		sb = protowire.AppendString(sb, st.Type)
+		sb = protowire.AppendTag(sb, 5, protowire.BytesType)
+		sb = protowire.AppendBytes(sb, st.Params)
+		b = protowire.AppendTag(b, 3, protowire.BytesType)
+		b = protowire.AppendBytes(b, sb)
+	}
+	return b
+}
+
+// decodeSagaProto decodes a serialized Saga encoded
+// as a Protocol Buffers message (see FormatProto).
+func decodeSagaProto(data []byte) (serializedSaga, error) {
+	var ss serializedSaga
+	err := consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			ss.ID = v
+			return n, nil
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			ss.CurrentStep = int(v)
+			return n, nil
+		case num == 3 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if n < 0 {
+				return n, nil
+			}
+			st, err := decodeStepProto(v)
+			if err != nil {
+				return 0, err
+			}
+			ss.Steps = append(ss.Steps, st)
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return ss, err
+}
+
+// decodeStepProto decodes a serialized step encoded
+// as a Protocol Buffers message (see FormatProto).
+func decodeStepProto(data []byte) (serializedStep, error) {
+	var st serializedStep
+	err := consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, b []byte) (int, error) {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			st.Name = v
+			return n, nil
+		case num == 2 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			st.Completed = protowire.DecodeBool(v)
+			return n, nil
+		case num == 3 && typ == protowire.VarintType:
+			v, n := protowire.ConsumeVarint(b)
+			st.Checkpoint = protowire.DecodeBool(v)
+			return n, nil
+		case num == 4 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeString(b)
+			st.Type = v
+			return n, nil
+		case num == 5 && typ == protowire.BytesType:
+			v, n := protowire.ConsumeBytes(b)
+			if len(v) > 0 {
+				st.Params = append(json.RawMessage(nil), v...)
+			}
+			return n, nil
+		}
+		return protowire.ConsumeFieldValue(num, typ, b), nil
+	})
+	return st, err
+}
+
+// consumeProtoFields calls consume for each field of the given
+// Protocol Buffers message, with the bytes following the field tag.
+// consume returns the length of the field value, or a negative
+// protowire error code.
+func consumeProtoFields(data []byte, consume func(num protowire.Number, typ protowire.Type, b []byte) (int, error)) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+		n, err := consume(num, typ, data)
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		data = data[n:]
+	}
+	return nil
+}
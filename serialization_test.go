@@ -0,0 +1,184 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// handoffProcess simulates a process running a Saga, with
+// its own registry and record of the actions it ran.
+type handoffProcess struct {
+	registry *StepRegistry
+	ran      []string
+}
+
+func newHandoffProcess(t *testing.T, failing string) *handoffProcess {
+	p := &handoffProcess{registry: NewStepRegistry()}
+	for _, name := range []string{"reserve", "charge", "ship"} {
+		name := name
+		err := p.registry.Register(name, func(stepName string, params map[string]any) (Step, error) {
+			return NewStep(stepName,
+				func(ctx context.Context) error {
+					p.ran = append(p.ran, stepName)
+					if stepName == failing {
+						return errors.New(stepName + " error")
+					}
+					return nil
+				},
+				func(ctx context.Context) error {
+					p.ran = append(p.ran, "undo "+stepName)
+					return nil
+				},
+			), nil
+		})
+		require.Nil(t, err)
+	}
+	return p
+}
+
+// start builds a Saga in the process and executes it until its step
+// named failing fails, leaving the compensation to the caller.
+func (p *handoffProcess) start(t *testing.T) Saga {
+	s := New(WithSagaID("order-42"), WithLazyCompensation())
+	for _, name := range []string{"reserve", "charge"} {
+		step, err := p.registry.Build(name, name, nil)
+		require.Nil(t, err)
+		s.AddStep(step)
+	}
+	s.AddCheckpoint("paid")
+	step, err := p.registry.Build("ship", "ship", nil)
+	require.Nil(t, err)
+	s.AddStep(step)
+	require.NotNil(t, s.Execute(context.Background()))
+	return s
+}
+
+func TestSerialize_Handoff(t *testing.T) {
+	formats := []struct {
+		name   string
+		format SerializationFormat
+	}{
+		{name: "json", format: FormatJSON},
+		{name: "gob", format: FormatGob},
+		{name: "proto", format: FormatProto},
+	}
+	for _, f := range formats {
+		t.Run(f.name+" resume", func(t *testing.T) {
+			first := newHandoffProcess(t, "ship")
+			s := first.start(t)
+			require.Equal(t, []string{"reserve", "charge", "ship"}, first.ran)
+			data, err := s.(Serializable).Serialize(f.format)
+			require.Nil(t, err)
+
+			second := newHandoffProcess(t, "")
+			restored, err := Deserialize(data, f.format, second.registry)
+			require.Nil(t, err)
+			require.Equal(t, "order-42", restored.ID())
+			require.Nil(t, restored.Execute(context.Background()))
+			require.Equal(t, []string{"ship"}, second.ran)
+		})
+		t.Run(f.name+" compensate", func(t *testing.T) {
+			first := newHandoffProcess(t, "charge")
+			s := first.start(t)
+			require.Equal(t, []string{"reserve", "charge"}, first.ran)
+			data, err := s.(Serializable).Serialize(f.format)
+			require.Nil(t, err)
+
+			second := newHandoffProcess(t, "")
+			restored, err := Deserialize(data, f.format, second.registry)
+			require.Nil(t, err)
+			require.Nil(t, restored.Compensate(context.Background()))
+			require.Equal(t, []string{"undo charge", "undo reserve"}, second.ran)
+		})
+	}
+}
+
+func TestSerialize_StepTypeAndParams(t *testing.T) {
+	formats := []struct {
+		name   string
+		format SerializationFormat
+	}{
+		{name: "json", format: FormatJSON},
+		{name: "gob", format: FormatGob},
+		{name: "proto", format: FormatProto},
+	}
+	for _, f := range formats {
+		t.Run(f.name, func(t *testing.T) {
+			var built []string
+			registry := NewStepRegistry()
+			err := registry.Register("ReserveInventory", func(name string, params map[string]any) (Step, error) {
+				built = append(built, name+":"+params["sku"].(string))
+				return NewStep(name, noop, noop), nil
+			}, "sku")
+			require.Nil(t, err)
+
+			s := New(WithSagaID("order-42"))
+			step, err := registry.Build("ReserveInventory", "reserve", map[string]any{"sku": "ABC-123"})
+			require.Nil(t, err)
+			s.AddStep(step)
+			data, err := s.(Serializable).Serialize(f.format)
+			require.Nil(t, err)
+
+			restored, err := Deserialize(data, f.format, registry)
+			require.Nil(t, err)
+			require.Nil(t, restored.Execute(context.Background()))
+			require.Equal(t, []string{"reserve:ABC-123", "reserve:ABC-123"}, built)
+		})
+	}
+}
+
+func TestDeserialize_Errors(t *testing.T) {
+	s := New(WithSagaID("order-42"))
+	s.AddStep(NewStep("unknown", noop, noop))
+	data, err := s.(Serializable).Serialize(FormatJSON)
+	require.Nil(t, err)
+
+	testCases := []struct {
+		name          string
+		data          []byte
+		format        SerializationFormat
+		registry      *StepRegistry
+		expectedError string
+	}{
+		{
+			name:          "no registry",
+			data:          data,
+			format:        FormatJSON,
+			expectedError: "step registry is required",
+		},
+		{
+			name:          "unsupported format",
+			data:          data,
+			format:        SerializationFormat(42),
+			registry:      NewStepRegistry(),
+			expectedError: "decoding saga: unsupported serialization format 42",
+		},
+		{
+			name:          "malformed data",
+			data:          []byte("{"),
+			format:        FormatJSON,
+			registry:      NewStepRegistry(),
+			expectedError: "decoding saga: unexpected end of JSON input",
+		},
+		{
+			name:          "unregistered step",
+			data:          data,
+			format:        FormatJSON,
+			registry:      NewStepRegistry(),
+			expectedError: `restoring step 0: unknown step type "unknown"`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Deserialize(tc.data, tc.format, tc.registry)
+			require.EqualError(t, err, tc.expectedError)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/pkg/errors"
+)
+
+// WithStateDiff option verifies that the step's compensation returns
+// the system to its state before the forward action. The state is
+// captured by capture before the forward action and after the
+// compensation, and both snapshots are passed to compare. If compare
+// reports that they differ, the compensation returns a
+// *CompensationIncompleteError. This is primarily a testing tool.
+// See JSONDiffComparator for a built-in comparator.
+func WithStateDiff(capture func(ctx context.Context) ([]byte, error), compare func(before, after []byte) (bool, error)) StepOption {
+	return func(s *step) {
+		s.stateDiffCapture = capture
+		s.stateDiffCompare = compare
+	}
+}
+
+// JSONDiffComparator returns a comparator, to be used with WithStateDiff,
+// that decodes both snapshots as JSON and reports whether they are
+// semantically equal (e.g. regardless of the order of object keys),
+// using go-cmp with the given options.
+func JSONDiffComparator(opts ...cmp.Option) func(before, after []byte) (bool, error) {
+	return func(before, after []byte) (bool, error) {
+		var b, a any
+		if err := json.Unmarshal(before, &b); err != nil {
+			return false, errors.Wrap(err, "decoding state before forward action")
+		}
+		if err := json.Unmarshal(after, &a); err != nil {
+			return false, errors.Wrap(err, "decoding state after compensation")
+		}
+		return cmp.Equal(b, a, opts...), nil
+	}
+}
+
+// captureStateDiffBefore captures the state before the
+// forward action, if state diffing is configured.
+func (s *step) captureStateDiffBefore(ctx context.Context) error {
+	if s.stateDiffCapture == nil || s.stateDiffCompare == nil {
+		return nil
+	}
+	before, err := s.stateDiffCapture(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "capturing state before step %s", s.name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stateDiffBefore = before
+	return nil
+}
+
+// verifyStateDiff captures the state after the compensation action and
+// compares it with the state captured before the forward action, if
+// state diffing is configured.
+func (s *step) verifyStateDiff(ctx context.Context) error {
+	if s.stateDiffCapture == nil || s.stateDiffCompare == nil {
+		return nil
+	}
+	after, err := s.stateDiffCapture(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "capturing state after compensating step %s", s.name)
+	}
+	s.mu.Lock()
+	before := s.stateDiffBefore
+	s.mu.Unlock()
+	equal, err := s.stateDiffCompare(before, after)
+	if err != nil {
+		return errors.Wrapf(err, "comparing state of step %s", s.name)
+	}
+	if !equal {
+		return &CompensationIncompleteError{StepName: s.name, Before: before, After: after}
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStateDiff(t *testing.T) {
+	testCases := []struct {
+		name          string
+		compensate    func(state *string) func(ctx context.Context) error
+		expectedError string
+	}{
+		{
+			name: "state restored",
+			compensate: func(state *string) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					*state = `{"stock": 10, "sku": "ABC-123"}`
+					return nil
+				}
+			},
+		},
+		{
+			name: "state not restored",
+			compensate: func(state *string) func(ctx context.Context) error {
+				return noop
+			},
+			expectedError: "compensation of step step1 did not restore its state",
+		},
+		{
+			name: "invalid state",
+			compensate: func(state *string) func(ctx context.Context) error {
+				return func(ctx context.Context) error {
+					*state = "corrupted"
+					return nil
+				}
+			},
+			expectedError: "comparing state of step step1: decoding state after compensation",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := `{"sku": "ABC-123", "stock": 10}`
+			step := NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					state = `{"sku": "ABC-123", "stock": 9}`
+					return nil
+				},
+				tc.compensate(&state),
+				WithStateDiff(func(ctx context.Context) ([]byte, error) {
+					return []byte(state), nil
+				}, JSONDiffComparator()),
+			)
+			require.Nil(t, step.ExecuteForward(context.Background()))
+			err := step.ExecuteCompensate(context.Background())
+			if tc.expectedError == "" {
+				require.Nil(t, err)
+				return
+			}
+			require.NotNil(t, err)
+			require.Contains(t, err.Error(), tc.expectedError)
+		})
+	}
+}
+
+func TestWithStateDiff_Incomplete(t *testing.T) {
+	state := "before"
+	step := NewStepWithOptions("step1",
+		func(ctx context.Context) error {
+			state = "after"
+			return nil
+		},
+		noop,
+		WithStateDiff(func(ctx context.Context) ([]byte, error) {
+			return []byte(state), nil
+		}, func(before, after []byte) (bool, error) {
+			return string(before) == string(after), nil
+		}),
+	)
+	require.Nil(t, step.ExecuteForward(context.Background()))
+	err := step.ExecuteCompensate(context.Background())
+	var incompleteErr *CompensationIncompleteError
+	require.True(t, errors.As(err, &incompleteErr))
+	require.Equal(t, "before", string(incompleteErr.Before))
+	require.Equal(t, "after", string(incompleteErr.After))
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"strconv"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// StateKeyStrategy computes the key under which the state of a step is
+// stored (see WithStateKeyStrategy).
+type StateKeyStrategy interface {
+	// KeyFor returns the storage key of the step added at the given
+	// index, with the given name (or alias, see WithStepAlias).
+	KeyFor(stepIndex int, stepName string) string
+}
+
+// WithStateKeyStrategy option makes the Saga store the state of each
+// step under the key computed by the given strategy. Like
+// WithStepNameResolver, it requires a StateManager implementing
+// NamedStateManager: with others, the Saga fails to execute. Migrating
+// a custom StateManager to string keys only takes implementing
+// NamedStateManager along with it. Combined with
+// WithStepNameResolver, the strategy receives the resolved step names.
+// See IndexKeyStrategy, NameKeyStrategy and UUIDKeyStrategy.
+func WithStateKeyStrategy(strategy StateKeyStrategy) Option {
+	return func(s *saga) {
+		s.stateKeyStrategy = strategy
+	}
+}
+
+// IndexKeyStrategy is a StateKeyStrategy keying the state of the steps
+// by their index, formatted as a decimal string, as StateManager does.
+type IndexKeyStrategy struct{}
+
+func (IndexKeyStrategy) KeyFor(stepIndex int, stepName string) string {
+	return strconv.Itoa(stepIndex)
+}
+
+// NameKeyStrategy is a StateKeyStrategy keying
+// the state of the steps by their name.
+type NameKeyStrategy struct{}
+
+func (NameKeyStrategy) KeyFor(stepIndex int, stepName string) string {
+	return stepName
+}
+
+// UUIDKeyStrategy is a StateKeyStrategy keying the state of each step by
+// a random UUID, generated when the strategy is created and kept for its
+// lifetime, e.g. so that keys reveal nothing about the steps. Since the
+// keys are not persisted, state can only be shared by the Sagas sharing
+// the same strategy. It is safe for concurrent use.
+type UUIDKeyStrategy struct {
+	keys []string
+}
+
+// NewUUIDKeyStrategy creates a new UUIDKeyStrategy generating the keys
+// of the given number of steps, checkpoints included. A Saga with more
+// steps fails to execute.
+func NewUUIDKeyStrategy(steps int) *UUIDKeyStrategy {
+	keys := make([]string, steps)
+	for i := range keys {
+		keys[i] = uuid.NewString()
+	}
+	return &UUIDKeyStrategy{keys: keys}
+}
+
+// KeyFor returns the key generated for the step at the given index,
+// or an empty string if the strategy has no key for it.
+func (u *UUIDKeyStrategy) KeyFor(stepIndex int, stepName string) string {
+	if stepIndex < 0 || stepIndex >= len(u.keys) {
+		return ""
+	}
+	return u.keys[stepIndex]
+}
+
+// checkStateKeyStrategy returns an error if the state key
+// strategy of the Saga has no key for some of its steps.
+func (s *saga) checkStateKeyStrategy() error {
+	u, ok := s.stateKeyStrategy.(*UUIDKeyStrategy)
+	if !ok || len(u.keys) >= len(s.steps) {
+		return nil
+	}
+	return errors.Errorf("state key strategy has keys for %d steps, but the saga has %d", len(u.keys), len(s.steps))
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStateKeyStrategy(t *testing.T) {
+	uuids := NewUUIDKeyStrategy(2)
+	testCases := []struct {
+		name         string
+		options      []Option
+		expectedKeys []string
+	}{
+		{
+			name:         "index keys",
+			options:      []Option{WithStateKeyStrategy(IndexKeyStrategy{})},
+			expectedKeys: []string{"0", "1"},
+		},
+		{
+			name:         "name keys",
+			options:      []Option{WithStateKeyStrategy(NameKeyStrategy{})},
+			expectedKeys: []string{"reserve", "charge"},
+		},
+		{
+			name: "name keys with resolver",
+			options: []Option{
+				WithStateKeyStrategy(NameKeyStrategy{}),
+				WithStepNameResolver(PrefixResolver("orders.")),
+			},
+			expectedKeys: []string{"orders.reserve", "orders.charge"},
+		},
+		{
+			name:         "uuid keys",
+			options:      []Option{WithStateKeyStrategy(uuids)},
+			expectedKeys: []string{uuids.KeyFor(0, "reserve"), uuids.KeyFor(1, "charge")},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sm := NewInMemoryStateManager()
+			saga := New(append([]Option{WithStateManager(sm)}, tc.options...)...)
+			saga.AddStep(NewStep("reserve", noop, noop))
+			saga.AddStep(NewStep("charge", noop, noop))
+			require.Nil(t, saga.Execute(context.Background()))
+			require.Len(t, sm.namedState, len(tc.expectedKeys))
+			for _, key := range tc.expectedKeys {
+				completed, err := sm.NamedStepState(key)
+				require.Nil(t, err)
+				require.True(t, completed, key)
+			}
+			require.Empty(t, sm.state)
+		})
+	}
+}
+
+func TestWithStateKeyStrategy_Errors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       []Option
+		expectedError string
+	}{
+		{
+			name:          "state manager without named state",
+			options:       []Option{WithStateKeyStrategy(NameKeyStrategy{}), WithStateManager(&mockStateManager{})},
+			expectedError: "keying step states requires a NamedStateManager (see WithStateKeyStrategy)",
+		},
+		{
+			name:          "missing uuid keys",
+			options:       []Option{WithStateKeyStrategy(NewUUIDKeyStrategy(1))},
+			expectedError: "state key strategy has keys for 1 steps, but the saga has 2",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			saga := New(tc.options...)
+			saga.AddStep(NewStep("reserve", noop, noop))
+			saga.AddStep(NewStep("charge", noop, noop))
+			require.EqualError(t, saga.Execute(context.Background()), tc.expectedError)
+		})
+	}
+}
+
+func TestUUIDKeyStrategy(t *testing.T) {
+	s := NewUUIDKeyStrategy(2)
+	key := s.KeyFor(0, "reserve")
+	_, err := uuid.Parse(key)
+	require.Nil(t, err)
+	require.Equal(t, key, s.KeyFor(0, "reserve"))
+	require.NotEqual(t, key, s.KeyFor(1, "charge"))
+	require.Empty(t, s.KeyFor(2, "ship"))
+}
@@ -20,3 +20,138 @@ type StateManager interface {
 	// false otherwise, and any error encountered during retrieval.
 	StepState(stepIndex int) (bool, error)
 }
+
+// VersionedStateManager is optionally implemented by StateManagers
+// that guard the state of each step with a version number, so that
+// concurrent runners of the same Saga do not overwrite each other's
+// state. The Saga uses it instead of SetStepState when available.
+type VersionedStateManager interface {
+	StateManager
+
+	// SetStepStateVersioned records the completion state of a specific
+	// step in the Saga, if its current version is expectedVersion.
+	// It returns the new version, or a *ConcurrentModificationError
+	// if the current version does not match.
+	SetStepStateVersioned(stepIndex int, success bool, expectedVersion int) (newVersion int, err error)
+
+	// StepStateWithVersion retrieves the completion state of a specific
+	// step in the Saga along with its version. The version of a step
+	// without state is 0.
+	StepStateWithVersion(stepIndex int) (success bool, version int, err error)
+}
+
+// NamedStateManager is optionally implemented by StateManagers that can
+// store the state of steps by key instead of index. The Saga uses it
+// when a StepNameResolver is configured (see WithStepNameResolver).
+type NamedStateManager interface {
+	// SetNamedStepState records the completion state
+	// of the step stored under the given key.
+	SetNamedStepState(key string, success bool) error
+
+	// NamedStepState retrieves the completion state
+	// of the step stored under the given key.
+	NamedStepState(key string) (bool, error)
+}
+
+// NamedVersionedStateManager is optionally implemented by
+// NamedStateManagers that guard the state of each step with a version
+// number, like VersionedStateManager does for the states stored by index.
+type NamedVersionedStateManager interface {
+	NamedStateManager
+
+	// SetNamedStepStateVersioned records the completion state of the
+	// step stored under the given key, if its current version is
+	// expectedVersion. It returns the new version, or a
+	// *ConcurrentModificationError if the current version does not match.
+	SetNamedStepStateVersioned(key string, success bool, expectedVersion int) (newVersion int, err error)
+
+	// NamedStepStateWithVersion retrieves the completion state of the
+	// step stored under the given key along with its version. The
+	// version of a step without state is 0.
+	NamedStepStateWithVersion(key string) (success bool, version int, err error)
+}
+
+// StepMetadataManager is optionally implemented by StateManagers that
+// persist step metadata, so that external monitoring tools can read
+// step-specific metadata from the state store.
+type StepMetadataManager interface {
+	// SetStepMetadata records the metadata of a specific step in the Saga.
+	SetStepMetadata(stepIndex int, metadata map[string]string) error
+
+	// GetStepMetadata retrieves the metadata of a specific step in the Saga.
+	// It returns nil if no metadata was recorded for the step.
+	GetStepMetadata(stepIndex int) (map[string]string, error)
+}
+
+// StepOutputManager is optionally implemented by StateManagers that
+// persist step outputs (see WithOutputCapture), so that subsequent steps
+// can load them and pick up where they left off, even after a restart.
+type StepOutputManager interface {
+	// SetStepOutput records the output of a specific step in the Saga.
+	SetStepOutput(stepIndex int, data []byte) error
+
+	// GetStepOutput retrieves the output of a specific step in the Saga.
+	// It returns nil if no output was recorded for the step.
+	GetStepOutput(stepIndex int) ([]byte, error)
+}
+
+// MetadataProvider is implemented by steps that carry metadata.
+// The metadata is persisted before the forward action of the step
+// runs, if the StateManager of the Saga is a StepMetadataManager.
+type MetadataProvider interface {
+	// Metadata returns the metadata of the step, or nil if none.
+	Metadata() map[string]string
+}
+
+// StepStateManager defines the interface for managing the state
+// of a single step. It is a simplified version of StateManager
+// without the step index, since it is bound to the step itself.
+type StepStateManager interface {
+	// SetCompleted records that the step completed successfully.
+	SetCompleted() error
+
+	// IsCompleted reports whether the step was successfully completed.
+	IsCompleted() (bool, error)
+}
+
+// StepStateOverride is implemented by steps that manage their own
+// state, overriding the Saga-level StateManager.
+type StepStateOverride interface {
+	// StepStateManager returns the state manager of the step,
+	// or nil if the step uses the Saga-level StateManager.
+	StepStateManager() StepStateManager
+}
+
+// stepStateManager returns the state manager of the given step,
+// or nil if the step does not override the Saga-level StateManager.
+func stepStateManager(step Step) StepStateManager {
+	if o, ok := stepAs[StepStateOverride](step); ok {
+		return o.StepStateManager()
+	}
+	return nil
+}
+
+// stateManagerWrapper is implemented by StateManager decorators, which
+// only support the optional StateManager interfaces (e.g.
+// NamedStateManager) that the StateManager they wrap supports.
+type stateManagerWrapper interface {
+	// unwrapStateManager returns the wrapped StateManager.
+	unwrapStateManager() StateManager
+}
+
+// stateManagerAs returns the given StateManager as a T, if it
+// implements T and, when it is a decorator, the StateManagers
+// it wraps support T.
+func stateManagerAs[T any](sm StateManager) (T, bool) {
+	t, ok := sm.(T)
+	if !ok {
+		return t, false
+	}
+	if w, ok := sm.(stateManagerWrapper); ok {
+		if _, ok := stateManagerAs[T](w.unwrapStateManager()); !ok {
+			var zero T
+			return zero, false
+		}
+	}
+	return t, true
+}
@@ -4,7 +4,15 @@
 
 package saga
 
-import "context"
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
 
 // Step defines the interface for a step in the Saga pattern.
 type Step interface {
@@ -23,31 +31,563 @@ type Step interface {
 	Name() string
 }
 
+// unwrapper is implemented by steps that wrap another step.
+type unwrapper interface {
+	Unwrap() Step
+}
+
+// stepAs looks for the first step implementing T in the chain of
+// steps wrapped by the given step, starting with the step itself.
+func stepAs[T any](step Step) (T, bool) {
+	for {
+		if t, ok := step.(T); ok {
+			return t, true
+		}
+		u, ok := step.(unwrapper)
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		step = u.Unwrap()
+	}
+}
+
+// OverridableStep is implemented by steps whose actions can be
+// overridden, so that tests can inject pre-canned results without
+// changing production code. Steps created by NewStep implement it.
+// By default, an override applies to the next call of the action
+// only; see WithPersistentOverride.
+type OverridableStep interface {
+	Step
+
+	// OverrideForward overrides the forward action of the step.
+	OverrideForward(fn func(ctx context.Context) error)
+
+	// OverrideCompensate overrides the compensation action of the step.
+	OverrideCompensate(fn func(ctx context.Context) error)
+}
+
+// WeightedStep is implemented by steps that carry a weight
+// used to compute the Saga's progress percentage.
+type WeightedStep interface {
+	// Weight returns the weight of the step and whether
+	// it was explicitly set.
+	Weight() (float64, bool)
+}
+
+// ConditionalCompensationStep is implemented by steps whose
+// compensation depends on the error that caused the Saga to fail.
+type ConditionalCompensationStep interface {
+	// ShouldCompensate reports whether the step must be compensated
+	// given the forward error that triggered the compensation.
+	ShouldCompensate(forwardErr error) bool
+}
+
+// shouldCompensate reports whether the given step must be compensated
+// given the forward error that triggered the compensation.
+func shouldCompensate(step Step, forwardErr error) bool {
+	if c, ok := stepAs[ConditionalCompensationStep](step); ok {
+		return c.ShouldCompensate(forwardErr)
+	}
+	return true
+}
+
+// AliasedStep is implemented by steps that can be given an alias,
+// a stable key used instead of the step name to look up the state
+// of the step, so that renaming a step does not break the state
+// persisted under its old name.
+type AliasedStep interface {
+	// StepAlias returns the alias of the step,
+	// or an empty string if it has none.
+	StepAlias() string
+}
+
+// stepKey returns the key used to look up the state
+// of the given step: its alias if any, its name otherwise.
+func stepKey(step Step) string {
+	if a, ok := stepAs[AliasedStep](step); ok {
+		if alias := a.StepAlias(); alias != "" {
+			return alias
+		}
+	}
+	return step.Name()
+}
+
+// compensationErrorHandlingStep is implemented by steps
+// that handle the errors of their compensation action.
+type compensationErrorHandlingStep interface {
+	// handleCompensationError returns the error to report for the
+	// given compensation error, or nil if it must be ignored.
+	handleCompensationError(ctx context.Context, err error) error
+}
+
+// handleCompensationError returns the error to report
+// for the given compensation error of the given step.
+func handleCompensationError(ctx context.Context, step Step, err error) error {
+	if h, ok := stepAs[compensationErrorHandlingStep](step); ok {
+		return h.handleCompensationError(ctx, err)
+	}
+	return err
+}
+
+// panicHandlingStep is implemented by steps
+// that handle the panics of their actions.
+type panicHandlingStep interface {
+	// hasPanicHandler reports whether the step has a panic handler.
+	hasPanicHandler() bool
+
+	// handlePanic returns the error to report for the given
+	// recovered panic, or nil if the action must be treated
+	// as successful.
+	handlePanic(ctx context.Context, recovered any, stack []byte) error
+}
+
+// compensationVerifyingStep is implemented by steps that verify
+// that their compensation had the intended effect.
+type compensationVerifyingStep interface {
+	// verifyCompensation returns an error if the
+	// compensation did not have the intended effect.
+	verifyCompensation(ctx context.Context) error
+}
+
+// ioLoggingStep is implemented by steps that extract
+// their input and output for logging purposes.
+type ioLoggingStep interface {
+	// logInput returns the input of the step, or nil if none.
+	logInput(ctx context.Context) map[string]any
+
+	// logOutput returns the output of the step, or nil if none.
+	logOutput(ctx context.Context, err error) map[string]any
+}
+
+// outputCapturingStep is implemented by steps that
+// capture their output after a successful execution.
+type outputCapturingStep interface {
+	// captureOutput returns the serialized output of the step,
+	// or nil if the step does not capture its output.
+	captureOutput(ctx context.Context) ([]byte, error)
+}
+
 // step is the concrete implementation of the Step interface.
 type step struct {
 	name       string
+	alias      string
 	forward    func(ctx context.Context) error
 	compensate func(ctx context.Context) error
+	weight     float64
+	hasWeight  bool
+	optional   bool
+	stateMgr   StepStateManager
+
+	compensationTimeout       time.Duration
+	forwardTimeout            time.Duration
+	progressiveTimeout        time.Duration
+	progressExtender          func(ctx context.Context) bool
+	timeBudget                time.Duration
+	budgetExhausted           func(ctx context.Context, remaining time.Duration) error
+	compensationMode          CompensationMode
+	compensationCondition     func(forwardErr error) bool
+	compensationErrorHandler  func(ctx context.Context, stepName string, err error) error
+	inputExtractor            func(ctx context.Context) map[string]any
+	outputExtractor           func(ctx context.Context, err error) map[string]any
+	inputValidator            func(ctx context.Context) error
+	postcondition             func(ctx context.Context) error
+	compensationVerifier      func(ctx context.Context) error
+	maxAttempts               int
+	retryDelay                time.Duration
+	retryMaxElapsed           time.Duration
+	retryMultiplier           float64
+	backoffPolicy             BackoffPolicy
+	forwardCorrelationIDGen   func() string
+	compensationGroup         string
+	retryResetTimeout         bool
+	limiter                   *rate.Limiter
+	retryNotify               func(ctx context.Context, stepName string, attempt int, err error, nextDelay time.Duration)
+	errorClassifier           func(err error) bool
+	attemptErrorClassifier    func(attempt int, err error) bool
+	errorCategorizer          func(err error) ErrorCategory
+	stepErrorSanitizer        func(err error) error
+	lastAttempts              int
+	lastForwardCorrelationIDs []string
+	preDelay                  time.Duration
+	postDelay                 time.Duration
+	delayCompensation         bool
+	metadata                  map[string]string
+	tags                      []string
+	resourceTag               string
+	outputCapture             func(ctx context.Context) ([]byte, error)
+	outputValueCapture        func(ctx context.Context) (any, error)
+	outputSerializer          StepOutputSerializer
+	atMostOnce                bool
+	attemptStore              AttemptStore
+	sharedAttemptStore        bool
+	idempotencyKey            string
+	idempotencyStore          IdempotencyStore
+	idempotencyTTL            time.Duration
+	locker                    StepLocker
+	w3cTracePropagation       bool
+	forwardOverride           func(ctx context.Context) error
+	compensateOverride        func(ctx context.Context) error
+	persistentOverride        bool
+	panicHandler              func(ctx context.Context, stepName string, recovered any, stack []byte) error
+	clock                     Clock
+	beforeForward             []func(ctx context.Context, stepName string) error
+	afterForward              []func(ctx context.Context, stepName string, err error) error
+	beforeCompensate          []func(ctx context.Context, stepName string) error
+	afterCompensate           []func(ctx context.Context, stepName string, err error) error
+	errorObservers            []func(ctx context.Context, stepName string, err error)
+	errorLogLevel             func(err error) slog.Level
+
+	stateCapture     func(ctx context.Context) (map[string]any, error)
+	mutationVerifier func(ctx context.Context, beforeState, afterState map[string]any) error
+	stateBefore      map[string]any
+	stateDiffCapture func(ctx context.Context) ([]byte, error)
+	stateDiffCompare func(before, after []byte) (bool, error)
+	stateDiffBefore  []byte
+	mu               sync.Mutex
+	retryNotifyMu    sync.Mutex
 }
 
 // NewStep creates a new Step instance with the provided name,
 // forward action, and compensation action.
 func NewStep(name string, forward, compensate func(ctx context.Context) error) Step {
-	return &step{
+	return NewStepWithOptions(name, forward, compensate)
+}
+
+// NewStepWithOptions creates a new Step instance with the provided name,
+// forward action, compensation action and step options.
+func NewStepWithOptions(name string, forward, compensate func(ctx context.Context) error, opts ...StepOption) Step {
+	return newStep(name, forward, compensate, opts)
+}
+
+// newStep creates a new step instance with the given options.
+func newStep(name string, forward, compensate func(ctx context.Context) error, opts []StepOption) *step {
+	s := &step{
 		name:       name,
 		forward:    forward,
 		compensate: compensate,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.atMostOnce && s.attemptStore == nil {
+		s.attemptStore = NewInMemoryAttemptStore()
+	}
+	return s
 }
 
 func (s *step) Name() string {
 	return s.name
 }
 
+func (s *step) StepAlias() string {
+	return s.alias
+}
+
+func (s *step) Weight() (float64, bool) {
+	return s.weight, s.hasWeight
+}
+
+func (s *step) Optional() bool {
+	return s.optional
+}
+
+func (s *step) StepStateManager() StepStateManager {
+	return s.stateMgr
+}
+
+func (s *step) ShouldCompensate(forwardErr error) bool {
+	if s.compensationCondition == nil {
+		return true
+	}
+	return s.compensationCondition(forwardErr)
+}
+
+func (s *step) Metadata() map[string]string {
+	return s.metadata
+}
+
+func (s *step) OverrideForward(fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.forwardOverride = fn
+}
+
+func (s *step) OverrideCompensate(fn func(ctx context.Context) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.compensateOverride = fn
+}
+
+// forwardAction returns the forward action to run: the override if
+// any, which is consumed unless it is persistent, or the step's own.
+func (s *step) forwardAction() func(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn := s.forwardOverride
+	if fn == nil {
+		return s.forward
+	}
+	if !s.persistentOverride {
+		s.forwardOverride = nil
+	}
+	return fn
+}
+
+// compensateAction returns the compensation action to run: the override
+// if any, which is consumed unless it is persistent, or the step's own.
+func (s *step) compensateAction() func(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn := s.compensateOverride
+	if fn == nil {
+		return s.compensate
+	}
+	if !s.persistentOverride {
+		s.compensateOverride = nil
+	}
+	return fn
+}
+
+func (s *step) captureOutput(ctx context.Context) ([]byte, error) {
+	switch {
+	case s.outputCapture != nil:
+		return s.outputCapture(ctx)
+	case s.outputValueCapture != nil:
+		v, err := s.outputValueCapture(ctx)
+		if err != nil {
+			return nil, err
+		}
+		serializer := s.outputSerializer
+		if serializer == nil {
+			serializer = JSONOutputSerializer{}
+		}
+		data, err := serializer.Encode(v)
+		if err != nil {
+			return nil, errors.Wrapf(err, "encoding output of step %s", s.name)
+		}
+		return data, nil
+	}
+	return nil, nil
+}
+
+func (s *step) handleCompensationError(ctx context.Context, err error) error {
+	if s.compensationErrorHandler == nil {
+		return err
+	}
+	return s.compensationErrorHandler(ctx, s.name, err)
+}
+
+func (s *step) hasPanicHandler() bool {
+	return s.panicHandler != nil
+}
+
+func (s *step) handlePanic(ctx context.Context, recovered any, stack []byte) error {
+	return s.panicHandler(ctx, s.name, recovered, stack)
+}
+
+func (s *step) verifyCompensation(ctx context.Context) error {
+	if s.compensationVerifier == nil {
+		return nil
+	}
+	if err := s.compensationVerifier(ctx); err != nil {
+		return &CompensationVerificationError{StepName: s.name, Cause: err}
+	}
+	return nil
+}
+
+func (s *step) logInput(ctx context.Context) map[string]any {
+	if s.inputExtractor == nil {
+		return nil
+	}
+	return s.inputExtractor(ctx)
+}
+
+func (s *step) logOutput(ctx context.Context, err error) map[string]any {
+	if s.outputExtractor == nil {
+		return nil
+	}
+	return s.outputExtractor(ctx, err)
+}
+
 func (s *step) ExecuteForward(ctx context.Context) error {
-	return s.forward(ctx)
+	return s.withLock(ctx, s.executeForward)
+}
+
+// executeForward runs the forward action of the step,
+// along with the configured checks and retries.
+func (s *step) executeForward(ctx context.Context) error {
+	ctx = withClock(ctx, s.clock)
+	if s.w3cTracePropagation {
+		ctx = withTraceHeaders(ctx)
+	}
+	return s.withIdempotentExecution(ctx, s.runForward)
+}
+
+// runForward runs the checks, the forward action and
+// the retries of the step (see executeForward).
+func (s *step) runForward(ctx context.Context) error {
+	if exhausted, err := s.checkTimeBudget(ctx); exhausted {
+		return s.categorize(err)
+	}
+	if err := s.captureStateBefore(ctx); err != nil {
+		return err
+	}
+	if err := s.captureStateDiffBefore(ctx); err != nil {
+		return err
+	}
+	if s.inputValidator != nil {
+		if err := s.inputValidator(ctx); err != nil {
+			return &InputValidationError{StepName: s.name, Cause: err}
+		}
+	}
+	if err := s.runBeforeHooks(ctx, s.beforeForward); err != nil {
+		return s.categorize(err)
+	}
+	if err := s.checkAtMostOnce(ctx); err != nil {
+		return err
+	}
+	forward := s.forwardWithRetry
+	if s.forwardTimeout > 0 && !s.retryResetTimeout {
+		forward = withTimeout(s.name, s.forwardTimeout, forward)
+	}
+	if s.progressiveTimeout > 0 {
+		forward = withProgressiveTimeout(s.name, s.progressiveTimeout, s.progressExtender, forward)
+	}
+	err := forward(ctx)
+	if err == nil && s.postcondition != nil {
+		if assertErr := s.postcondition(ctx); assertErr != nil {
+			err = &PostconditionFailedError{StepName: s.name, Cause: assertErr}
+		}
+	}
+	err = s.runAfterHooks(ctx, s.afterForward, err)
+	return s.categorize(err)
 }
 
 func (s *step) ExecuteCompensate(ctx context.Context) error {
-	return s.compensate(ctx)
+	compensate := s.compensateAction()
+	// Steps without compensation action have nothing to compensate.
+	if compensate == nil {
+		return nil
+	}
+	return s.withLock(ctx, func(ctx context.Context) error {
+		err := s.executeCompensate(ctx, compensate)
+		if err != nil {
+			s.observeError(ctx, err)
+		}
+		return err
+	})
+}
+
+// executeCompensate runs the given compensation action
+// of the step, along with the configured checks.
+func (s *step) executeCompensate(ctx context.Context, compensate func(ctx context.Context) error) error {
+	ctx = withClock(ctx, s.clock)
+	if s.effectiveCompensationTimeout() > 0 {
+		action := compensate
+		compensate = func(ctx context.Context) error {
+			return s.compensateWithTimeout(ctx, action)
+		}
+	}
+	if err := s.runBeforeHooks(ctx, s.beforeCompensate); err != nil {
+		return err
+	}
+	var err error
+	if s.delayCompensation {
+		err = s.withDelays(ctx, compensate)
+	} else {
+		err = compensate(ctx)
+	}
+	err = s.runAfterHooks(ctx, s.afterCompensate, err)
+	if err != nil {
+		return err
+	}
+	if err := s.verifyMutation(ctx); err != nil {
+		return err
+	}
+	if err := s.verifyStateDiff(ctx); err != nil {
+		return err
+	}
+	return s.forgetExecution(ctx)
+}
+
+// categorize wraps the given forward error in a *CategorizedError,
+// if an error categorizer is configured.
+func (s *step) categorize(err error) error {
+	if err == nil || s.errorCategorizer == nil {
+		return err
+	}
+	return &CategorizedError{StepName: s.name, Category: s.errorCategorizer(err), Cause: err}
+}
+
+// captureStateBefore captures the state before the forward action,
+// if mutation verification is configured.
+func (s *step) captureStateBefore(ctx context.Context) error {
+	if s.stateCapture == nil || s.mutationVerifier == nil {
+		return nil
+	}
+	before, err := s.stateCapture(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "capturing state before step %s", s.name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stateBefore = before
+	return nil
+}
+
+// verifyMutation captures the state after the compensation action and
+// verifies it against the state captured before the forward action,
+// if mutation verification is configured.
+func (s *step) verifyMutation(ctx context.Context) error {
+	if s.stateCapture == nil || s.mutationVerifier == nil {
+		return nil
+	}
+	after, err := s.stateCapture(ctx)
+	if err != nil {
+		return errors.Wrapf(err, "capturing state after compensating step %s", s.name)
+	}
+	s.mu.Lock()
+	before := s.stateBefore
+	s.mu.Unlock()
+	if err := s.mutationVerifier(ctx, before, after); err != nil {
+		return &MutationVerificationError{StepName: s.name, Cause: err}
+	}
+	return nil
+}
+
+// compensateWithTimeout runs the given compensation action bounded by
+// the configured compensation timeout, which is the deadline of the
+// context passed to the action. If the timeout fires first, it returns
+// a *CompensationTimeoutError without waiting for the action.
+func (s *step) compensateWithTimeout(ctx context.Context, compensate func(ctx context.Context) error) error {
+	d := s.effectiveCompensationTimeout()
+	tctx, cancel := withClockTimeout(ctx, d)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- compensate(tctx)
+	}()
+	select {
+	case err := <-done:
+		if err != nil && ctx.Err() == nil && tctx.Err() == context.DeadlineExceeded {
+			return &CompensationTimeoutError{StepName: s.name, Timeout: d}
+		}
+		return err
+	case <-tctx.Done():
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return &CompensationTimeoutError{StepName: s.name, Timeout: d}
+	}
+}
+
+// effectiveCompensationTimeout returns the timeout bounding the
+// compensation action: the configured one if any, or else twice the
+// forward timeout. A negative value means no timeout.
+func (s *step) effectiveCompensationTimeout() time.Duration {
+	if s.compensationTimeout != 0 {
+		return s.compensationTimeout
+	}
+	return 2 * s.forwardTimeout
 }
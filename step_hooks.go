@@ -0,0 +1,85 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import "context"
+
+// WithBeforeForward option adds a hook called before the forward action
+// of the step. If it fails, the forward action is not called and the
+// step fails with its error. Several hooks run in the order they are
+// added, stopping at the first failure.
+func WithBeforeForward(hook func(ctx context.Context, stepName string) error) StepOption {
+	return func(s *step) {
+		s.beforeForward = append(s.beforeForward, hook)
+	}
+}
+
+// WithAfterForward option adds a hook called after the forward action
+// of the step, with its error. If the hook fails, its error becomes the
+// error of the step, so that a post-processing failure of a successful
+// forward action is treated as a step failure. Several hooks run in the
+// order they are added, each one receiving the error so far.
+func WithAfterForward(hook func(ctx context.Context, stepName string, err error) error) StepOption {
+	return func(s *step) {
+		s.afterForward = append(s.afterForward, hook)
+	}
+}
+
+// WithBeforeCompensate option adds a hook called before the compensation
+// action of the step. See WithBeforeForward.
+func WithBeforeCompensate(hook func(ctx context.Context, stepName string) error) StepOption {
+	return func(s *step) {
+		s.beforeCompensate = append(s.beforeCompensate, hook)
+	}
+}
+
+// WithAfterCompensate option adds a hook called after the compensation
+// action of the step, with its error. See WithAfterForward.
+func WithAfterCompensate(hook func(ctx context.Context, stepName string, err error) error) StepOption {
+	return func(s *step) {
+		s.afterCompensate = append(s.afterCompensate, hook)
+	}
+}
+
+// WithErrorObserver option adds an observer called, for side effects
+// only (e.g. logging or metrics), after each failed attempt of the
+// forward action, before it is retried, and after each failed
+// compensation. Unlike WithCompensationErrorHandler, it cannot change
+// the error. Several observers run in the order they are added.
+func WithErrorObserver(observe func(ctx context.Context, stepName string, err error)) StepOption {
+	return func(s *step) {
+		s.errorObservers = append(s.errorObservers, observe)
+	}
+}
+
+// observeError calls the error observers in order with the given error.
+func (s *step) observeError(ctx context.Context, err error) {
+	for _, observe := range s.errorObservers {
+		observe(ctx, s.name, err)
+	}
+}
+
+// runBeforeHooks calls the given before hooks in order,
+// stopping at the first failure.
+func (s *step) runBeforeHooks(ctx context.Context, hooks []func(ctx context.Context, stepName string) error) error {
+	for _, hook := range hooks {
+		if err := hook(ctx, s.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterHooks calls the given after hooks in order with the error
+// of the action, returning the error of the last hook that failed,
+// or the error of the action if none did.
+func (s *step) runAfterHooks(ctx context.Context, hooks []func(ctx context.Context, stepName string, err error) error, err error) error {
+	for _, hook := range hooks {
+		if hookErr := hook(ctx, s.name, err); hookErr != nil {
+			err = hookErr
+		}
+	}
+	return err
+}
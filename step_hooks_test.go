@@ -0,0 +1,145 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepHooks(t *testing.T) {
+	testCases := []struct {
+		name          string
+		forwardErr    error
+		beforeErr     error
+		afterErr      error
+		expectedCalls []string
+		expectedError string
+	}{
+		{
+			name: "success",
+			expectedCalls: []string{
+				"before 1 step1", "before 2 step1", "forward",
+				"after 1 step1 <nil>", "after 2 step1 <nil>",
+			},
+		},
+		{
+			name:          "before hook failure",
+			beforeErr:     errors.New("before error"),
+			expectedCalls: []string{"before 1 step1"},
+			expectedError: "before error",
+		},
+		{
+			name:     "after hook failure",
+			afterErr: errors.New("after error"),
+			expectedCalls: []string{
+				"before 1 step1", "before 2 step1", "forward",
+				"after 1 step1 <nil>", "after 2 step1 after error",
+			},
+			expectedError: "after error",
+		},
+		{
+			name:       "forward failure",
+			forwardErr: errors.New("forward error"),
+			expectedCalls: []string{
+				"before 1 step1", "before 2 step1", "forward",
+				"after 1 step1 forward error", "after 2 step1 forward error",
+			},
+			expectedError: "forward error",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			before := func(n string, err error) func(ctx context.Context, stepName string) error {
+				return func(ctx context.Context, stepName string) error {
+					calls = append(calls, "before "+n+" "+stepName)
+					return err
+				}
+			}
+			after := func(n string, hookErr error) func(ctx context.Context, stepName string, err error) error {
+				return func(ctx context.Context, stepName string, err error) error {
+					calls = append(calls, "after "+n+" "+stepName+" "+errString(err))
+					return hookErr
+				}
+			}
+			forward := func(ctx context.Context) error {
+				calls = append(calls, "forward")
+				return tc.forwardErr
+			}
+			step := NewStepWithOptions("step1", forward, forward,
+				WithBeforeForward(before("1", tc.beforeErr)),
+				WithBeforeForward(before("2", nil)),
+				WithAfterForward(after("1", tc.afterErr)),
+				WithAfterForward(after("2", nil)),
+				WithBeforeCompensate(before("1", tc.beforeErr)),
+				WithBeforeCompensate(before("2", nil)),
+				WithAfterCompensate(after("1", tc.afterErr)),
+				WithAfterCompensate(after("2", nil)),
+			)
+			for _, execute := range []func(ctx context.Context) error{step.ExecuteForward, step.ExecuteCompensate} {
+				calls = nil
+				err := execute(context.Background())
+				if tc.expectedError != "" {
+					require.NotNil(t, err)
+					require.Equal(t, tc.expectedError, err.Error())
+				} else {
+					require.Nil(t, err)
+				}
+				require.Equal(t, tc.expectedCalls, calls)
+			}
+		})
+	}
+}
+
+// errString returns the message of the given error, or "<nil>".
+func errString(err error) string {
+	if err == nil {
+		return "<nil>"
+	}
+	return err.Error()
+}
+
+func TestWithErrorObserver(t *testing.T) {
+	var observed []string
+	observer := func(prefix string) func(ctx context.Context, stepName string, err error) {
+		return func(ctx context.Context, stepName string, err error) {
+			observed = append(observed, prefix+" "+stepName+": "+err.Error())
+		}
+	}
+	attempts := 0
+	saga := New()
+	saga.AddStep(NewStepWithOptions("step1", noop,
+		func(ctx context.Context) error {
+			return errors.New("compensate error")
+		},
+		WithErrorObserver(observer("first")),
+		WithErrorObserver(observer("second")),
+		WithCompensationErrorHandler(func(ctx context.Context, stepName string, err error) error {
+			return nil
+		}),
+	))
+	saga.AddStep(NewStepWithOptions("step2",
+		func(ctx context.Context) error {
+			attempts++
+			return fmt.Errorf("attempt %d error", attempts)
+		},
+		noop,
+		WithRetry(2, 0),
+		WithErrorObserver(observer("first")),
+	))
+	err := saga.Execute(context.Background())
+	require.EqualError(t, err, "executing step step2: attempt 2 error")
+	require.Equal(t, []string{
+		"first step2: attempt 1 error",
+		"first step2: attempt 2 error",
+		"first step1: compensate error",
+		"second step1: compensate error",
+	}, observed)
+}
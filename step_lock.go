@@ -0,0 +1,44 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// StepLocker acquires external locks protecting
+// the shared resources modified by steps.
+type StepLocker interface {
+	// Lock acquires the lock of the given step, returning
+	// the function that releases it.
+	Lock(ctx context.Context, stepName string) (unlock func(ctx context.Context) error, err error)
+}
+
+// NoOpStepLocker is a StepLocker that does not lock anything.
+// It is meant for testing.
+type NoOpStepLocker struct{}
+
+func (NoOpStepLocker) Lock(ctx context.Context, stepName string) (func(ctx context.Context) error, error) {
+	return func(ctx context.Context) error { return nil }, nil
+}
+
+// withLock runs the given action holding the external lock of the
+// step, if any. If the lock cannot be acquired, the action is not run.
+func (s *step) withLock(ctx context.Context, action func(ctx context.Context) error) error {
+	if s.locker == nil {
+		return action(ctx)
+	}
+	unlock, err := s.locker.Lock(ctx, s.name)
+	if err != nil {
+		return errors.Wrapf(err, "acquiring lock for step %s", s.name)
+	}
+	err = action(ctx)
+	if unlockErr := unlock(ctx); unlockErr != nil && err == nil {
+		return errors.Wrapf(unlockErr, "releasing lock for step %s", s.name)
+	}
+	return err
+}
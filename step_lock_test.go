@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingStepLocker struct {
+	calls   []string
+	lockErr error
+}
+
+func (l *recordingStepLocker) Lock(ctx context.Context, stepName string) (func(ctx context.Context) error, error) {
+	if err := l.lockErr; err != nil {
+		// fails only once.
+		l.lockErr = nil
+		return nil, err
+	}
+	l.calls = append(l.calls, "lock "+stepName)
+	return func(ctx context.Context) error {
+		l.calls = append(l.calls, "unlock "+stepName)
+		return nil
+	}, nil
+}
+
+func TestWithExternalLock(t *testing.T) {
+	testCases := []struct {
+		name                string
+		lockErr             error
+		expectedCalls       []string
+		expectedCompensated bool
+		expectedError       string
+	}{
+		{
+			name: "lock held during forward and compensation",
+			expectedCalls: []string{
+				"lock step2", "record forward", "unlock step2",
+				"lock step2", "record compensate", "unlock step2",
+			},
+			expectedCompensated: true,
+			expectedError:       "executing step step3: forward error",
+		},
+		{
+			name:    "lock acquisition error",
+			lockErr: errors.New("lock unavailable"),
+			expectedCalls: []string{
+				"lock step2", "record compensate", "unlock step2",
+			},
+			expectedCompensated: true,
+			expectedError:       "executing step step2: acquiring lock for step step2: lock unavailable",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			locker := &recordingStepLocker{lockErr: tc.lockErr}
+			compensated := false
+			s := New()
+			s.AddStep(NewStep("step1",
+				noop,
+				func(ctx context.Context) error {
+					compensated = true
+					return nil
+				},
+			))
+			s.AddStep(NewStepWithOptions("step2",
+				func(ctx context.Context) error {
+					locker.calls = append(locker.calls, "record forward")
+					return nil
+				},
+				func(ctx context.Context) error {
+					locker.calls = append(locker.calls, "record compensate")
+					return nil
+				},
+				WithExternalLock(locker),
+			))
+			s.AddStep(NewStep("step3",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				noop,
+			))
+			require.EqualError(t, s.Execute(context.Background()), tc.expectedError)
+			require.Equal(t, tc.expectedCalls, locker.calls)
+			require.Equal(t, tc.expectedCompensated, compensated)
+		})
+	}
+}
+
+func TestNoOpStepLocker(t *testing.T) {
+	unlock, err := NoOpStepLocker{}.Lock(context.Background(), "step1")
+	require.Nil(t, err)
+	require.Nil(t, unlock(context.Background()))
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+// StepNameResolver resolves the key under which the state
+// of a step is stored, given the name of the step.
+type StepNameResolver interface {
+	// Resolve returns the storage key of the step with the given name.
+	Resolve(stepName string) string
+}
+
+// StepNameResolverFunc is an adapter to allow the use of
+// ordinary functions as StepNameResolvers.
+type StepNameResolverFunc func(stepName string) string
+
+func (f StepNameResolverFunc) Resolve(stepName string) string {
+	return f(stepName)
+}
+
+// WithStepNameResolver option makes the Saga store the state of each
+// step under the key resolved from its name (or alias, see
+// WithStepAlias) by the given resolver, instead of its index, so that
+// the state survives steps being renamed or reordered between
+// deployments. It requires a StateManager implementing
+// NamedStateManager; others keep storing the state by step index.
+func WithStepNameResolver(r StepNameResolver) Option {
+	return func(s *saga) {
+		s.stepNameResolver = r
+	}
+}
+
+// IdentityResolver resolves step names to themselves.
+var IdentityResolver StepNameResolver = StepNameResolverFunc(func(stepName string) string {
+	return stepName
+})
+
+// PrefixResolver returns a StepNameResolver that prefixes step names
+// with the given prefix (e.g. to namespace the keys of several Sagas
+// sharing a StateManager).
+func PrefixResolver(prefix string) StepNameResolver {
+	return StepNameResolverFunc(func(stepName string) string {
+		return prefix + stepName
+	})
+}
+
+// AliasResolver returns a StepNameResolver translating the names found
+// in aliases to their values, such as the old names of renamed steps,
+// for backward compatibility with the state stored under them. Other
+// names resolve to themselves.
+func AliasResolver(aliases map[string]string) StepNameResolver {
+	return StepNameResolverFunc(func(stepName string) string {
+		if key, ok := aliases[stepName]; ok {
+			return key
+		}
+		return stepName
+	})
+}
+
+// namedStateManager returns the Saga's StateManager as a
+// NamedStateManager and the storage key of the given step, added at
+// the given index, if a step name resolver, a state key strategy or a
+// step sorter (see WithStepSorter) is configured and the StateManager
+// supports it.
+func (s *saga) namedStateManager(i int, step Step) (NamedStateManager, string, bool) {
+	resolver := s.stepNameResolver
+	if resolver == nil && (s.nameKeyedState || s.stateKeyStrategy != nil) {
+		resolver = IdentityResolver
+	}
+	if resolver == nil {
+		return nil, "", false
+	}
+	nm, ok := stateManagerAs[NamedStateManager](s.stateManager)
+	if !ok {
+		return nil, "", false
+	}
+	key := resolver.Resolve(stepKey(step))
+	if s.stateKeyStrategy != nil {
+		key = s.stateKeyStrategy.KeyFor(i, key)
+	}
+	return nm, s.withServiceName(key), true
+}
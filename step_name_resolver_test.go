@@ -0,0 +1,106 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStepNameResolvers(t *testing.T) {
+	testCases := []struct {
+		name     string
+		resolver StepNameResolver
+		stepName string
+		expected string
+	}{
+		{
+			name:     "identity",
+			resolver: IdentityResolver,
+			stepName: "charge",
+			expected: "charge",
+		},
+		{
+			name:     "prefix",
+			resolver: PrefixResolver("order:"),
+			stepName: "charge",
+			expected: "order:charge",
+		},
+		{
+			name:     "alias",
+			resolver: AliasResolver(map[string]string{"charge": "charge-card"}),
+			stepName: "charge",
+			expected: "charge-card",
+		},
+		{
+			name:     "alias without match",
+			resolver: AliasResolver(map[string]string{"charge": "charge-card"}),
+			stepName: "ship",
+			expected: "ship",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, tc.resolver.Resolve(tc.stepName))
+		})
+	}
+}
+
+func TestWithStepNameResolver(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testCases := []struct {
+		name         string
+		stateManager StateManager
+	}{
+		{
+			name:         "in-memory state manager",
+			stateManager: NewInMemoryStateManager(),
+		},
+		{
+			name:         "redis state manager",
+			stateManager: NewRedisStateManager(client, "saga:order-42"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			step := func(name string, err error) Step {
+				return NewStep(name,
+					func(ctx context.Context) error {
+						calls = append(calls, name)
+						return err
+					},
+					noop,
+				)
+			}
+
+			// First deployment: ship fails after charge-card completed.
+			saga := New(WithStateManager(tc.stateManager), WithStepNameResolver(PrefixResolver("order:")))
+			saga.AddStep(step("charge-card", nil))
+			saga.AddStep(step("ship", errors.New("ship error")))
+			require.NotNil(t, saga.Execute(context.Background()))
+
+			// Second deployment: charge-card was renamed and a step was
+			// inserted before it, yet its state is found by name.
+			calls = nil
+			saga = New(WithStateManager(tc.stateManager), WithStepNameResolver(StepNameResolverFunc(func(stepName string) string {
+				return PrefixResolver("order:").Resolve(AliasResolver(map[string]string{"charge": "charge-card"}).Resolve(stepName))
+			})))
+			saga.AddStep(step("validate", nil))
+			saga.AddStep(step("charge", nil))
+			saga.AddStep(step("ship", nil))
+			require.Nil(t, saga.Execute(context.Background()))
+			require.Equal(t, []string{"validate", "ship"}, calls)
+		})
+	}
+}
@@ -0,0 +1,403 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"time"
+)
+
+// StepOption defines a function type that applies a
+// configuration option to a Step instance.
+type StepOption func(*step)
+
+// WithStepWeight option sets the weight of the step, used
+// to compute the Saga's progress percentage. Steps without
+// an explicit weight are treated as having weight 1.0.
+// The weight must be positive, which is validated by Execute.
+func WithStepWeight(w float64) StepOption {
+	return func(s *step) {
+		s.weight = w
+		s.hasWeight = true
+	}
+}
+
+// WithCompensationTimeout option bounds the execution of the
+// step's compensation action by the given duration. If the timeout
+// fires, the compensation returns a *CompensationTimeoutError, which
+// is reported along with the other compensation errors; the Saga
+// cannot compensate a compensation. For steps created by
+// NewTimeoutStepWithOptions, it defaults to twice the forward timeout.
+// A negative duration disables it.
+func WithCompensationTimeout(d time.Duration) StepOption {
+	return func(s *step) {
+		s.compensationTimeout = d
+	}
+}
+
+// WithOptionalStep option marks the step as non-critical:
+// if its forward action fails, the Saga logs the error (if a logger
+// is configured), marks the step as done and continues with the next
+// step without triggering compensation. The failure is reported
+// in the Summary's SkippedSteps.
+func WithOptionalStep() StepOption {
+	return func(s *step) {
+		s.optional = true
+	}
+}
+
+// WithStepStateManager option makes the step keep its own state
+// in the given StepStateManager instead of the Saga-level StateManager.
+// This allows using, for example, durable storage only for some steps.
+func WithStepStateManager(sm StepStateManager) StepOption {
+	return func(s *step) {
+		s.stateMgr = sm
+	}
+}
+
+// WithCompensationMode option states whether the step is expected to
+// have a compensation action. Execute fails before executing any step
+// with a *MissingCompensationError if a step requiring a compensation
+// action (the default) has none, or with an *UnexpectedCompensationError
+// if a step forbidding it has one.
+func WithCompensationMode(mode CompensationMode) StepOption {
+	return func(s *step) {
+		s.compensationMode = mode
+	}
+}
+
+// WithCompensationCondition option makes the compensation of the step
+// conditional: before compensating, the Saga calls predicate with the
+// forward error that caused it to fail, and skips the step's compensation
+// if it returns false.
+func WithCompensationCondition(predicate func(forwardErr error) bool) StepOption {
+	return func(s *step) {
+		s.compensationCondition = predicate
+	}
+}
+
+// WithStepInputLogger option sets a function that extracts the input
+// of the step from its context. Before calling the forward action,
+// the Saga logs the extracted values at debug level under the
+// "step.input" key. It requires the Saga to have a logger (WithLogger).
+func WithStepInputLogger(extract func(ctx context.Context) map[string]any) StepOption {
+	return func(s *step) {
+		s.inputExtractor = extract
+	}
+}
+
+// WithStepOutputLogger option sets a function that extracts the output
+// of the step from its context and the forward error. After the forward
+// action returns, the Saga logs the extracted values at debug level under
+// the "step.output" key. It requires the Saga to have a logger (WithLogger).
+func WithStepOutputLogger(extract func(ctx context.Context, err error) map[string]any) StepOption {
+	return func(s *step) {
+		s.outputExtractor = extract
+	}
+}
+
+// WithStateCapture option sets the function used to capture the state
+// affected by the step, for mutation verification (see WithMutationVerifier).
+func WithStateCapture(fn func(ctx context.Context) (map[string]any, error)) StepOption {
+	return func(s *step) {
+		s.stateCapture = fn
+	}
+}
+
+// WithMutationVerifier option verifies that the step's compensation
+// reverses the effects of its forward action. The state is captured
+// (see WithStateCapture) before the forward action and after the
+// compensation, and both are passed to verify. If verify fails, the
+// compensation returns a *MutationVerificationError.
+// This is primarily a testing tool, and it is only enabled when
+// both options are set.
+func WithMutationVerifier(verify func(ctx context.Context, beforeState, afterState map[string]any) error) StepOption {
+	return func(s *step) {
+		s.mutationVerifier = verify
+	}
+}
+
+// WithCompensationVerifier option sets a function verifying that the
+// compensation of the step had the intended effect, called by the Saga
+// after the compensation succeeds. If it fails, the compensation is
+// reported as failed with a *CompensationVerificationError, which is
+// logged and sent to the dead letter handler, if any, but the
+// compensation is not retried.
+func WithCompensationVerifier(verify func(ctx context.Context) error) StepOption {
+	return func(s *step) {
+		s.compensationVerifier = verify
+	}
+}
+
+// WithInputValidation option sets a function that validates the input
+// of the step before its forward action runs. If it fails, the step
+// fails with an *InputValidationError, which triggers compensation
+// but is never retried.
+func WithInputValidation(validate func(ctx context.Context) error) StepOption {
+	return func(s *step) {
+		s.inputValidator = validate
+	}
+}
+
+// WithStepErrorSanitizer option sets a function transforming the errors
+// of the step before they are reported, overriding the error sanitizer
+// of the Saga (see WithErrorSanitizer).
+func WithStepErrorSanitizer(sanitize func(err error) error) StepOption {
+	return func(s *step) {
+		s.stepErrorSanitizer = sanitize
+	}
+}
+
+// WithPostconditionAssertion option sets a function asserting that the
+// system is in the expected state after the forward action succeeds,
+// e.g. that a database write was durably committed. If the assertion
+// fails, the step fails with a *PostconditionFailedError, triggering
+// compensation, even though the forward action succeeded.
+func WithPostconditionAssertion(assert func(ctx context.Context) error) StepOption {
+	return func(s *step) {
+		s.postcondition = assert
+	}
+}
+
+// WithRetry option makes the step retry its forward action up to
+// maxAttempts times in total, waiting delay between attempts,
+// while the returned error is retriable (see WithErrorClassifier).
+func WithRetry(maxAttempts int, delay time.Duration) StepOption {
+	return func(s *step) {
+		s.maxAttempts = maxAttempts
+		s.retryDelay = delay
+	}
+}
+
+// WithRetryMaxElapsed option makes the step retry its forward action
+// while the returned error is retriable (see WithErrorClassifier), until
+// maxElapsed has passed since the first attempt. The delay between
+// attempts starts at initialDelay and is multiplied by multiplier after
+// each attempt. Retrying stops early if the next attempt would start
+// after maxElapsed. Combined with WithRetry, retrying also stops when
+// the attempts are exhausted.
+func WithRetryMaxElapsed(maxElapsed, initialDelay time.Duration, multiplier float64) StepOption {
+	return func(s *step) {
+		s.retryMaxElapsed = maxElapsed
+		s.retryDelay = initialDelay
+		s.retryMultiplier = multiplier
+	}
+}
+
+// WithRetryResetTimeout option controls how the timeout of a step
+// created by NewTimeoutStepWithOptions applies when the step is retried.
+// When reset is true, each attempt gets a fresh timeout: with a 5s
+// timeout and 3 attempts, the step can take up to 15s (plus the retry
+// delays), but a slow attempt does not eat into the budget of the next
+// ones. When reset is false (the default), the timeout is a budget for
+// all the attempts and the delays between them: the step never takes
+// more than 5s, but later attempts may be cut short or never run.
+func WithRetryResetTimeout(reset bool) StepOption {
+	return func(s *step) {
+		s.retryResetTimeout = reset
+	}
+}
+
+// WithRetryNotification option sets a function called after each failed
+// attempt of the forward action that is going to be retried, with the
+// step name, the attempt number (starting at 1), the error of the
+// attempt and the delay before the next one. It is useful for logging,
+// metrics, or alerting when a step keeps being retried.
+func WithRetryNotification(notify func(ctx context.Context, stepName string, attempt int, err error, nextDelay time.Duration)) StepOption {
+	return func(s *step) {
+		s.retryNotify = notify
+	}
+}
+
+// WithPersistentOverride option makes the overrides set through
+// OverridableStep apply to every subsequent call of the overridden
+// action, instead of the next one only.
+func WithPersistentOverride() StepOption {
+	return func(s *step) {
+		s.persistentOverride = true
+	}
+}
+
+// WithPanicHandler option sets a function handling the panics of the
+// forward and compensation actions of the step, with the recovered
+// value and the stack trace. It takes priority over WithPanicRecovery.
+// The handler can return nil to treat the action as successful, return
+// an error to treat it as failed, or panic again.
+func WithPanicHandler(handler func(ctx context.Context, stepName string, recovered any, stack []byte) error) StepOption {
+	return func(s *step) {
+		s.panicHandler = handler
+	}
+}
+
+// WithErrorClassifier option sets the predicate deciding whether
+// a forward error is retriable. By default, all errors but
+// *InputValidationError are retriable.
+func WithErrorClassifier(isRetriable func(err error) bool) StepOption {
+	return func(s *step) {
+		s.errorClassifier = isRetriable
+	}
+}
+
+// WithAttemptBasedErrorClassifier option sets a predicate deciding
+// whether a forward error is retriable given the attempt (starting at
+// 1) that returned it, e.g. to retry an error on the first attempts
+// only. Combined with WithErrorClassifier, an error is retriable only if
+// both predicates agree. Combined with WithRetryMaxElapsed, it gives
+// full control over the retry behavior.
+func WithAttemptBasedErrorClassifier(classifier func(attempt int, err error) bool) StepOption {
+	return func(s *step) {
+		s.attemptErrorClassifier = classifier
+	}
+}
+
+// WithErrorCategorizer option sets the function classifying the errors
+// of the forward action. When the forward action fails, after retries,
+// its error is wrapped in a *CategorizedError carrying the category.
+func WithErrorCategorizer(categorize func(err error) ErrorCategory) StepOption {
+	return func(s *step) {
+		s.errorCategorizer = categorize
+	}
+}
+
+// WithAtMostOnce option guarantees that the forward action of the step
+// runs at most once, even when the saga is executed again. If the step
+// was already attempted, whether it succeeded or not, ExecuteForward
+// returns an *AlreadyAttemptedError without running the forward action.
+// The step is never retried.
+//
+// Attempts are recorded in the step's AttemptStore (an in-memory one
+// unless WithAttemptStore is used) under the ID of the Saga followed
+// by the step's idempotency key (the step alias or name unless
+// WithIdempotencyKey is used).
+func WithAtMostOnce() StepOption {
+	return func(s *step) {
+		s.atMostOnce = true
+	}
+}
+
+// WithAttemptStore option sets the store used to record the
+// attempts of an at-most-once step. Use a shared, durable store to
+// keep the guarantee across step instances and processes. As attempts
+// are keyed by Saga ID, the step fails when executed without one
+// (see WithSagaID).
+func WithAttemptStore(store AttemptStore) StepOption {
+	return func(s *step) {
+		s.attemptStore = store
+		s.sharedAttemptStore = true
+	}
+}
+
+// WithIdempotencyKey option sets the stable key identifying
+// the step, such as a payment or order identifier.
+func WithIdempotencyKey(key string) StepOption {
+	return func(s *step) {
+		s.idempotencyKey = key
+	}
+}
+
+// WithPreExecutionDelay option waits for the given duration before
+// each attempt of the forward action (e.g. to rate limit API calls).
+// The wait is interrupted if the context is done.
+func WithPreExecutionDelay(d time.Duration) StepOption {
+	return func(s *step) {
+		s.preDelay = d
+	}
+}
+
+// WithPostExecutionDelay option waits for the given duration after the
+// forward action succeeds (e.g. to give an external system time to
+// process). The wait is interrupted if the context is done.
+func WithPostExecutionDelay(d time.Duration) StepOption {
+	return func(s *step) {
+		s.postDelay = d
+	}
+}
+
+// WithDelayCompensation option sets whether the pre and post
+// execution delays also apply to the compensation action.
+// By default, they only apply to the forward action.
+func WithDelayCompensation(delay bool) StepOption {
+	return func(s *step) {
+		s.delayCompensation = delay
+	}
+}
+
+// WithStepMetadata option sets the metadata of the step, persisted
+// before its forward action runs if the StateManager of the Saga
+// is a StepMetadataManager. See MetadataProvider.
+func WithStepMetadata(metadata map[string]string) StepOption {
+	return func(s *step) {
+		s.metadata = metadata
+	}
+}
+
+// WithOutputCapture option makes the Saga serialize the output of the
+// step after its forward action succeeds, and record it in its
+// StateManager, if it implements StepOutputManager.
+func WithOutputCapture(serialize func(ctx context.Context) ([]byte, error)) StepOption {
+	return func(s *step) {
+		s.outputCapture = serialize
+	}
+}
+
+// WithOutputValueCapture option is like WithOutputCapture, but the
+// output returned by capture is serialized with the serializer of the
+// step (see WithOutputSerializer).
+func WithOutputValueCapture(capture func(ctx context.Context) (any, error)) StepOption {
+	return func(s *step) {
+		s.outputValueCapture = capture
+	}
+}
+
+// WithOutputSerializer option sets the serializer of the outputs
+// captured with WithOutputValueCapture. By default, outputs are
+// serialized with JSONOutputSerializer.
+func WithOutputSerializer(serializer StepOutputSerializer) StepOption {
+	return func(s *step) {
+		s.outputSerializer = serializer
+	}
+}
+
+// WithW3CTracePropagation option injects the W3C Trace-Context headers
+// of the step span in the context passed to its forward action, so that
+// it can propagate them to external services without importing
+// OpenTelemetry (see TraceHeadersFromContext). It requires the Saga to
+// be configured with WithTracer; otherwise, no headers are injected.
+func WithW3CTracePropagation() StepOption {
+	return func(s *step) {
+		s.w3cTracePropagation = true
+	}
+}
+
+// WithExternalLock option makes the step hold the lock acquired from
+// the given locker while running its forward and compensation actions.
+// If the lock cannot be acquired, the action is not run and fails.
+func WithExternalLock(locker StepLocker) StepOption {
+	return func(s *step) {
+		s.locker = locker
+	}
+}
+
+// WithStepAlias option sets an alias for the step, used instead of
+// its name as the key to look up its state (e.g. the attempts of an
+// at-most-once step). When renaming a step, add WithStepAlias with
+// its old name to preserve continuity with in-flight executions
+// persisted under that name.
+func WithStepAlias(alias string) StepOption {
+	return func(s *step) {
+		s.alias = alias
+	}
+}
+
+// WithCompensationErrorHandler option sets a handler called when the
+// compensation action of the step fails. The handler can return nil to
+// ignore the error (e.g. a "not found" error, meaning the resource was
+// already cleaned up), the error itself to report it, or a different
+// error to transform it.
+func WithCompensationErrorHandler(handler func(ctx context.Context, stepName string, err error) error) StepOption {
+	return func(s *step) {
+		s.compensationErrorHandler = handler
+	}
+}
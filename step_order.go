@@ -0,0 +1,149 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// WithStepOrderer option makes the Saga execute its steps in the order
+// returned by the given orderer, called at the start of each Execute
+// with the steps in the order they were added (e.g. for A/B testing
+// different workflow orderings). The orderer must return all the steps,
+// and only them, or Execute fails with a *StepOrderingError.
+// The state of each step is still recorded under the index it was added
+// at, so that it is preserved across different orderings.
+func WithStepOrderer(orderer func(steps []Step) []Step) Option {
+	return func(s *saga) {
+		s.stepOrderer = orderer
+	}
+}
+
+// WithRandomStepOrder option makes the Saga execute its steps in a
+// random order, for chaos testing. See WithStepOrderer.
+func WithRandomStepOrder() Option {
+	return WithStepOrderer(func(steps []Step) []Step {
+		shuffled := append([]Step(nil), steps...)
+		rand.Shuffle(len(shuffled), func(i, j int) {
+			shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		})
+		return shuffled
+	})
+}
+
+// WithStepSorter option makes the Saga execute its steps in the order
+// returned by the given sorter, e.g. to run fast steps first depending
+// on current system conditions. It works like WithStepOrderer, but since
+// the order may differ between runs, the state of each step is recorded
+// under its name rather than its index when the StateManager supports it
+// (see NamedStateManager), using IdentityResolver unless another step
+// name resolver is set (see WithStepNameResolver).
+// See ByNameSorter, ByWeightSorter and StableRandomSorter.
+func WithStepSorter(sorter func(steps []Step) []Step) Option {
+	return func(s *saga) {
+		s.stepOrderer = sorter
+		s.nameKeyedState = true
+	}
+}
+
+// ByNameSorter returns a step sorter ordering the steps by name.
+func ByNameSorter() func(steps []Step) []Step {
+	return func(steps []Step) []Step {
+		sort.SliceStable(steps, func(i, j int) bool {
+			return steps[i].Name() < steps[j].Name()
+		})
+		return steps
+	}
+}
+
+// ByWeightSorter returns a step sorter ordering the steps by increasing
+// weight (see WithStepWeight), so that the lightest steps run first.
+// Steps without a weight count as weight 1.0.
+func ByWeightSorter() func(steps []Step) []Step {
+	weight := func(step Step) float64 {
+		if w, ok := stepAs[WeightedStep](step); ok {
+			if v, set := w.Weight(); set {
+				return v
+			}
+		}
+		return 1.0
+	}
+	return func(steps []Step) []Step {
+		sort.SliceStable(steps, func(i, j int) bool {
+			return weight(steps[i]) < weight(steps[j])
+		})
+		return steps
+	}
+}
+
+// StableRandomSorter returns a step sorter shuffling the steps in a
+// random order determined by the given seed, so that the same seed
+// always gives the same order.
+func StableRandomSorter(seed int64) func(steps []Step) []Step {
+	return func(steps []Step) []Step {
+		r := rand.New(rand.NewSource(seed))
+		r.Shuffle(len(steps), func(i, j int) {
+			steps[i], steps[j] = steps[j], steps[i]
+		})
+		return steps
+	}
+}
+
+// orderSteps computes the execution order of the steps, if a step
+// orderer is configured, matching the ordered steps with the added
+// ones by key (see stepKey).
+func (s *saga) orderSteps() error {
+	s.stepOrder = nil
+	if s.stepOrderer == nil {
+		return nil
+	}
+	ordered := s.stepOrderer(append([]Step(nil), s.steps...))
+	unused := make(map[string][]int)
+	for i, step := range s.steps {
+		key := stepKey(step)
+		unused[key] = append(unused[key], i)
+	}
+	order := make([]int, 0, len(ordered))
+	orderingErr := &StepOrderingError{}
+	for _, step := range ordered {
+		key := stepKey(step)
+		indexes := unused[key]
+		if len(indexes) == 0 {
+			orderingErr.Unexpected = append(orderingErr.Unexpected, key)
+			continue
+		}
+		order = append(order, indexes[0])
+		unused[key] = indexes[1:]
+	}
+	used := make([]bool, len(s.steps))
+	for _, i := range order {
+		used[i] = true
+	}
+	for i, step := range s.steps {
+		if !used[i] {
+			orderingErr.Missing = append(orderingErr.Missing, stepKey(step))
+		}
+	}
+	if len(orderingErr.Missing) > 0 || len(orderingErr.Unexpected) > 0 {
+		return orderingErr
+	}
+	s.stepOrder = order
+	return nil
+}
+
+// declaredIndex returns the index the step at the given
+// execution position was added at.
+func (s *saga) declaredIndex(pos int) int {
+	if s.stepOrder == nil {
+		return pos
+	}
+	return s.stepOrder[pos]
+}
+
+// stepAt returns the step at the given execution position.
+func (s *saga) stepAt(pos int) Step {
+	return s.steps[s.declaredIndex(pos)]
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithStepOrderer(t *testing.T) {
+	reverse := func(steps []Step) []Step {
+		reversed := make([]Step, len(steps))
+		for i, step := range steps {
+			reversed[len(steps)-1-i] = step
+		}
+		return reversed
+	}
+	testCases := []struct {
+		name          string
+		orderer       func(steps []Step) []Step
+		expectedCalls []string
+		expectedError string
+	}{
+		{
+			name:          "reversed order",
+			orderer:       reverse,
+			expectedCalls: []string{"step3", "step2", "step1"},
+		},
+		{
+			name: "missing step",
+			orderer: func(steps []Step) []Step {
+				return steps[1:]
+			},
+			expectedError: "invalid step ordering: missing steps [step1], unexpected steps []",
+		},
+		{
+			name: "unexpected step",
+			orderer: func(steps []Step) []Step {
+				return append(steps, NewStep("step4", noop, noop))
+			},
+			expectedError: "invalid step ordering: missing steps [], unexpected steps [step4]",
+		},
+		{
+			name: "duplicated step",
+			orderer: func(steps []Step) []Step {
+				return []Step{steps[0], steps[0], steps[1]}
+			},
+			expectedError: "invalid step ordering: missing steps [step3], unexpected steps [step1]",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var calls []string
+			saga := New(WithStepOrderer(tc.orderer))
+			for _, name := range []string{"step1", "step2", "step3"} {
+				name := name
+				saga.AddStep(NewStep(name,
+					func(ctx context.Context) error {
+						calls = append(calls, name)
+						return nil
+					},
+					noop,
+				))
+			}
+			err := saga.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError, err.Error())
+				var orderingErr *StepOrderingError
+				require.True(t, errors.As(err, &orderingErr))
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestWithStepOrderer_StatePreserved(t *testing.T) {
+	sm := NewInMemoryStateManager()
+	var calls, compensations []string
+	fail := true
+	newSaga := func(opts ...Option) Saga {
+		saga := New(append([]Option{WithStateManager(sm)}, opts...)...)
+		for _, name := range []string{"step1", "step2", "step3"} {
+			name := name
+			saga.AddStep(NewStep(name,
+				func(ctx context.Context) error {
+					calls = append(calls, name)
+					if name == "step2" && fail {
+						return errors.New("step2 error")
+					}
+					return nil
+				},
+				func(ctx context.Context) error {
+					compensations = append(compensations, name)
+					return nil
+				},
+			))
+		}
+		return saga
+	}
+
+	// step3, then step2 fails: step2 and step3 are compensated.
+	saga := newSaga(WithStepOrderer(func(steps []Step) []Step {
+		return []Step{steps[2], steps[1], steps[0]}
+	}))
+	require.NotNil(t, saga.Execute(context.Background()))
+	require.Equal(t, []string{"step3", "step2"}, calls)
+	require.Equal(t, []string{"step2", "step3"}, compensations)
+	completed, err := sm.StepState(2)
+	require.Nil(t, err)
+	require.True(t, completed)
+
+	// In the declared order, step3 is skipped since it was completed.
+	calls = nil
+	fail = false
+	require.Nil(t, newSaga().Execute(context.Background()))
+	require.Equal(t, []string{"step1", "step2"}, calls)
+}
+
+func TestWithRandomStepOrder(t *testing.T) {
+	var calls []string
+	saga := New(WithRandomStepOrder())
+	for _, name := range []string{"step1", "step2", "step3", "step4"} {
+		name := name
+		saga.AddStep(NewStep(name,
+			func(ctx context.Context) error {
+				calls = append(calls, name)
+				return nil
+			},
+			noop,
+		))
+	}
+	require.Nil(t, saga.Execute(context.Background()))
+	require.ElementsMatch(t, []string{"step1", "step2", "step3", "step4"}, calls)
+}
+
+func TestStepSorters(t *testing.T) {
+	names := func(steps []Step) []string {
+		var names []string
+		for _, step := range steps {
+			names = append(names, step.Name())
+		}
+		return names
+	}
+	newSteps := func() []Step {
+		return []Step{
+			NewStepWithOptions("charge", noop, noop, WithStepWeight(3)),
+			NewStep("notify", noop, noop),
+			NewStepWithOptions("audit", noop, noop, WithStepWeight(0.5)),
+			NewStepWithOptions("reserve", noop, noop, WithStepWeight(2)),
+		}
+	}
+	require.Equal(t, []string{"audit", "charge", "notify", "reserve"}, names(ByNameSorter()(newSteps())))
+	require.Equal(t, []string{"audit", "notify", "reserve", "charge"}, names(ByWeightSorter()(newSteps())))
+	shuffled := names(StableRandomSorter(42)(newSteps()))
+	require.Equal(t, shuffled, names(StableRandomSorter(42)(newSteps())))
+	require.ElementsMatch(t, names(newSteps()), shuffled)
+}
+
+func TestWithStepSorter_NameKeyedState(t *testing.T) {
+	sm := NewInMemoryStateManager()
+	var calls []string
+	fail := true
+	newSaga := func(names ...string) Saga {
+		saga := New(WithStateManager(sm), WithStepSorter(ByNameSorter()))
+		for _, name := range names {
+			name := name
+			saga.AddStep(NewStep(name,
+				func(ctx context.Context) error {
+					calls = append(calls, name)
+					if name == "b" && fail {
+						return errors.New("b error")
+					}
+					return nil
+				},
+				noop,
+			))
+		}
+		return saga
+	}
+
+	require.NotNil(t, newSaga("c", "a", "b").Execute(context.Background()))
+	require.Equal(t, []string{"a", "b"}, calls)
+	completed, err := sm.NamedStepState("a")
+	require.Nil(t, err)
+	require.True(t, completed)
+
+	// Added in another order, a is still known as completed.
+	calls = nil
+	fail = false
+	require.Nil(t, newSaga("b", "a", "c").Execute(context.Background()))
+	require.Equal(t, []string{"b", "c"}, calls)
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// StepFactory defines a function type that builds a Step
+// with the given name from a set of parameters.
+type StepFactory func(name string, params map[string]any) (Step, error)
+
+// stepRegistration holds a registered step factory along
+// with the parameters it requires.
+type stepRegistration struct {
+	factory        StepFactory
+	requiredParams []string
+}
+
+// StepRegistry maps step type names to the factories used to build
+// them. It is the registry used by the Sagas loaded from configuration
+// files (see NewFromConfig) and restored by Deserialize; TypeRegistry
+// is a separate registry of step type constructors, used by Build.
+// It is safe for concurrent use.
+type StepRegistry struct {
+	registrations map[string]stepRegistration
+	mu            sync.RWMutex
+}
+
+// NewStepRegistry creates a new, empty StepRegistry.
+func NewStepRegistry() *StepRegistry {
+	return &StepRegistry{
+		registrations: make(map[string]stepRegistration),
+	}
+}
+
+// Register associates the given step type with a factory.
+// requiredParams lists the parameters that must be present
+// when building a step of this type.
+func (r *StepRegistry) Register(stepType string, factory StepFactory, requiredParams ...string) error {
+	if stepType == "" {
+		return errors.New("step type is required")
+	}
+	if factory == nil {
+		return errors.Errorf("factory for step type %s is required", stepType)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.registrations[stepType]; exists {
+		return errors.Errorf("step type %s is already registered", stepType)
+	}
+	r.registrations[stepType] = stepRegistration{
+		factory:        factory,
+		requiredParams: requiredParams,
+	}
+	return nil
+}
+
+// Types returns the registered step types, sorted by name.
+func (r *StepRegistry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	types := make([]string, 0, len(r.registrations))
+	for stepType := range r.registrations {
+		types = append(types, stepType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Build creates a step of the given type, validating that all
+// required parameters are present. The step records the type and
+// parameters it was built from, so that it can be rebuilt by
+// Deserialize after being serialized (see Serializable).
+func (r *StepRegistry) Build(stepType, name string, params map[string]any) (Step, error) {
+	r.mu.RLock()
+	reg, exists := r.registrations[stepType]
+	r.mu.RUnlock()
+	if !exists {
+		return nil, errors.Errorf("unknown step type %q", stepType)
+	}
+	for _, param := range reg.requiredParams {
+		if _, ok := params[param]; !ok {
+			return nil, errors.Errorf("missing required param %q for step type %s", param, stepType)
+		}
+	}
+	step, err := reg.factory(name, params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "building step %s of type %s", name, stepType)
+	}
+	return &registeredStep{Step: step, stepType: stepType, params: params}, nil
+}
+
+// registeredStep wraps a step built by a StepRegistry, recording
+// the step type and parameters it was built from.
+type registeredStep struct {
+	Step
+	stepType string
+	params   map[string]any
+}
+
+func (s *registeredStep) Unwrap() Step {
+	return s.Step
+}
@@ -0,0 +1,368 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStep_CompensationTimeout(t *testing.T) {
+	testCases := []struct {
+		name          string
+		compensate    func(ctx context.Context) error
+		expectedError error
+	}{
+		{
+			name:       "compensation finishes in time",
+			compensate: noop,
+		},
+		{
+			name: "compensation fails in time",
+			compensate: func(ctx context.Context) error {
+				return errors.New("compensate error")
+			},
+			expectedError: errors.New("compensate error"),
+		},
+		{
+			name: "compensation hangs",
+			compensate: func(ctx context.Context) error {
+				// ignores the context on purpose.
+				time.Sleep(time.Second)
+				return nil
+			},
+			expectedError: errors.New("compensation of step step1 timed out after 10ms"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step := NewStepWithOptions("step1", noop, tc.compensate, WithCompensationTimeout(10*time.Millisecond))
+			err := step.ExecuteCompensate(context.Background())
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError.Error(), err.Error())
+			} else {
+				require.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestSaga_CompensationTimeoutCollected(t *testing.T) {
+	saga := New()
+	saga.AddStep(NewStepWithOptions("step1", noop,
+		func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		},
+		WithCompensationTimeout(10*time.Millisecond),
+	))
+	saga.AddStep(NewStep("step2",
+		func(ctx context.Context) error {
+			return errors.New("step2 error")
+		},
+		noop,
+	))
+	err := saga.Execute(context.Background())
+	require.NotNil(t, err)
+	var timeoutErr *CompensationTimeoutError
+	require.True(t, errors.As(err, &timeoutErr))
+	require.Equal(t, "step1", timeoutErr.StepName)
+}
+
+func TestStep_MutationVerifier(t *testing.T) {
+	testCases := []struct {
+		name          string
+		compensate    func(state map[string]any) error
+		expectedError error
+	}{
+		{
+			name: "compensation restores the state",
+			compensate: func(state map[string]any) error {
+				state["balance"] = 100
+				return nil
+			},
+		},
+		{
+			name: "compensation does not restore the state",
+			compensate: func(state map[string]any) error {
+				return nil
+			},
+			expectedError: errors.New("mutation verification failed for step debit: balance 100 became 50"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			state := map[string]any{"balance": 100}
+			step := NewStepWithOptions("debit",
+				func(ctx context.Context) error {
+					state["balance"] = 50
+					return nil
+				},
+				func(ctx context.Context) error {
+					return tc.compensate(state)
+				},
+				WithStateCapture(func(ctx context.Context) (map[string]any, error) {
+					return map[string]any{"balance": state["balance"]}, nil
+				}),
+				WithMutationVerifier(func(ctx context.Context, before, after map[string]any) error {
+					if before["balance"] != after["balance"] {
+						return fmt.Errorf("balance %v became %v", before["balance"], after["balance"])
+					}
+					return nil
+				}),
+			)
+			require.Nil(t, step.ExecuteForward(context.Background()))
+			err := step.ExecuteCompensate(context.Background())
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError.Error(), err.Error())
+				var verificationErr *MutationVerificationError
+				require.True(t, errors.As(err, &verificationErr))
+			} else {
+				require.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestStep_Retry(t *testing.T) {
+	errPermanent := errors.New("permanent error")
+	testCases := []struct {
+		name             string
+		forwardErrors    []error
+		options          []StepOption
+		expectedAttempts int
+		expectedError    error
+	}{
+		{
+			name:             "no retry",
+			forwardErrors:    []error{errors.New("error 1"), nil},
+			expectedAttempts: 1,
+			expectedError:    errors.New("error 1"),
+		},
+		{
+			name:             "succeeds after retry",
+			forwardErrors:    []error{errors.New("error 1"), errors.New("error 2"), nil},
+			options:          []StepOption{WithRetry(3, time.Millisecond)},
+			expectedAttempts: 3,
+		},
+		{
+			name:             "attempts exhausted",
+			forwardErrors:    []error{errors.New("error 1"), errors.New("error 2"), nil},
+			options:          []StepOption{WithRetry(2, 0)},
+			expectedAttempts: 2,
+			expectedError:    errors.New("error 2"),
+		},
+		{
+			name:             "succeeds within max elapsed time",
+			forwardErrors:    []error{errors.New("error 1"), errors.New("error 2"), nil},
+			options:          []StepOption{WithRetryMaxElapsed(time.Second, time.Millisecond, 2)},
+			expectedAttempts: 3,
+		},
+		{
+			// attempts at 0, 10, 30 and 70ms; the next one would start at 150ms.
+			name: "max elapsed time exceeded",
+			forwardErrors: []error{
+				errors.New("error 1"),
+				errors.New("error 2"),
+				errors.New("error 3"),
+				errors.New("error 4"),
+				errors.New("error 5"),
+			},
+			options:          []StepOption{WithRetryMaxElapsed(100*time.Millisecond, 10*time.Millisecond, 2)},
+			expectedAttempts: 4,
+			expectedError:    errors.New("error 4"),
+		},
+		{
+			name:          "non-retriable error",
+			forwardErrors: []error{errPermanent, nil},
+			options: []StepOption{
+				WithRetry(3, 0),
+				WithErrorClassifier(func(err error) bool {
+					return !errors.Is(err, errPermanent)
+				}),
+			},
+			expectedAttempts: 1,
+			expectedError:    errPermanent,
+		},
+		{
+			name: "attempt-based classifier gives up",
+			forwardErrors: []error{
+				errors.New("unavailable 1"),
+				errors.New("unavailable 2"),
+				errors.New("unavailable 3"),
+				nil,
+			},
+			options: []StepOption{
+				WithRetry(5, 0),
+				WithAttemptBasedErrorClassifier(func(attempt int, err error) bool {
+					return attempt < 3
+				}),
+			},
+			expectedAttempts: 3,
+			expectedError:    errors.New("unavailable 3"),
+		},
+		{
+			name:          "attempt-based classifier combined with error classifier",
+			forwardErrors: []error{errors.New("error 1"), errPermanent, nil},
+			options: []StepOption{
+				WithRetry(5, 0),
+				WithAttemptBasedErrorClassifier(func(attempt int, err error) bool {
+					return attempt < 3
+				}),
+				WithErrorClassifier(func(err error) bool {
+					return !errors.Is(err, errPermanent)
+				}),
+			},
+			expectedAttempts: 2,
+			expectedError:    errPermanent,
+		},
+		{
+			name:          "input validation error is not retried",
+			forwardErrors: []error{nil},
+			options: []StepOption{
+				WithRetry(3, 0),
+				WithInputValidation(func(ctx context.Context) error {
+					return errors.New("amount must be positive")
+				}),
+			},
+			expectedAttempts: 0,
+			expectedError:    errors.New("invalid input for step step1: amount must be positive"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			attempts := 0
+			step := NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					err := tc.forwardErrors[attempts]
+					attempts++
+					return err
+				},
+				noop,
+				tc.options...,
+			)
+			err := step.ExecuteForward(context.Background())
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError.Error(), err.Error())
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, tc.expectedAttempts, attempts)
+		})
+	}
+}
+
+func TestStep_RetryNotification(t *testing.T) {
+	type notification struct {
+		stepName  string
+		attempt   int
+		err       string
+		nextDelay time.Duration
+	}
+	var notifications []notification
+	attempts := 0
+	step := NewStepWithOptions("step1",
+		func(ctx context.Context) error {
+			attempts++
+			return fmt.Errorf("error %d", attempts)
+		},
+		noop,
+		WithRetry(3, 0),
+		WithRetryMaxElapsed(time.Second, time.Millisecond, 2),
+		WithRetryNotification(func(ctx context.Context, stepName string, attempt int, err error, nextDelay time.Duration) {
+			notifications = append(notifications, notification{stepName, attempt, err.Error(), nextDelay})
+		}),
+	)
+	require.EqualError(t, step.ExecuteForward(context.Background()), "error 3")
+	require.Equal(t, []notification{
+		{"step1", 1, "error 1", time.Millisecond},
+		{"step1", 2, "error 2", 2 * time.Millisecond},
+	}, notifications)
+}
+
+func TestStep_Override(t *testing.T) {
+	testCases := []struct {
+		name                   string
+		opts                   []StepOption
+		expectedForwardErrs    []string
+		expectedCompensateErrs []string
+	}{
+		{
+			name:                   "one-shot override",
+			expectedForwardErrs:    []string{"fake forward error", "forward error"},
+			expectedCompensateErrs: []string{"fake compensate error", "compensate error"},
+		},
+		{
+			name:                   "persistent override",
+			opts:                   []StepOption{WithPersistentOverride()},
+			expectedForwardErrs:    []string{"fake forward error", "fake forward error"},
+			expectedCompensateErrs: []string{"fake compensate error", "fake compensate error"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step := NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					return errors.New("forward error")
+				},
+				func(ctx context.Context) error {
+					return errors.New("compensate error")
+				},
+				tc.opts...,
+			)
+			o, ok := step.(OverridableStep)
+			require.True(t, ok)
+			o.OverrideForward(func(ctx context.Context) error {
+				return errors.New("fake forward error")
+			})
+			o.OverrideCompensate(func(ctx context.Context) error {
+				return errors.New("fake compensate error")
+			})
+			for _, expectedErr := range tc.expectedForwardErrs {
+				require.EqualError(t, step.ExecuteForward(context.Background()), expectedErr)
+			}
+			for _, expectedErr := range tc.expectedCompensateErrs {
+				require.EqualError(t, step.ExecuteCompensate(context.Background()), expectedErr)
+			}
+		})
+	}
+}
+
+func TestStepKey(t *testing.T) {
+	testCases := []struct {
+		name        string
+		step        Step
+		expectedKey string
+	}{
+		{
+			name:        "without alias",
+			step:        NewStep("step1", noop, noop),
+			expectedKey: "step1",
+		},
+		{
+			name:        "with alias",
+			step:        NewStepWithOptions("step1", noop, noop, WithStepAlias("old-step1")),
+			expectedKey: "old-step1",
+		},
+		{
+			name:        "wrapped step with alias",
+			step:        AsOptional(NewStepWithOptions("step1", noop, noop, WithStepAlias("old-step1"))),
+			expectedKey: "old-step1",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedKey, stepKey(tc.step))
+		})
+	}
+}
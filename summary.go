@@ -0,0 +1,82 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import "time"
+
+// Summary holds information about the current
+// (or last) execution of a Saga.
+type Summary struct {
+	// SagaID is the identifier of the Saga, if any.
+	SagaID string
+
+	// ProgressPercent is the progress of the execution.
+	ProgressPercent float64
+
+	// SkippedSteps lists the optional steps that failed
+	// and were skipped, along with the reason.
+	SkippedSteps []StepResult
+
+	// Checkpoints lists the names of the checkpoints
+	// reached, in the order they were reached.
+	Checkpoints []string
+
+	// FailedStep is the step that caused the Saga to fail, if any.
+	FailedStep *StepResult
+
+	// CompensatedSteps lists the steps successfully
+	// compensated, in the order they were compensated.
+	CompensatedSteps []StepResult
+
+	// StepTimings lists the timings of the executed
+	// and compensated steps, ordered by step index.
+	StepTimings []StepTiming
+}
+
+// StepTiming holds the timing of a step execution.
+type StepTiming struct {
+	StepName           string
+	StepIndex          int
+	ForwardDuration    time.Duration
+	CompensateDuration time.Duration
+
+	// TotalAttempts is the number of attempts
+	// of the forward action, including retries.
+	TotalAttempts int
+}
+
+// SlowestStep returns the timing of the step whose forward action
+// took the longest, or a zero StepTiming if no step was executed.
+func (s Summary) SlowestStep() StepTiming {
+	var slowest StepTiming
+	for i, t := range s.StepTimings {
+		if i == 0 || t.ForwardDuration > slowest.ForwardDuration {
+			slowest = t
+		}
+	}
+	return slowest
+}
+
+// FastestStep returns the timing of the step whose forward action
+// took the shortest, or a zero StepTiming if no step was executed.
+func (s Summary) FastestStep() StepTiming {
+	var fastest StepTiming
+	for i, t := range s.StepTimings {
+		if i == 0 || t.ForwardDuration < fastest.ForwardDuration {
+			fastest = t
+		}
+	}
+	return fastest
+}
+
+// StepResult holds the outcome of a step execution.
+type StepResult struct {
+	StepName  string
+	StepIndex int
+	Err       error
+
+	// Category is the category of Err (see WithErrorCategorizer).
+	Category ErrorCategory
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaga_StepTimings(t *testing.T) {
+	sleep := func(d time.Duration) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			time.Sleep(d)
+			return nil
+		}
+	}
+	attempts := 0
+	saga := New()
+	saga.AddStep(NewStep("step1", sleep(20*time.Millisecond), sleep(10*time.Millisecond)))
+	saga.AddStep(NewStepWithOptions("step2",
+		func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("transient error")
+			}
+			return nil
+		},
+		noop,
+		WithRetry(3, 0),
+	))
+	saga.AddCheckpoint("checkpoint")
+	saga.AddStep(NewStep("step3",
+		func(ctx context.Context) error {
+			return errors.New("forward error")
+		},
+		noop,
+	))
+	require.NotNil(t, saga.Execute(context.Background()))
+
+	timings := saga.Summary().StepTimings
+	require.Len(t, timings, 3)
+	expected := []struct {
+		stepName      string
+		stepIndex     int
+		totalAttempts int
+	}{
+		{stepName: "step1", stepIndex: 0, totalAttempts: 1},
+		{stepName: "step2", stepIndex: 1, totalAttempts: 3},
+		{stepName: "step3", stepIndex: 3, totalAttempts: 1},
+	}
+	for i, e := range expected {
+		require.Equal(t, e.stepName, timings[i].StepName)
+		require.Equal(t, e.stepIndex, timings[i].StepIndex)
+		require.Equal(t, e.totalAttempts, timings[i].TotalAttempts)
+	}
+	require.GreaterOrEqual(t, timings[0].ForwardDuration, 20*time.Millisecond)
+	require.GreaterOrEqual(t, timings[0].CompensateDuration, 10*time.Millisecond)
+	require.Equal(t, "step1", saga.Summary().SlowestStep().StepName)
+}
+
+func TestSaga_StepTimings_Clock(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	advance := func(d time.Duration) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			clock.Advance(d)
+			return nil
+		}
+	}
+	sm := NewInMemoryStateManager()
+	// The first two steps were completed by a previous execution.
+	require.Nil(t, sm.SetStepState(0, true))
+	require.Nil(t, sm.SetStepState(1, true))
+	saga := New(WithClock(clock), WithStateManager(sm))
+	saga.AddStep(NewStep("step1", noop, advance(time.Second)))
+	saga.AddStep(NewStep("step2", noop, advance(2*time.Second)))
+	saga.AddStep(NewStep("step3",
+		func(ctx context.Context) error {
+			clock.Advance(3 * time.Second)
+			return errors.New("forward error")
+		},
+		noop,
+	))
+	require.NotNil(t, saga.Execute(context.Background()))
+
+	require.Equal(t, []StepTiming{
+		{StepName: "step1", StepIndex: 0, CompensateDuration: time.Second},
+		{StepName: "step2", StepIndex: 1, CompensateDuration: 2 * time.Second},
+		{StepName: "step3", StepIndex: 2, ForwardDuration: 3 * time.Second, TotalAttempts: 1},
+	}, saga.Summary().StepTimings)
+}
+
+func TestSummary_SlowestAndFastestStep(t *testing.T) {
+	testCases := []struct {
+		name            string
+		summary         Summary
+		expectedSlowest StepTiming
+		expectedFastest StepTiming
+	}{
+		{
+			name: "no steps",
+		},
+		{
+			name: "steps",
+			summary: Summary{
+				StepTimings: []StepTiming{
+					{StepName: "step1", ForwardDuration: 2 * time.Second},
+					{StepName: "step2", ForwardDuration: 3 * time.Second},
+					{StepName: "step3", ForwardDuration: time.Second},
+				},
+			},
+			expectedSlowest: StepTiming{StepName: "step2", ForwardDuration: 3 * time.Second},
+			expectedFastest: StepTiming{StepName: "step3", ForwardDuration: time.Second},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expectedSlowest, tc.summary.SlowestStep())
+			require.Equal(t, tc.expectedFastest, tc.summary.FastestStep())
+		})
+	}
+}
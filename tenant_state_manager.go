@@ -0,0 +1,120 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// TenantAwareStateManager is a StateManager decorator that isolates
+// the state of each tenant in a shared inner NamedStateManager.
+//
+// The state of each step is stored in the inner StateManager under the
+// key "{tenantID}:{stepIndex}", or "{tenantID}:n:{key}" when stored by
+// key (see NamedStateManager). Since tenant IDs cannot contain ':', the
+// keys of different tenants never collide. When the inner StateManager
+// is a NamedVersionedStateManager, the TenantAwareStateManager is also
+// a VersionedStateManager and a NamedVersionedStateManager.
+//
+// Step metadata and outputs, which are stored by step
+// index only, are not supported.
+type TenantAwareStateManager struct {
+	inner    NamedStateManager
+	tenantID string
+}
+
+// versionedTenantAwareStateManager is a TenantAwareStateManager
+// whose inner StateManager is a NamedVersionedStateManager.
+type versionedTenantAwareStateManager struct {
+	*TenantAwareStateManager
+	inner NamedVersionedStateManager
+}
+
+// NewTenantAwareStateManager creates a new StateManager that namespaces
+// all the state it keeps in inner by the given tenant ID. It fails if
+// inner is not a NamedStateManager, since the states of different
+// tenants could not be kept apart, or if the tenant ID contains ':'.
+func NewTenantAwareStateManager(inner StateManager, tenantID string) (StateManager, error) {
+	nm, ok := stateManagerAs[NamedStateManager](inner)
+	if !ok {
+		return nil, errors.New("tenant isolation requires a NamedStateManager")
+	}
+	if strings.Contains(tenantID, ":") {
+		return nil, errors.Errorf("invalid tenant ID %q: it must not contain ':'", tenantID)
+	}
+	m := &TenantAwareStateManager{
+		inner:    nm,
+		tenantID: tenantID,
+	}
+	if vm, ok := stateManagerAs[NamedVersionedStateManager](inner); ok {
+		return &versionedTenantAwareStateManager{TenantAwareStateManager: m, inner: vm}, nil
+	}
+	return m, nil
+}
+
+func (m *TenantAwareStateManager) SetStepState(stepIndex int, success bool) error {
+	return m.inner.SetNamedStepState(m.indexKey(stepIndex), success)
+}
+
+func (m *TenantAwareStateManager) StepState(stepIndex int) (bool, error) {
+	return m.inner.NamedStepState(m.indexKey(stepIndex))
+}
+
+func (m *TenantAwareStateManager) SetNamedStepState(key string, success bool) error {
+	return m.inner.SetNamedStepState(m.namedKey(key), success)
+}
+
+func (m *TenantAwareStateManager) NamedStepState(key string) (bool, error) {
+	return m.inner.NamedStepState(m.namedKey(key))
+}
+
+// indexKey returns the key used in the inner
+// StateManager for the given step index.
+func (m *TenantAwareStateManager) indexKey(stepIndex int) string {
+	return m.tenantID + ":" + strconv.Itoa(stepIndex)
+}
+
+// namedKey returns the key used in the inner
+// StateManager for the given step key.
+func (m *TenantAwareStateManager) namedKey(key string) string {
+	return m.tenantID + ":n:" + key
+}
+
+func (m *versionedTenantAwareStateManager) SetStepStateVersioned(stepIndex int, success bool, expectedVersion int) (int, error) {
+	return m.inner.SetNamedStepStateVersioned(m.indexKey(stepIndex), success, expectedVersion)
+}
+
+func (m *versionedTenantAwareStateManager) StepStateWithVersion(stepIndex int) (bool, int, error) {
+	return m.inner.NamedStepStateWithVersion(m.indexKey(stepIndex))
+}
+
+func (m *versionedTenantAwareStateManager) SetNamedStepStateVersioned(key string, success bool, expectedVersion int) (int, error) {
+	return m.inner.SetNamedStepStateVersioned(m.namedKey(key), success, expectedVersion)
+}
+
+func (m *versionedTenantAwareStateManager) NamedStepStateWithVersion(key string) (bool, int, error) {
+	return m.inner.NamedStepStateWithVersion(m.namedKey(key))
+}
+
+// TenantAwareStateManagerFactory creates tenant-aware
+// state managers sharing the same inner StateManager.
+type TenantAwareStateManagerFactory struct {
+	inner StateManager
+}
+
+// NewTenantAwareFactory creates a new TenantAwareStateManagerFactory
+// for the given inner StateManager.
+func NewTenantAwareFactory(inner StateManager) *TenantAwareStateManagerFactory {
+	return &TenantAwareStateManagerFactory{inner: inner}
+}
+
+// ForTenant returns a StateManager isolated to the given
+// tenant (see NewTenantAwareStateManager).
+func (f *TenantAwareStateManagerFactory) ForTenant(id string) (StateManager, error) {
+	return NewTenantAwareStateManager(f.inner, id)
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantAwareStateManager(t *testing.T) {
+	inner := NewInMemoryStateManager()
+	factory := NewTenantAwareFactory(inner)
+	tenantA, err := factory.ForTenant("a")
+	require.Nil(t, err)
+	tenantB, err := factory.ForTenant("b")
+	require.Nil(t, err)
+
+	require.Nil(t, tenantA.SetStepState(0, true))
+
+	completed, err := tenantA.StepState(0)
+	require.Nil(t, err)
+	require.True(t, completed)
+
+	// State from tenant A does not bleed into tenant B.
+	completed, err = tenantB.StepState(0)
+	require.Nil(t, err)
+	require.False(t, completed)
+
+	// Nor into the inner state manager's own keys.
+	completed, err = inner.StepState(0)
+	require.Nil(t, err)
+	require.False(t, completed)
+	completed, err = inner.NamedStepState("a:0")
+	require.Nil(t, err)
+	require.True(t, completed)
+
+	// States stored by key do not collide with states stored by index.
+	named := tenantB.(NamedStateManager)
+	require.Nil(t, named.SetNamedStepState("0", true))
+	completed, err = tenantB.StepState(0)
+	require.Nil(t, err)
+	require.False(t, completed)
+	completed, err = named.NamedStepState("0")
+	require.Nil(t, err)
+	require.True(t, completed)
+
+	// Versioned state is forwarded to the inner state manager.
+	vm, ok := tenantA.(VersionedStateManager)
+	require.True(t, ok)
+	_, version, err := vm.StepStateWithVersion(0)
+	require.Nil(t, err)
+	require.Equal(t, 1, version)
+	_, err = vm.SetStepStateVersioned(0, false, 0)
+	require.EqualError(t, err, `state of step "a:0" was modified concurrently: expected version 0, got 1`)
+	version, err = vm.SetStepStateVersioned(0, false, 1)
+	require.Nil(t, err)
+	require.Equal(t, 2, version)
+}
+
+func TestNewTenantAwareStateManager(t *testing.T) {
+	testCases := []struct {
+		name              string
+		inner             StateManager
+		tenantID          string
+		expectedVersioned bool
+		expectedError     string
+	}{
+		{
+			name:              "named versioned state manager",
+			inner:             NewInMemoryStateManager(),
+			tenantID:          "acme",
+			expectedVersioned: true,
+		},
+		{
+			name:     "named state manager",
+			inner:    &namedOnlyStateManager{NewInMemoryStateManager()},
+			tenantID: "acme",
+		},
+		{
+			name:          "state manager without keys",
+			inner:         &mockStateManager{},
+			tenantID:      "acme",
+			expectedError: "tenant isolation requires a NamedStateManager",
+		},
+		{
+			name:          "invalid tenant ID",
+			inner:         NewInMemoryStateManager(),
+			tenantID:      "acme:eu",
+			expectedError: `invalid tenant ID "acme:eu": it must not contain ':'`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sm, err := NewTenantAwareStateManager(tc.inner, tc.tenantID)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.Nil(t, err)
+			_, ok := sm.(NamedStateManager)
+			require.True(t, ok)
+			_, ok = sm.(VersionedStateManager)
+			require.Equal(t, tc.expectedVersioned, ok)
+		})
+	}
+}
+
+// namedOnlyStateManager hides the versioning
+// of the NamedStateManager it wraps.
+type namedOnlyStateManager struct {
+	inner *InMemoryStateManager
+}
+
+func (m *namedOnlyStateManager) SetStepState(stepIndex int, success bool) error {
+	return m.inner.SetStepState(stepIndex, success)
+}
+
+func (m *namedOnlyStateManager) StepState(stepIndex int) (bool, error) {
+	return m.inner.StepState(stepIndex)
+}
+
+func (m *namedOnlyStateManager) SetNamedStepState(key string, success bool) error {
+	return m.inner.SetNamedStepState(key, success)
+}
+
+func (m *namedOnlyStateManager) NamedStepState(key string) (bool, error) {
+	return m.inner.NamedStepState(key)
+}
+
+func TestSaga_WithTenantID(t *testing.T) {
+	inner := NewInMemoryStateManager()
+	calls := 0
+	newSaga := func(tenantID string, opts ...Option) Saga {
+		s := New(append([]Option{WithTenantID(tenantID), WithStateManager(inner)}, opts...)...)
+		s.AddStep(NewStep("step1",
+			func(ctx context.Context) error {
+				calls++
+				return nil
+			},
+			noop,
+		))
+		return s
+	}
+
+	require.Nil(t, newSaga("a").Execute(context.Background()))
+	require.Nil(t, newSaga("b").Execute(context.Background()))
+	require.Equal(t, 2, calls)
+
+	// Tenant A's step is already completed.
+	require.Nil(t, newSaga("a").Execute(context.Background()))
+	require.Equal(t, 2, calls)
+
+	// Name-keyed state is isolated too.
+	require.Nil(t, newSaga("a", WithStepSorter(ByNameSorter())).Execute(context.Background()))
+	require.Nil(t, newSaga("b", WithStepSorter(ByNameSorter())).Execute(context.Background()))
+	require.Equal(t, 4, calls)
+	completed, err := inner.NamedStepState("b:n:step1")
+	require.Nil(t, err)
+	require.True(t, completed)
+
+	// A state manager that cannot keep tenants apart is rejected.
+	s := New(WithTenantID("a"), WithStateManager(&mockStateManager{}))
+	s.AddStep(NewStep("step1", noop, noop))
+	require.EqualError(t, s.Execute(context.Background()), "isolating tenant state: tenant isolation requires a NamedStateManager")
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"time"
+)
+
+// WithTimeBudget option makes the step check, before running its
+// forward action, the time remaining until the deadline of its context.
+// If less than budget remains, the forward action is not run and the
+// step result is the one returned by budgetExhausted, called with the
+// remaining time, e.g. to return a cached result or to skip the step
+// gracefully instead of timing out. Contexts without a deadline always
+// have enough time.
+func WithTimeBudget(budget time.Duration, budgetExhausted func(ctx context.Context, remaining time.Duration) error) StepOption {
+	return func(s *step) {
+		s.timeBudget = budget
+		s.budgetExhausted = budgetExhausted
+	}
+}
+
+// checkTimeBudget reports whether the time budget of the step is
+// exhausted, along with the step result to return in that case.
+func (s *step) checkTimeBudget(ctx context.Context) (bool, error) {
+	if s.budgetExhausted == nil {
+		return false, nil
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return false, nil
+	}
+	remaining := deadline.Sub(clockFromContext(ctx).Now())
+	if remaining >= s.timeBudget {
+		return false, nil
+	}
+	return true, s.budgetExhausted(ctx, remaining)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithTimeBudget(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name              string
+		deadline          time.Time
+		budgetResult      error
+		expectedForward   bool
+		expectedRemaining time.Duration
+		expectedError     error
+	}{
+		{
+			name:            "no deadline",
+			expectedForward: true,
+		},
+		{
+			name:            "enough time",
+			deadline:        now.Add(time.Minute),
+			expectedForward: true,
+		},
+		{
+			name:              "budget exhausted, step skipped gracefully",
+			deadline:          now.Add(5 * time.Second),
+			expectedRemaining: 5 * time.Second,
+		},
+		{
+			name:              "budget exhausted, step failed",
+			deadline:          now.Add(time.Second),
+			budgetResult:      errors.New("not enough time"),
+			expectedRemaining: time.Second,
+			expectedError:     errors.New("not enough time"),
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if !tc.deadline.IsZero() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithDeadline(ctx, tc.deadline)
+				defer cancel()
+			}
+			forwarded := false
+			var remaining time.Duration
+			step := NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					forwarded = true
+					return nil
+				},
+				noop,
+				WithTimeBudget(10*time.Second, func(ctx context.Context, r time.Duration) error {
+					remaining = r
+					return tc.budgetResult
+				}),
+				WithStepClock(NewFakeClock(now)),
+			)
+			err := step.ExecuteForward(ctx)
+			require.Equal(t, tc.expectedError, err)
+			require.Equal(t, tc.expectedForward, forwarded)
+			require.Equal(t, tc.expectedRemaining, remaining)
+		})
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"time"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// NewTimeoutStep creates a new Step whose forward action is bounded by
+// the given timeout. If the timeout fires before the forward action
+// completes, its context is canceled and a *StepTimeoutError is returned
+// without waiting for it to return.
+func NewTimeoutStep(name string, timeout time.Duration, forward func(ctx context.Context) error, compensate func(ctx context.Context) error) Step {
+	return NewTimeoutStepWithOptions(name, timeout, forward, compensate)
+}
+
+// NewTimeoutStepWithOptions creates a new Step whose forward action is
+// bounded by the given timeout, with the given step options. If the step
+// is retried, the timeout covers all the attempts, unless
+// WithRetryResetTimeout is used.
+func NewTimeoutStepWithOptions(name string, timeout time.Duration, forward func(ctx context.Context) error, compensate func(ctx context.Context) error, opts ...StepOption) Step {
+	s := newStep(name, forward, compensate, opts)
+	s.forwardTimeout = timeout
+	return s
+}
+
+// withTimeout bounds the given forward action of the step with the
+// given name by the given timeout, which is the deadline of the
+// context passed to the action.
+func withTimeout(name string, timeout time.Duration, forward func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		tctx, cancel := withClockTimeout(ctx, timeout)
+		defer cancel()
+		g, gctx := errgroup.WithContext(tctx)
+		g.Go(func() error {
+			return forward(gctx)
+		})
+		done := make(chan error, 1)
+		go func() {
+			done <- g.Wait()
+		}()
+		select {
+		case err := <-done:
+			if err != nil && ctx.Err() == nil && tctx.Err() == context.DeadlineExceeded {
+				return &StepTimeoutError{StepName: name, Timeout: timeout}
+			}
+			return err
+		case <-tctx.Done():
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			return &StepTimeoutError{StepName: name, Timeout: timeout}
+		}
+	}
+}
+
+// WithProgressiveTimeout option bounds the forward action of the step,
+// retries included, by a timeout that is extended as long as the action
+// makes progress, e.g. for batch processing. Every baseTimeout/10, the
+// extender is called: if it reports progress, the timeout is pushed
+// back to baseTimeout from then. If it reports no progress twice in a
+// row, or the timeout expires, the forward action's context is canceled
+// and a *StepTimeoutError is returned without waiting for it to return.
+func WithProgressiveTimeout(baseTimeout time.Duration, extender func(ctx context.Context) bool) StepOption {
+	return func(s *step) {
+		s.progressiveTimeout = baseTimeout
+		s.progressExtender = extender
+	}
+}
+
+// withProgressiveTimeout bounds the given forward action of the step
+// with the given name by a timeout extended while the extender reports
+// progress. See WithProgressiveTimeout.
+func withProgressiveTimeout(name string, base time.Duration, extender func(ctx context.Context) bool, forward func(ctx context.Context) error) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		clock := clockFromContext(ctx)
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		g, gctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			return forward(gctx)
+		})
+		done := make(chan error, 1)
+		go func() {
+			done <- g.Wait()
+		}()
+		interval := base / 10
+		if interval <= 0 {
+			interval = base
+		}
+		deadline := clock.Now().Add(base)
+		stalled := 0
+		timer := clock.NewTimer(interval)
+		defer func() {
+			timer.Stop()
+		}()
+		for {
+			select {
+			case err := <-done:
+				return err
+			case now := <-timer.C():
+				timer = clock.NewTimer(interval)
+				if extender(ctx) {
+					stalled = 0
+					deadline = now.Add(base)
+					continue
+				}
+				stalled++
+				if stalled >= 2 || !now.Before(deadline) {
+					return &StepTimeoutError{StepName: name, Timeout: base}
+				}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+}
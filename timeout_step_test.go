@@ -0,0 +1,245 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTimeoutStep(t *testing.T) {
+	testCases := []struct {
+		name          string
+		forwardTime   time.Duration
+		forwardErr    error
+		ctxTimeout    time.Duration
+		expectedError error
+	}{
+		{
+			name:        "completion before timeout",
+			forwardTime: time.Millisecond,
+		},
+		{
+			name:          "forward error before timeout",
+			forwardTime:   time.Millisecond,
+			forwardErr:    errors.New("forward error"),
+			expectedError: errors.New("forward error"),
+		},
+		{
+			name:          "timeout before completion",
+			forwardTime:   time.Hour,
+			expectedError: &StepTimeoutError{StepName: "step1", Timeout: 50 * time.Millisecond},
+		},
+		{
+			name:          "context cancellation while waiting",
+			forwardTime:   time.Hour,
+			ctxTimeout:    10 * time.Millisecond,
+			expectedError: context.DeadlineExceeded,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			canceled := make(chan struct{})
+			step := NewTimeoutStep("step1", 50*time.Millisecond,
+				func(ctx context.Context) error {
+					select {
+					case <-time.After(tc.forwardTime):
+						return tc.forwardErr
+					case <-ctx.Done():
+						close(canceled)
+						return ctx.Err()
+					}
+				},
+				noop,
+			)
+			ctx := context.Background()
+			if tc.ctxTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tc.ctxTimeout)
+				defer cancel()
+			}
+			err := step.ExecuteForward(ctx)
+			if tc.expectedError != nil {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError.Error(), err.Error())
+				if tc.forwardTime == time.Hour {
+					// the forward action is signaled to stop.
+					select {
+					case <-canceled:
+					case <-time.After(time.Second):
+						t.Fatal("forward action was not canceled")
+					}
+				}
+			} else {
+				require.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestNewTimeoutStep_RetryResetTimeout(t *testing.T) {
+	testCases := []struct {
+		name             string
+		reset            bool
+		expectedAttempts int32
+	}{
+		{
+			name:             "timeout shared by all attempts",
+			expectedAttempts: 1,
+		},
+		{
+			name:             "timeout reset for each attempt",
+			reset:            true,
+			expectedAttempts: 3,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Now())
+			var attempts atomic.Int32
+			step := NewTimeoutStepWithOptions("step1", 5*time.Second,
+				func(ctx context.Context) error {
+					attempts.Add(1)
+					<-ctx.Done()
+					return ctx.Err()
+				},
+				noop,
+				WithRetry(3, 0),
+				WithRetryResetTimeout(tc.reset),
+				WithStepClock(clock),
+			)
+			done := make(chan struct{})
+			go func() {
+				// Fire the timeout once per started attempt.
+				for ticks := int32(0); ; {
+					select {
+					case <-done:
+						return
+					default:
+					}
+					if attempts.Load() > ticks && clock.Waiters() > 0 {
+						clock.Tick()
+						ticks++
+					}
+					time.Sleep(time.Millisecond)
+				}
+			}()
+			err := step.ExecuteForward(context.Background())
+			close(done)
+			require.NotNil(t, err)
+			require.Equal(t, "step step1 timed out after 5s", err.Error())
+			require.Equal(t, tc.expectedAttempts, attempts.Load())
+		})
+	}
+}
+
+func TestNewTimeoutStep_CompensationTimeout(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       []StepOption
+		expectedError string
+	}{
+		{
+			name:          "defaults to twice the forward timeout",
+			expectedError: "compensation of step step1 timed out after 20ms",
+		},
+		{
+			name:          "explicit compensation timeout",
+			options:       []StepOption{WithCompensationTimeout(5 * time.Millisecond)},
+			expectedError: "compensation of step step1 timed out after 5ms",
+		},
+		{
+			name:    "disabled compensation timeout",
+			options: []StepOption{WithCompensationTimeout(-1)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step := NewTimeoutStepWithOptions("step1", 10*time.Millisecond, noop,
+				func(ctx context.Context) error {
+					// ignores the context on purpose.
+					time.Sleep(50 * time.Millisecond)
+					return nil
+				},
+				tc.options...,
+			)
+			err := step.ExecuteCompensate(context.Background())
+			if tc.expectedError != "" {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError, err.Error())
+				return
+			}
+			require.Nil(t, err)
+		})
+	}
+}
+
+func TestWithProgressiveTimeout(t *testing.T) {
+	testCases := []struct {
+		name          string
+		progress      func(check int) bool
+		finishAfter   int
+		expectedError string
+	}{
+		{
+			name:        "long step making progress",
+			progress:    func(check int) bool { return true },
+			finishAfter: 15,
+		},
+		{
+			name:        "intermittent progress",
+			progress:    func(check int) bool { return check%2 == 0 },
+			finishAfter: 15,
+		},
+		{
+			name:          "stuck step",
+			progress:      func(check int) bool { return check <= 3 },
+			finishAfter:   15,
+			expectedError: "step step1 timed out after 10s",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clock := NewFakeClock(time.Now())
+			finish := make(chan struct{})
+			checks := 0
+			step := NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					select {
+					case <-finish:
+						return nil
+					case <-ctx.Done():
+						return ctx.Err()
+					}
+				},
+				noop,
+				WithProgressiveTimeout(10*time.Second, func(ctx context.Context) bool {
+					checks++
+					if checks == tc.finishAfter {
+						close(finish)
+					}
+					return tc.progress(checks)
+				}),
+				WithStepClock(clock),
+			)
+			done := make(chan struct{})
+			go tickUntilDone(clock, done)
+			err := step.ExecuteForward(context.Background())
+			close(done)
+			if tc.expectedError != "" {
+				require.NotNil(t, err)
+				require.Equal(t, tc.expectedError, err.Error())
+				return
+			}
+			require.Nil(t, err)
+			require.GreaterOrEqual(t, checks, tc.finishAfter)
+		})
+	}
+}
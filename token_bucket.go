@@ -0,0 +1,61 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// WithTokenBucket option rate limits the forward action of the step
+// with a token bucket refilled with r tokens per second, holding up to
+// burst tokens. Each attempt of the forward action waits for a token,
+// following the Saga's clock (see WithClock). If the context is done
+// while waiting, or its deadline comes before the next token, the
+// attempt fails.
+func WithTokenBucket(r float64, burst int) StepOption {
+	return func(s *step) {
+		s.limiter = rate.NewLimiter(rate.Limit(r), burst)
+	}
+}
+
+// WithSharedTokenBucket option rate limits the forward action of the
+// step with the given limiter, which can be shared by several steps
+// calling the same external service. See WithTokenBucket.
+func WithSharedTokenBucket(limiter *rate.Limiter) StepOption {
+	return func(s *step) {
+		s.limiter = limiter
+	}
+}
+
+// waitToken waits for a token of the step's token bucket, if any.
+// The token is reserved at the time of the context's clock, which
+// the step then sleeps on until the token is available.
+func (s *step) waitToken(ctx context.Context) error {
+	if s.limiter == nil {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	clock := clockFromContext(ctx)
+	now := clock.Now()
+	reservation := s.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return errors.Errorf("waiting for token bucket of step %s: burst %d is lower than one token", s.name, s.limiter.Burst())
+	}
+	delay := reservation.DelayFrom(now)
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		reservation.CancelAt(now)
+		return errors.Errorf("waiting for token bucket of step %s: next token is available after the context deadline", s.name)
+	}
+	if err := sleepContext(ctx, delay); err != nil {
+		reservation.CancelAt(clock.Now())
+		return err
+	}
+	return nil
+}
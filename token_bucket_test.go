@@ -0,0 +1,111 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+// neverRefill is a refill rate slow enough
+// for the bucket to never refill.
+const neverRefill = rate.Limit(1e-6)
+
+func TestWithTokenBucket(t *testing.T) {
+	testCases := []struct {
+		name             string
+		burst            int
+		cancelled        bool
+		expectedForwards int
+		expectedError    string
+	}{
+		{
+			name:             "tokens available",
+			burst:            3,
+			expectedForwards: 3,
+		},
+		{
+			name:             "throttled",
+			burst:            2,
+			expectedForwards: 2,
+			expectedError:    "waiting for token bucket of step step1: next token is available after the context deadline",
+		},
+		{
+			name:          "context cancelled",
+			burst:         1,
+			cancelled:     true,
+			expectedError: "context canceled",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			forwards := 0
+			step := NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					forwards++
+					return nil
+				},
+				noop,
+				WithTokenBucket(float64(neverRefill), tc.burst),
+			)
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			if tc.cancelled {
+				cancel()
+			}
+			var err error
+			for i := 0; i < 3 && err == nil; i++ {
+				err = step.ExecuteForward(ctx)
+			}
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, tc.expectedForwards, forwards)
+		})
+	}
+}
+
+func TestWithTokenBucket_Refill(t *testing.T) {
+	clock := NewFakeClock(time.Now())
+	forwards := 0
+	step := NewStepWithOptions("step1",
+		func(ctx context.Context) error {
+			forwards++
+			return nil
+		},
+		noop,
+		WithTokenBucket(1, 1),
+		WithStepClock(clock),
+	)
+	require.Nil(t, step.ExecuteForward(context.Background()))
+
+	// The second execution waits for the bucket to refill.
+	done := make(chan error)
+	go func() {
+		done <- step.ExecuteForward(context.Background())
+	}()
+	require.Eventually(t, func() bool { return clock.Waiters() == 1 }, time.Second, time.Millisecond)
+	require.Equal(t, 1, forwards)
+	clock.Advance(time.Second)
+	require.Nil(t, <-done)
+	require.Equal(t, 2, forwards)
+}
+
+func TestWithSharedTokenBucket(t *testing.T) {
+	limiter := rate.NewLimiter(neverRefill, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	s := New()
+	s.AddStep(NewStepWithOptions("step1", noop, noop, WithSharedTokenBucket(limiter)))
+	s.AddStep(NewStepWithOptions("step2", noop, noop, WithSharedTokenBucket(limiter)))
+	require.EqualError(t, s.Execute(ctx),
+		"executing step step2: waiting for token bucket of step step2: next token is available after the context deadline")
+}
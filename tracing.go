@@ -0,0 +1,191 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracer option makes the Saga create an OpenTelemetry
+// span, using the given tracer, for each step execution.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(s *saga) {
+		s.tracer = tracer
+	}
+}
+
+// WithBaggagePropagation option adds the given OpenTelemetry baggage
+// members (e.g. "tenant.id", "user.id"), when present in the context,
+// as attributes of each step span. It requires WithTracer.
+func WithBaggagePropagation(keys ...string) Option {
+	return func(s *saga) {
+		s.baggageKeys = append(s.baggageKeys, keys...)
+	}
+}
+
+// WithBaggageInheritance option adds all the OpenTelemetry baggage
+// members present in the context as attributes of each step span.
+// It requires WithTracer.
+func WithBaggageInheritance() Option {
+	return func(s *saga) {
+		s.inheritBaggage = true
+	}
+}
+
+// WithSpanAttributeInheritance option adds the given attributes of the
+// parent span (e.g. "tenant.id", "user.id" set on the span of the
+// incoming request), when present, to each step span. The attributes
+// can only be read from spans exposing them, like the ones of the
+// OpenTelemetry SDK. It requires WithTracer.
+func WithSpanAttributeInheritance(keys ...attribute.Key) Option {
+	return func(s *saga) {
+		s.spanAttributeKeys = append(s.spanAttributeKeys, keys...)
+	}
+}
+
+// InheritAllSpanAttributes option adds all the attributes of the
+// parent span to each step span (see WithSpanAttributeInheritance).
+// It requires WithTracer.
+func InheritAllSpanAttributes() Option {
+	return func(s *saga) {
+		s.inheritSpanAttributes = true
+	}
+}
+
+// WithTraceContextExtractor option makes the Saga call the given
+// extractor once at the start of Execute, and use the context it
+// returns for all the steps, so that the step spans continue an
+// existing trace (e.g. the one of the incoming HTTP request that
+// started the Saga). It requires WithTracer.
+func WithTraceContextExtractor(extractor func(ctx context.Context) context.Context) Option {
+	return func(s *saga) {
+		s.traceContextExtractor = extractor
+	}
+}
+
+// HTTPRequestTraceExtractor returns a trace context extractor, to be
+// used with WithTraceContextExtractor, that extracts the trace context
+// and baggage from the headers of the given request using the given
+// propagator. If propagator is nil, the W3C Trace-Context and Baggage
+// propagators are used.
+func HTTPRequestTraceExtractor(r *http.Request, propagator propagation.TextMapPropagator) func(ctx context.Context) context.Context {
+	if propagator == nil {
+		propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+	}
+	return func(ctx context.Context) context.Context {
+		return propagator.Extract(ctx, propagation.HeaderCarrier(r.Header))
+	}
+}
+
+// startStepSpan starts a span for the given step, which is the current
+// one, if a tracer is configured. Otherwise, it returns a nil span.
+func (s *saga) startStepSpan(ctx context.Context, step Step) (context.Context, trace.Span) {
+	if s.tracer == nil {
+		return ctx, nil
+	}
+	attrs := s.parentSpanAttributes(ctx)
+	attrs = append(attrs,
+		attribute.String("saga.step.name", s.stepName(step)),
+		attribute.Int("saga.step.index", s.currentStep),
+	)
+	if s.id != "" {
+		attrs = append(attrs, attribute.String("saga.id", s.id))
+	}
+	if s.serviceName != "" {
+		attrs = append(attrs, attribute.String("service.name", s.serviceName))
+	}
+	if id, ok := CorrelationIDFromContext(ctx); ok {
+		attrs = append(attrs, attribute.String("saga.correlation_id", id))
+	}
+	attrs = append(attrs, s.baggageAttributes(ctx)...)
+	return s.tracer.Start(ctx, s.stepName(step), trace.WithAttributes(attrs...))
+}
+
+// endStepSpan records the outcome of the step and ends its span.
+func endStepSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// baggageAttributes returns the baggage members of the given
+// context that must be added as span attributes.
+func (s *saga) baggageAttributes(ctx context.Context) []attribute.KeyValue {
+	b := baggage.FromContext(ctx)
+	var attrs []attribute.KeyValue
+	if s.inheritBaggage {
+		for _, m := range b.Members() {
+			attrs = append(attrs, attribute.String(m.Key(), m.Value()))
+		}
+		return attrs
+	}
+	for _, key := range s.baggageKeys {
+		if m := b.Member(key); m.Key() != "" {
+			attrs = append(attrs, attribute.String(key, m.Value()))
+		}
+	}
+	return attrs
+}
+
+// attributedSpan is implemented by spans whose attributes
+// can be read, like the ones of the OpenTelemetry SDK.
+type attributedSpan interface {
+	Attributes() []attribute.KeyValue
+}
+
+// parentSpanAttributes returns the attributes of the span of
+// the given context that must be inherited by the step spans.
+func (s *saga) parentSpanAttributes(ctx context.Context) []attribute.KeyValue {
+	if !s.inheritSpanAttributes && len(s.spanAttributeKeys) == 0 {
+		return nil
+	}
+	parent, ok := trace.SpanFromContext(ctx).(attributedSpan)
+	if !ok {
+		return nil
+	}
+	if s.inheritSpanAttributes {
+		return parent.Attributes()
+	}
+	var attrs []attribute.KeyValue
+	for _, kv := range parent.Attributes() {
+		if slices.Contains(s.spanAttributeKeys, kv.Key) {
+			attrs = append(attrs, kv)
+		}
+	}
+	return attrs
+}
+
+// TraceHeadersFromContext returns the W3C Trace-Context headers
+// (e.g. "traceparent") injected in the context passed to the forward
+// action of steps with WithW3CTracePropagation, to be added to the
+// requests sent to external services.
+func TraceHeadersFromContext(ctx context.Context) map[string]string {
+	headers, _ := ctx.Value(traceHeadersKey).(map[string]string)
+	return headers
+}
+
+// withTraceHeaders returns a copy of ctx carrying the W3C Trace-Context
+// headers of the span in ctx. It returns ctx if there is no such span.
+func withTraceHeaders(ctx context.Context) context.Context {
+	headers := make(map[string]string)
+	propagation.TraceContext{}.Inject(ctx, propagation.MapCarrier(headers))
+	if len(headers) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, traceHeadersKey, headers)
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/baggage"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSaga_Tracing(t *testing.T) {
+	tenant, err := baggage.NewMember("tenant.id", "acme")
+	require.Nil(t, err)
+	user, err := baggage.NewMember("user.id", "john")
+	require.Nil(t, err)
+	b, err := baggage.New(tenant, user)
+	require.Nil(t, err)
+	ctx := baggage.ContextWithBaggage(context.Background(), b)
+
+	testCases := []struct {
+		name          string
+		options       []Option
+		expectedAttrs []attribute.KeyValue
+	}{
+		{
+			name: "no baggage",
+			expectedAttrs: []attribute.KeyValue{
+				attribute.String("saga.step.name", "step1"),
+				attribute.Int("saga.step.index", 0),
+				attribute.String("saga.id", "order-1"),
+			},
+		},
+		{
+			name:    "baggage propagation",
+			options: []Option{WithBaggagePropagation("tenant.id", "missing")},
+			expectedAttrs: []attribute.KeyValue{
+				attribute.String("saga.step.name", "step1"),
+				attribute.Int("saga.step.index", 0),
+				attribute.String("saga.id", "order-1"),
+				attribute.String("tenant.id", "acme"),
+			},
+		},
+		{
+			name:    "correlation ID",
+			options: []Option{WithCorrelationIDGenerator(func() string { return "corr-1" })},
+			expectedAttrs: []attribute.KeyValue{
+				attribute.String("saga.step.name", "step1"),
+				attribute.Int("saga.step.index", 0),
+				attribute.String("saga.id", "order-1"),
+				attribute.String("saga.correlation_id", "corr-1"),
+			},
+		},
+		{
+			name:    "baggage inheritance",
+			options: []Option{WithBaggageInheritance()},
+			expectedAttrs: []attribute.KeyValue{
+				attribute.String("saga.step.name", "step1"),
+				attribute.Int("saga.step.index", 0),
+				attribute.String("saga.id", "order-1"),
+				attribute.String("tenant.id", "acme"),
+				attribute.String("user.id", "john"),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+			opts := append([]Option{WithSagaID("order-1"), WithTracer(tp.Tracer("test"))}, tc.options...)
+			saga := New(opts...)
+			saga.AddStep(NewStep("step1",
+				func(ctx context.Context) error {
+					return errors.New("step1 error")
+				},
+				noop,
+			))
+			require.NotNil(t, saga.Execute(ctx))
+
+			spans := sr.Ended()
+			require.Len(t, spans, 1)
+			require.Equal(t, "step1", spans[0].Name())
+			require.ElementsMatch(t, tc.expectedAttrs, spans[0].Attributes())
+			require.Equal(t, codes.Error, spans[0].Status().Code)
+			require.Equal(t, "step1 error", spans[0].Status().Description)
+		})
+	}
+}
+
+func TestWithW3CTracePropagation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		withTracer    bool
+		expectHeaders bool
+	}{
+		{
+			name:          "with tracer",
+			withTracer:    true,
+			expectHeaders: true,
+		},
+		{
+			name: "without tracer",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+			var opts []Option
+			if tc.withTracer {
+				opts = append(opts, WithTracer(tp.Tracer("test")))
+			}
+			var headers map[string]string
+			saga := New(opts...)
+			saga.AddStep(NewStepWithOptions("step1",
+				func(ctx context.Context) error {
+					headers = TraceHeadersFromContext(ctx)
+					return nil
+				},
+				noop,
+				WithW3CTracePropagation(),
+			))
+			require.Nil(t, saga.Execute(context.Background()))
+			if !tc.expectHeaders {
+				require.Nil(t, headers)
+				return
+			}
+			spans := sr.Ended()
+			require.Len(t, spans, 1)
+			sc := spans[0].SpanContext()
+			expected := "00-" + sc.TraceID().String() + "-" + sc.SpanID().String() + "-01"
+			require.Equal(t, map[string]string{"traceparent": expected}, headers)
+		})
+	}
+}
+
+func TestWithTraceContextExtractor(t *testing.T) {
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	r, err := http.NewRequest(http.MethodPost, "/orders", nil)
+	require.Nil(t, err)
+	r.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	r.Header.Set("baggage", "tenant.id=acme")
+
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+	saga := New(
+		WithTracer(tp.Tracer("test")),
+		WithTraceContextExtractor(HTTPRequestTraceExtractor(r, nil)),
+		WithBaggagePropagation("tenant.id"),
+	)
+	saga.AddStep(NewStep("step1", noop, noop))
+	saga.AddStep(NewStep("step2", noop, noop))
+	require.Nil(t, saga.Execute(context.Background()))
+
+	spans := sr.Ended()
+	require.Len(t, spans, 2)
+	for _, span := range spans {
+		require.Equal(t, traceID, span.SpanContext().TraceID().String())
+		require.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+		require.Contains(t, span.Attributes(), attribute.String("tenant.id", "acme"))
+	}
+}
+
+func TestWithSpanAttributeInheritance(t *testing.T) {
+	testCases := []struct {
+		name          string
+		options       []Option
+		expectedAttrs []attribute.KeyValue
+	}{
+		{
+			name: "no inheritance",
+			expectedAttrs: []attribute.KeyValue{
+				attribute.String("saga.step.name", "step1"),
+				attribute.Int("saga.step.index", 0),
+			},
+		},
+		{
+			name:    "given keys",
+			options: []Option{WithSpanAttributeInheritance("tenant.id", "missing")},
+			expectedAttrs: []attribute.KeyValue{
+				attribute.String("tenant.id", "acme"),
+				attribute.String("saga.step.name", "step1"),
+				attribute.Int("saga.step.index", 0),
+			},
+		},
+		{
+			name:    "all attributes",
+			options: []Option{InheritAllSpanAttributes()},
+			expectedAttrs: []attribute.KeyValue{
+				attribute.String("tenant.id", "acme"),
+				attribute.String("user.id", "john"),
+				attribute.String("http.route", "/orders"),
+				attribute.String("saga.step.name", "step1"),
+				attribute.Int("saga.step.index", 0),
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sr := tracetest.NewSpanRecorder()
+			tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+			tracer := tp.Tracer("test")
+			ctx, parent := tracer.Start(context.Background(), "request")
+			parent.SetAttributes(
+				attribute.String("tenant.id", "acme"),
+				attribute.String("user.id", "john"),
+				attribute.String("http.route", "/orders"),
+			)
+			saga := New(append([]Option{WithTracer(tracer)}, tc.options...)...)
+			saga.AddStep(NewStep("step1", noop, noop))
+			require.NoError(t, saga.Execute(ctx))
+			parent.End()
+
+			spans := sr.Ended()
+			require.Len(t, spans, 2)
+			require.Equal(t, "step1", spans[0].Name())
+			require.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID())
+			require.ElementsMatch(t, tc.expectedAttrs, spans[0].Attributes())
+		})
+	}
+}
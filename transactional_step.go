@@ -0,0 +1,128 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// transactionalStep is a step whose forward and
+// compensation actions run within database transactions.
+type transactionalStep struct {
+	*step
+	db         *sql.DB
+	forward    func(ctx context.Context, tx *sql.Tx) error
+	compensate func(ctx context.Context, tx *sql.Tx) error
+
+	txMu      sync.Mutex
+	rollback  func() error
+	committed bool
+}
+
+// NewTransactionalStep creates a new Step whose forward action runs
+// within a transaction started with db.BeginTx. The transaction is
+// committed if forward succeeds, and rolled back if it fails or panics
+// (the panic is propagated, and can be turned into an error with
+// WithPanicRecovery).
+//
+// Its compensation rolls back the last transaction if it is still
+// open. A committed transaction cannot be rolled back, so its effects
+// are undone by compensate, which runs within a new transaction the
+// same way. compensate is required: without it, the forward action
+// fails without beginning any transaction.
+func NewTransactionalStep(name string, db *sql.DB, forward, compensate func(ctx context.Context, tx *sql.Tx) error) Step {
+	return NewTransactionalStepWithOptions(name, db, forward, compensate)
+}
+
+// NewTransactionalStepWithOptions creates a new transactional Step
+// with the provided step options. See NewTransactionalStep.
+func NewTransactionalStepWithOptions(name string, db *sql.DB, forward, compensate func(ctx context.Context, tx *sql.Tx) error, opts ...StepOption) Step {
+	s := &transactionalStep{
+		db:         db,
+		forward:    forward,
+		compensate: compensate,
+	}
+	s.step = newStep(name, s.runInTx, s.compensateTx, opts)
+	return s
+}
+
+// runInTx runs the forward action within a new transaction.
+func (s *transactionalStep) runInTx(ctx context.Context) error {
+	if s.compensate == nil {
+		return errors.Errorf("transactional step %s requires a compensation action for its committed transactions", s.name)
+	}
+	err := s.inTx(ctx, s.forward, func(tx *sql.Tx) {
+		s.txMu.Lock()
+		s.rollback = tx.Rollback
+		s.committed = false
+		s.txMu.Unlock()
+	})
+	if err != nil {
+		return err
+	}
+	s.txMu.Lock()
+	s.committed = true
+	s.txMu.Unlock()
+	return nil
+}
+
+// compensateTx undoes the last transaction: it runs the compensation
+// action within a new transaction if it was committed, or rolls it
+// back if it is still open.
+func (s *transactionalStep) compensateTx(ctx context.Context) error {
+	s.txMu.Lock()
+	rollback, committed := s.rollback, s.committed
+	s.txMu.Unlock()
+	if committed {
+		if err := s.inTx(ctx, s.compensate, func(tx *sql.Tx) {}); err != nil {
+			return err
+		}
+		s.txMu.Lock()
+		s.committed = false
+		s.txMu.Unlock()
+		return nil
+	}
+	if rollback == nil {
+		return nil
+	}
+	if err := rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+		return errors.Wrapf(err, "rolling back transaction for step %s", s.name)
+	}
+	return nil
+}
+
+// inTx runs fn within a new transaction, committed if fn succeeds and
+// rolled back if it fails or panics. begun is called with the
+// transaction before running fn.
+func (s *transactionalStep) inTx(ctx context.Context, fn func(ctx context.Context, tx *sql.Tx) error, begun func(tx *sql.Tx)) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrapf(err, "beginning transaction for step %s", s.name)
+	}
+	begun(tx)
+	defer func() {
+		if r := recover(); r != nil {
+			_ = tx.Rollback()
+			panic(r)
+		}
+	}()
+	if err := fn(ctx, tx); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			return &MultiError{Errors: []error{
+				err,
+				errors.Wrapf(rollbackErr, "rolling back transaction for step %s", s.name),
+			}}
+		}
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.Wrapf(err, "committing transaction for step %s", s.name)
+	}
+	return nil
+}
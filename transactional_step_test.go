@@ -0,0 +1,226 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// txDriver is a database/sql driver recording
+// the outcome of the transactions.
+type txDriver struct {
+	mu        sync.Mutex
+	commits   int
+	rollbacks int
+	beginErr  error
+	commitErr error
+}
+
+func (d *txDriver) Open(name string) (driver.Conn, error) {
+	return &txConn{driver: d}, nil
+}
+
+type txConn struct {
+	driver *txDriver
+}
+
+func (c *txConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *txConn) Close() error {
+	return nil
+}
+
+func (c *txConn) Begin() (driver.Tx, error) {
+	if c.driver.beginErr != nil {
+		return nil, c.driver.beginErr
+	}
+	return &txTx{driver: c.driver}, nil
+}
+
+type txTx struct {
+	driver *txDriver
+}
+
+func (t *txTx) Commit() error {
+	t.driver.mu.Lock()
+	defer t.driver.mu.Unlock()
+	if t.driver.commitErr != nil {
+		return t.driver.commitErr
+	}
+	t.driver.commits++
+	return nil
+}
+
+func (t *txTx) Rollback() error {
+	t.driver.mu.Lock()
+	defer t.driver.mu.Unlock()
+	t.driver.rollbacks++
+	return nil
+}
+
+func TestNewTransactionalStep(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		driver                *txDriver
+		forward               func(ctx context.Context, tx *sql.Tx) error
+		expectedError         string
+		expectedCommits       int
+		expectedRollbacks     int
+		expectedCompensations int
+	}{
+		{
+			name:   "commit on success",
+			driver: &txDriver{},
+			forward: func(ctx context.Context, tx *sql.Tx) error {
+				return nil
+			},
+			expectedCommits:       1,
+			expectedCompensations: 1,
+		},
+		{
+			name:   "rollback on error",
+			driver: &txDriver{},
+			forward: func(ctx context.Context, tx *sql.Tx) error {
+				return errors.New("forward error")
+			},
+			expectedError:     "forward error",
+			expectedRollbacks: 1,
+		},
+		{
+			name:   "rollback on panic",
+			driver: &txDriver{},
+			forward: func(ctx context.Context, tx *sql.Tx) error {
+				panic("boom")
+			},
+			expectedError:     "step step1 panicked: boom",
+			expectedRollbacks: 1,
+		},
+		{
+			name:   "begin error",
+			driver: &txDriver{beginErr: errors.New("connection refused")},
+			forward: func(ctx context.Context, tx *sql.Tx) error {
+				return nil
+			},
+			expectedError: "beginning transaction for step step1: connection refused",
+		},
+		{
+			name:   "commit error",
+			driver: &txDriver{commitErr: errors.New("serialization failure")},
+			forward: func(ctx context.Context, tx *sql.Tx) error {
+				return nil
+			},
+			expectedError: "committing transaction for step step1: serialization failure",
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			driverName := "txdriver" + string(rune('a'+i))
+			sql.Register(driverName, tc.driver)
+			db, err := sql.Open(driverName, "")
+			require.Nil(t, err)
+			defer db.Close()
+
+			compensations := 0
+			saga := New(WithPanicRecovery())
+			saga.AddStep(NewTransactionalStep("step1", db, tc.forward, func(ctx context.Context, tx *sql.Tx) error {
+				compensations++
+				return nil
+			}))
+			err = saga.Execute(context.Background())
+			if tc.expectedError != "" {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), tc.expectedError)
+			} else {
+				require.Nil(t, err)
+			}
+			require.Equal(t, tc.expectedCommits, tc.driver.commits)
+			require.Equal(t, tc.expectedRollbacks, tc.driver.rollbacks)
+
+			// committed transactions are compensated within a new
+			// transaction, the ones rolled back have nothing to undo.
+			require.Nil(t, saga.Compensate(context.Background()))
+			require.Equal(t, tc.expectedCompensations, compensations)
+			require.Equal(t, tc.expectedCommits+tc.expectedCompensations, tc.driver.commits)
+			require.Equal(t, tc.expectedRollbacks, tc.driver.rollbacks)
+		})
+	}
+}
+
+func TestNewTransactionalStepWithOptions(t *testing.T) {
+	sql.Register("txdriverwithoptions", &txDriver{})
+	db, err := sql.Open("txdriverwithoptions", "")
+	require.Nil(t, err)
+	defer db.Close()
+	attempts := 0
+	step := NewTransactionalStepWithOptions("step1", db,
+		func(ctx context.Context, tx *sql.Tx) error {
+			attempts++
+			if attempts < 2 {
+				return errors.New("deadlock")
+			}
+			return nil
+		},
+		func(ctx context.Context, tx *sql.Tx) error {
+			return nil
+		},
+		WithRetry(2, 0),
+	)
+	require.Nil(t, step.ExecuteForward(context.Background()))
+	require.Equal(t, 2, attempts)
+}
+
+func TestNewTransactionalStep_CompensationErrors(t *testing.T) {
+	testCases := []struct {
+		name              string
+		compensate        func(ctx context.Context, tx *sql.Tx) error
+		expectedError     string
+		expectedCommits   int
+		expectedRollbacks int
+	}{
+		{
+			name:          "missing compensation",
+			expectedError: "transactional step step1 requires a compensation action for its committed transactions",
+		},
+		{
+			name: "compensation error",
+			compensate: func(ctx context.Context, tx *sql.Tx) error {
+				return errors.New("compensation error")
+			},
+			expectedError:     "compensation error",
+			expectedCommits:   1,
+			expectedRollbacks: 1,
+		},
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			driver := &txDriver{}
+			driverName := "txdrivercompensation" + string(rune('a'+i))
+			sql.Register(driverName, driver)
+			db, err := sql.Open(driverName, "")
+			require.Nil(t, err)
+			defer db.Close()
+
+			step := NewTransactionalStep("step1", db, func(ctx context.Context, tx *sql.Tx) error {
+				return nil
+			}, tc.compensate)
+			err = step.ExecuteForward(context.Background())
+			if err == nil {
+				err = step.ExecuteCompensate(context.Background())
+			}
+			require.EqualError(t, err, tc.expectedError)
+			require.Equal(t, tc.expectedCommits, driver.commits)
+			require.Equal(t, tc.expectedRollbacks, driver.rollbacks)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// TypeRegistry maps type names to constructors of step types,
+// enabling plugin-style step extension (e.g. step types loaded from
+// shared libraries). Types are registered with Register and steps are
+// built with Build. It is safe for concurrent use.
+//
+// TypeRegistry is independent of StepRegistry: it only serves Register
+// and Build. The Sagas loaded from configuration files (see
+// NewFromConfig) and restored by Deserialize build their steps with a
+// StepRegistry, in which a type of a TypeRegistry can be registered
+// with a StepFactory calling Build.
+type TypeRegistry struct {
+	factories map[string]func(params map[string]any) (Step, error)
+	mu        sync.RWMutex
+}
+
+// NewTypeRegistry creates a new, empty TypeRegistry.
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		factories: make(map[string]func(params map[string]any) (Step, error)),
+	}
+}
+
+// Register associates the given type name with a constructor of *T.
+// *T must implement Step.
+func Register[T any](registry *TypeRegistry, name string, factory func(params map[string]any) (*T, error)) error {
+	if name == "" {
+		return errors.New("type name is required")
+	}
+	if factory == nil {
+		return errors.Errorf("factory for type %s is required", name)
+	}
+	if _, ok := any((*T)(nil)).(Step); !ok {
+		return errors.Errorf("type %s: %T does not implement Step", name, (*T)(nil))
+	}
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, exists := registry.factories[name]; exists {
+		return errors.Errorf("type %s is already registered", name)
+	}
+	registry.factories[name] = func(params map[string]any) (Step, error) {
+		t, err := factory(params)
+		if err != nil {
+			return nil, err
+		}
+		if t == nil {
+			return nil, errors.New("factory returned nil")
+		}
+		return any(t).(Step), nil
+	}
+	return nil
+}
+
+// Build creates a step of the given type name with the given params,
+// with the constructor registered in the given TypeRegistry.
+func Build(registry *TypeRegistry, typeName string, params map[string]any) (Step, error) {
+	registry.mu.RLock()
+	factory, exists := registry.factories[typeName]
+	registry.mu.RUnlock()
+	if !exists {
+		return nil, errors.Errorf("unknown type %q", typeName)
+	}
+	step, err := factory(params)
+	if err != nil {
+		return nil, errors.Wrapf(err, "building step of type %s", typeName)
+	}
+	return step, nil
+}
+
+// ListRegistered returns the registered type names, sorted by name.
+func (r *TypeRegistry) ListRegistered() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsRegistered reports whether the given type name is registered.
+func (r *TypeRegistry) IsRegistered(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, exists := r.factories[name]
+	return exists
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type emailStep struct {
+	to string
+}
+
+func (e *emailStep) ExecuteForward(ctx context.Context) error    { return nil }
+func (e *emailStep) ExecuteCompensate(ctx context.Context) error { return nil }
+func (e *emailStep) Name() string                                { return "email " + e.to }
+
+type notAStep struct{}
+
+func newEmailStep(params map[string]any) (*emailStep, error) {
+	to, ok := params["to"].(string)
+	if !ok {
+		return nil, errors.New("missing recipient")
+	}
+	return &emailStep{to: to}, nil
+}
+
+func TestTypeRegistry_Register(t *testing.T) {
+	testCases := []struct {
+		name          string
+		register      func(r *TypeRegistry) error
+		expectedError string
+	}{
+		{
+			name: "happy path",
+			register: func(r *TypeRegistry) error {
+				return Register(r, "email", newEmailStep)
+			},
+		},
+		{
+			name: "empty name",
+			register: func(r *TypeRegistry) error {
+				return Register(r, "", newEmailStep)
+			},
+			expectedError: "type name is required",
+		},
+		{
+			name: "nil factory",
+			register: func(r *TypeRegistry) error {
+				return Register[emailStep](r, "email", nil)
+			},
+			expectedError: "factory for type email is required",
+		},
+		{
+			name: "already registered",
+			register: func(r *TypeRegistry) error {
+				if err := Register(r, "email", newEmailStep); err != nil {
+					return err
+				}
+				return Register(r, "email", newEmailStep)
+			},
+			expectedError: "type email is already registered",
+		},
+		{
+			name: "type does not implement Step",
+			register: func(r *TypeRegistry) error {
+				return Register(r, "invalid", func(params map[string]any) (*notAStep, error) {
+					return &notAStep{}, nil
+				})
+			},
+			expectedError: "type invalid: *saga.notAStep does not implement Step",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.register(NewTypeRegistry())
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.Nil(t, err)
+			}
+		})
+	}
+}
+
+func TestTypeRegistry_Build(t *testing.T) {
+	registry := NewTypeRegistry()
+	require.Nil(t, Register(registry, "email", newEmailStep))
+	testCases := []struct {
+		name          string
+		typeName      string
+		params        map[string]any
+		expectedName  string
+		expectedError string
+	}{
+		{
+			name:         "happy path",
+			typeName:     "email",
+			params:       map[string]any{"to": "alice"},
+			expectedName: "email alice",
+		},
+		{
+			name:          "unknown type",
+			typeName:      "sms",
+			expectedError: `unknown type "sms"`,
+		},
+		{
+			name:          "factory error",
+			typeName:      "email",
+			expectedError: "building step of type email: missing recipient",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			step, err := Build(registry, tc.typeName, tc.params)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.Nil(t, err)
+				require.Equal(t, tc.expectedName, step.Name())
+			}
+		})
+	}
+}
+
+func TestTypeRegistry_ListRegistered(t *testing.T) {
+	registry := NewTypeRegistry()
+	require.Empty(t, registry.ListRegistered())
+	require.False(t, registry.IsRegistered("email"))
+	require.Nil(t, Register(registry, "sms", func(params map[string]any) (*emailStep, error) {
+		return &emailStep{}, nil
+	}))
+	require.Nil(t, Register(registry, "email", newEmailStep))
+	require.Equal(t, []string{"email", "sms"}, registry.ListRegistered())
+	require.True(t, registry.IsRegistered("email"))
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import "github.com/pkg/errors"
+
+// Validator validates the configuration of a Saga
+// before it executes (see WithValidator).
+type Validator interface {
+	// Validate returns an error if the given Saga is misconfigured.
+	Validate(saga Saga) error
+}
+
+// ValidatorFunc is an adapter to allow the use of
+// ordinary functions as Validators.
+type ValidatorFunc func(saga Saga) error
+
+func (f ValidatorFunc) Validate(saga Saga) error {
+	return f(saga)
+}
+
+// WithValidator option makes Execute validate the Saga with the given
+// validator before running any step, returning its error, if any.
+// Several validators can be combined with CompositeValidator.
+// Validators must not call Execute nor Compensate.
+func WithValidator(v Validator) Option {
+	return func(s *saga) {
+		s.validator = v
+	}
+}
+
+// UniqueStepNamesValidator requires the steps of the
+// Saga, checkpoints included, to have unique names.
+var UniqueStepNamesValidator Validator = ValidatorFunc(func(saga Saga) error {
+	names := make(map[string]struct{})
+	for i, step := range saga.Steps() {
+		if _, exists := names[step.Name()]; exists {
+			return errors.Errorf("step %d: name %q is duplicated", i, step.Name())
+		}
+		names[step.Name()] = struct{}{}
+	}
+	return nil
+})
+
+// NonEmptyStepsValidator requires the Saga to have at least one step.
+var NonEmptyStepsValidator Validator = ValidatorFunc(func(saga Saga) error {
+	if len(saga.Steps()) == 0 {
+		return errors.New("saga has no steps")
+	}
+	return nil
+})
+
+// CompensationFunctionValidator requires all the steps of the Saga,
+// checkpoints excluded, to have a compensation action, regardless of
+// their compensation mode (see WithCompensationMode). It fails with a
+// *MissingCompensationError.
+var CompensationFunctionValidator Validator = ValidatorFunc(func(saga Saga) error {
+	for _, step := range saga.Steps() {
+		if isCheckpoint(step) {
+			continue
+		}
+		if c, ok := stepAs[compensationHolder](step); ok && !c.hasCompensation() {
+			return &MissingCompensationError{StepName: step.Name()}
+		}
+	}
+	return nil
+})
+
+// CompositeValidator returns a Validator running the given
+// validators in order, stopping at the first failure.
+func CompositeValidator(validators ...Validator) Validator {
+	return ValidatorFunc(func(saga Saga) error {
+		for _, v := range validators {
+			if err := v.Validate(saga); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// compensationHolder is implemented by steps that
+// may or may not have a compensation action.
+type compensationHolder interface {
+	// hasCompensation reports whether the step
+	// has a compensation action.
+	hasCompensation() bool
+}
+
+func (s *step) hasCompensation() bool {
+	return s.compensate != nil
+}
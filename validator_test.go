@@ -0,0 +1,127 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package saga
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidators(t *testing.T) {
+	testCases := []struct {
+		name          string
+		validator     Validator
+		steps         []Step
+		checkpoint    string
+		expectedError string
+	}{
+		{
+			name:      "unique step names",
+			validator: UniqueStepNamesValidator,
+			steps:     []Step{NewStep("step1", noop, noop), NewStep("step2", noop, noop)},
+		},
+		{
+			name:          "duplicated step names",
+			validator:     UniqueStepNamesValidator,
+			steps:         []Step{NewStep("step1", noop, noop), NewStep("step1", noop, noop)},
+			expectedError: `step 1: name "step1" is duplicated`,
+		},
+		{
+			name:          "checkpoint named after a step",
+			validator:     UniqueStepNamesValidator,
+			steps:         []Step{NewStep("step1", noop, noop)},
+			checkpoint:    "step1",
+			expectedError: `step 1: name "step1" is duplicated`,
+		},
+		{
+			name:      "non empty steps",
+			validator: NonEmptyStepsValidator,
+			steps:     []Step{NewStep("step1", noop, noop)},
+		},
+		{
+			name:          "empty steps",
+			validator:     NonEmptyStepsValidator,
+			expectedError: "saga has no steps",
+		},
+		{
+			name:       "all steps with compensation",
+			validator:  CompensationFunctionValidator,
+			steps:      []Step{NewStep("step1", noop, noop)},
+			checkpoint: "checkpoint",
+		},
+		{
+			name:          "step without compensation",
+			validator:     CompensationFunctionValidator,
+			steps:         []Step{NewStep("step1", noop, noop), NewStep("step2", noop, nil)},
+			expectedError: "step step2 has no compensation",
+		},
+		{
+			name:      "batch step with compensation",
+			validator: CompensationFunctionValidator,
+			steps:     []Step{NewBatchStep("step1", []any{1}, func(ctx context.Context, batch []any) error { return nil }, func(ctx context.Context, processedBatches [][]any) error { return nil }, 1)},
+		},
+		{
+			name:          "batch step without compensation",
+			validator:     CompensationFunctionValidator,
+			steps:         []Step{NewBatchStep("step1", []any{1}, func(ctx context.Context, batch []any) error { return nil }, nil, 1)},
+			expectedError: "step step1 has no compensation",
+		},
+		{
+			name:      "composite",
+			validator: CompositeValidator(NonEmptyStepsValidator, UniqueStepNamesValidator, CompensationFunctionValidator),
+			steps:     []Step{NewStep("step1", noop, noop)},
+		},
+		{
+			name:          "composite failing",
+			validator:     CompositeValidator(NonEmptyStepsValidator, UniqueStepNamesValidator, CompensationFunctionValidator),
+			expectedError: "saga has no steps",
+		},
+		{
+			name:          "validator func",
+			validator:     ValidatorFunc(func(Saga) error { return errors.New("invalid") }),
+			steps:         []Step{NewStep("step1", noop, noop)},
+			expectedError: "invalid",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			saga := New()
+			for _, step := range tc.steps {
+				saga.AddStep(step)
+			}
+			if tc.checkpoint != "" {
+				saga.AddCheckpoint(tc.checkpoint)
+			}
+			err := tc.validator.Validate(saga)
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestWithValidator(t *testing.T) {
+	var executed bool
+	saga := New(WithValidator(UniqueStepNamesValidator))
+	saga.AddStep(NewStep("step1", func(ctx context.Context) error {
+		executed = true
+		return nil
+	}, noop))
+	saga.AddStep(NewStep("step1", noop, noop))
+	err := saga.Execute(context.Background())
+	require.EqualError(t, err, `validating saga: step 1: name "step1" is duplicated`)
+	require.False(t, executed)
+
+	var missing *MissingCompensationError
+	saga = New(WithValidator(CompensationFunctionValidator))
+	saga.AddStep(NewStep("step1", noop, nil))
+	require.ErrorAs(t, saga.Execute(context.Background()), &missing)
+	require.Equal(t, "step1", missing.StepName)
+}
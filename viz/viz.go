@@ -0,0 +1,97 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+// Package viz renders sagas as text diagrams for terminal output.
+package viz
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/tiagomelo/go-saga"
+)
+
+// Status indicators of the steps.
+const (
+	StatusCompleted   = "[✓]"
+	StatusFailed      = "[✗]"
+	StatusCompensated = "[~]"
+	StatusPending     = "[ ]"
+	StatusUnknown     = "[?]"
+)
+
+// ToASCII renders the given saga as a vertical flowchart, with each
+// step in a box followed by its status indicator. Forward paths are
+// shown as → arrows between the steps, and compensation paths as
+// ← arrows. The status of each step is read from the given state
+// manager and from the saga summary.
+func ToASCII(s saga.Saga, sm saga.StateManager) string {
+	var b strings.Builder
+	// writes to a strings.Builder never fail.
+	_ = PrintTo(&b, s, sm)
+	return b.String()
+}
+
+// PrintTo writes the ASCII flowchart of the given saga to w.
+// See ToASCII.
+func PrintTo(w io.Writer, s saga.Saga, sm saga.StateManager) error {
+	steps := s.Steps()
+	summary := s.Summary()
+	compensated := make(map[int]bool, len(summary.CompensatedSteps))
+	for _, c := range summary.CompensatedSteps {
+		compensated[c.StepIndex] = true
+	}
+	width := 0
+	for _, step := range steps {
+		if n := len([]rune(step.Name())); n > width {
+			width = n
+		}
+	}
+	border := "+" + strings.Repeat("-", width+2) + "+"
+	arrowIndent := strings.Repeat(" ", (width+4)/2)
+	for i, step := range steps {
+		if i > 0 {
+			arrows := arrowIndent + "→"
+			// the compensation path flows back from this step into the previous one.
+			failed := summary.FailedStep != nil && summary.FailedStep.StepIndex == i
+			if compensated[i-1] && (compensated[i] || failed) {
+				arrows += " ←"
+			}
+			if _, err := fmt.Fprintln(w, arrows); err != nil {
+				return err
+			}
+		}
+		name := step.Name()
+		padding := strings.Repeat(" ", width-len([]rune(name)))
+		lines := []string{
+			border,
+			fmt.Sprintf("| %s%s | %s", name, padding, stepStatus(i, sm, summary, compensated)),
+			border,
+		}
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(w, line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// stepStatus returns the status indicator of the step at the given index.
+func stepStatus(stepIndex int, sm saga.StateManager, summary saga.Summary, compensated map[int]bool) string {
+	completed, err := sm.StepState(stepIndex)
+	switch {
+	case err != nil:
+		return StatusUnknown
+	case summary.FailedStep != nil && summary.FailedStep.StepIndex == stepIndex:
+		return StatusFailed
+	case compensated[stepIndex]:
+		return StatusCompensated
+	case completed:
+		return StatusCompleted
+	default:
+		return StatusPending
+	}
+}
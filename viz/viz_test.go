@@ -0,0 +1,111 @@
+// Copyright (c) 2024 Tiago Melo. All rights reserved.
+// Use of this source code is governed by the MIT License that can be found in
+// the LICENSE file.
+
+package viz
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tiagomelo/go-saga"
+)
+
+type mockStateManager struct {
+	saga.StateManager
+	errIndex int
+}
+
+func (m *mockStateManager) StepState(stepIndex int) (bool, error) {
+	if stepIndex == m.errIndex {
+		return false, errors.New("state unavailable")
+	}
+	return m.StateManager.StepState(stepIndex)
+}
+
+func noop(ctx context.Context) error {
+	return nil
+}
+
+func TestToASCII(t *testing.T) {
+	testCases := []struct {
+		name           string
+		failingStep    string
+		stateErrIndex  int
+		expectedOutput string
+	}{
+		{
+			name:          "successful saga",
+			stateErrIndex: -1,
+			expectedOutput: `+-----------------+
+| reserve         | [✓]
++-----------------+
+         →
++-----------------+
+| charge customer | [✓]
++-----------------+
+         →
++-----------------+
+| ship            | [✓]
++-----------------+
+`,
+		},
+		{
+			name:          "failed saga",
+			failingStep:   "charge customer",
+			stateErrIndex: -1,
+			expectedOutput: `+-----------------+
+| reserve         | [~]
++-----------------+
+         → ←
++-----------------+
+| charge customer | [✗]
++-----------------+
+         →
++-----------------+
+| ship            | [ ]
++-----------------+
+`,
+		},
+		{
+			name:          "state manager error",
+			stateErrIndex: 1,
+			expectedOutput: `+-----------------+
+| reserve         | [✓]
++-----------------+
+         →
++-----------------+
+| charge customer | [?]
++-----------------+
+         →
++-----------------+
+| ship            | [✓]
++-----------------+
+`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sm := saga.NewInMemoryStateManager()
+			s := saga.New(saga.WithStateManager(sm))
+			for _, name := range []string{"reserve", "charge customer", "ship"} {
+				forward := noop
+				if name == tc.failingStep {
+					forward = func(ctx context.Context) error {
+						return errors.New("card declined")
+					}
+				}
+				s.AddStep(saga.NewStep(name, forward, noop))
+			}
+			_ = s.Execute(context.Background())
+			output := ToASCII(s, &mockStateManager{StateManager: sm, errIndex: tc.stateErrIndex})
+			require.Equal(t, tc.expectedOutput, output)
+			var buf bytes.Buffer
+			require.Nil(t, PrintTo(&buf, s, &mockStateManager{StateManager: sm, errIndex: tc.stateErrIndex}))
+			require.Equal(t, tc.expectedOutput, buf.String())
+		})
+	}
+}